@@ -0,0 +1,11 @@
+-- Migration: Remove Message Referrals
+-- Version: 1.0.0
+-- Description: Drops the click-to-WhatsApp attribution table
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_message_referrals_ctwa_clid;
+DROP INDEX IF EXISTS idx_message_referrals_sender;
+DROP TABLE IF EXISTS message_referrals CASCADE;
+
+COMMIT;
@@ -0,0 +1,27 @@
+-- Migration: Add Message Referrals
+-- Version: 1.0.0
+-- Description: Stores click-to-WhatsApp ad attribution carried on inbound messages
+
+CREATE TABLE message_referrals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message_id TEXT NOT NULL,
+    sender_phone VARCHAR(20) NOT NULL,
+    source_url TEXT NOT NULL,
+    source_id TEXT,
+    source_type VARCHAR(50),
+    headline TEXT,
+    body TEXT,
+    media_type VARCHAR(20),
+    image_url TEXT,
+    video_url TEXT,
+    thumbnail_url TEXT,
+    ctwa_clid TEXT,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT message_referrals_message_unique UNIQUE (message_id)
+);
+
+CREATE INDEX idx_message_referrals_sender ON message_referrals(sender_phone, received_at);
+CREATE INDEX idx_message_referrals_ctwa_clid ON message_referrals(ctwa_clid) WHERE ctwa_clid IS NOT NULL;
+
+COMMENT ON TABLE message_referrals IS 'Click-to-WhatsApp ad attribution captured from inbound message referrals';
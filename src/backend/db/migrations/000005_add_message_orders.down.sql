@@ -0,0 +1,11 @@
+-- Migration: Remove Message Orders
+-- Version: 1.0.0
+-- Description: Drops the catalog orders table
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_message_orders_catalog;
+DROP INDEX IF EXISTS idx_message_orders_sender;
+DROP TABLE IF EXISTS message_orders CASCADE;
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- Migration: Add Message Orders
+-- Version: 1.0.0
+-- Description: Stores catalog orders placed by customers from sent product catalogs
+
+CREATE TABLE message_orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message_id TEXT NOT NULL,
+    sender_phone VARCHAR(20) NOT NULL,
+    catalog_id TEXT NOT NULL,
+    text TEXT,
+    items JSONB NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT message_orders_message_unique UNIQUE (message_id),
+    CONSTRAINT message_orders_items_array CHECK (jsonb_typeof(items) = 'array')
+);
+
+CREATE INDEX idx_message_orders_sender ON message_orders(sender_phone, received_at);
+CREATE INDEX idx_message_orders_catalog ON message_orders(catalog_id);
+
+COMMENT ON TABLE message_orders IS 'Catalog orders received from customers, linked to the conversation by sender phone';
+COMMENT ON COLUMN message_orders.items IS 'Ordered product items with product_retailer_id, quantity, item_price and currency';
@@ -0,0 +1,10 @@
+-- Migration: Remove Message External Reference
+-- Version: 1.0.0
+-- Description: Drops the caller-supplied external reference column
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_messages_external_ref;
+ALTER TABLE messages DROP COLUMN IF EXISTS external_ref;
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Migration: Add Message External Reference
+-- Version: 1.0.0
+-- Description: Stores a caller-supplied reference echoed back in webhooks for CRM correlation
+
+ALTER TABLE messages ADD COLUMN external_ref VARCHAR(512);
+
+CREATE INDEX idx_messages_external_ref ON messages(external_ref) WHERE external_ref IS NOT NULL;
+
+COMMENT ON COLUMN messages.external_ref IS 'Caller reference sent as biz_opaque_callback_data and matched on webhooks';
@@ -0,0 +1,12 @@
+-- Migration: Remove Message Claims
+-- Version: 1.0.0
+-- Description: Drops worker claim tracking from messages
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_messages_processing;
+DROP INDEX IF EXISTS idx_messages_pending_claim;
+ALTER TABLE messages DROP COLUMN IF EXISTS claimed_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS claimed_by;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration: Add Message Claims
+-- Version: 1.0.0
+-- Description: Tracks which worker claimed a pending message so concurrent workers never process the same row
+
+ALTER TABLE messages ADD COLUMN claimed_by TEXT;
+ALTER TABLE messages ADD COLUMN claimed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_messages_pending_claim ON messages(created_at) WHERE status = 'pending';
+CREATE INDEX idx_messages_processing ON messages(claimed_at) WHERE status = 'processing';
+
+COMMENT ON COLUMN messages.claimed_by IS 'Worker that claimed the message for processing';
+COMMENT ON COLUMN messages.claimed_at IS 'Time the message was claimed for processing';
@@ -0,0 +1,9 @@
+-- Migration: Remove Message Callback URL
+-- Version: 1.0.0
+-- Description: Drops the per-message status callback endpoint
+
+BEGIN;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS callback_url;
+
+COMMIT;
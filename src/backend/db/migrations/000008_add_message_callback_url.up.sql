@@ -0,0 +1,7 @@
+-- Migration: Add Message Callback URL
+-- Version: 1.0.0
+-- Description: Stores an optional per-message endpoint notified of delivery status changes
+
+ALTER TABLE messages ADD COLUMN callback_url VARCHAR(2048);
+
+COMMENT ON COLUMN messages.callback_url IS 'Endpoint receiving signed status change notifications for this message';
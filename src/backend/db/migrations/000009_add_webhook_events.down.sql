@@ -0,0 +1,10 @@
+-- Migration: Remove Webhook Events
+-- Version: 1.0.0
+-- Description: Drops the received webhook event history
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_webhook_events_message;
+DROP TABLE IF EXISTS webhook_events CASCADE;
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Migration: Add Webhook Events
+-- Version: 1.0.0
+-- Description: Persists every received webhook event with its verification status for auditing and replay
+
+CREATE TABLE webhook_events (
+    id BIGSERIAL PRIMARY KEY,
+    message_id TEXT NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    status VARCHAR(20),
+    verified BOOLEAN NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_events_message ON webhook_events(message_id, received_at, id);
+
+COMMENT ON TABLE webhook_events IS 'Raw webhook events received per message, including ones that failed signature verification';
@@ -0,0 +1,10 @@
+-- Migration: Remove Message Status History
+-- Version: 1.0.0
+-- Description: Drops the message status audit trail
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_message_status_history_message;
+DROP TABLE IF EXISTS message_status_history CASCADE;
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Migration: Add Message Status History
+-- Version: 1.0.0
+-- Description: Records every message status transition for support auditing
+
+CREATE TABLE message_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    message_id UUID NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_message_status_history_message ON message_status_history(message_id, changed_at, id);
+
+COMMENT ON TABLE message_status_history IS 'Ordered audit trail of message status transitions';
+COMMENT ON COLUMN message_status_history.reason IS 'Source or cause of the transition, such as webhook or an error detail';
@@ -0,0 +1,9 @@
+-- Migration: Remove Customer Sessions
+-- Version: 1.0.0
+-- Description: Drops customer service window tracking
+
+BEGIN;
+
+DROP TABLE IF EXISTS customer_sessions CASCADE;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Migration: Add Customer Sessions
+-- Version: 1.0.0
+-- Description: Tracks each customer's last inbound message to determine the 24h customer service window
+
+CREATE TABLE customer_sessions (
+    phone VARCHAR(20) PRIMARY KEY,
+    last_inbound_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE customer_sessions IS 'Latest inbound message time per customer, used to gate free-form messages';
+COMMENT ON COLUMN customer_sessions.last_inbound_at IS 'When the customer last messaged the business; the service window runs 24h from this time';
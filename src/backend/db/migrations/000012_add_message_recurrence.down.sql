@@ -0,0 +1,9 @@
+-- Migration: Remove Message Recurrence
+-- Version: 1.0.0
+-- Description: Drops the scheduled message recurrence rule
+
+BEGIN;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS recurrence;
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Migration: Add Message Recurrence
+-- Version: 1.0.0
+-- Description: Stores an optional recurrence rule so scheduled messages reschedule themselves
+
+ALTER TABLE messages ADD COLUMN recurrence JSONB;
+
+COMMENT ON COLUMN messages.recurrence IS 'Interval, count or until bound, series ID and occurrence number of a recurring scheduled message';
@@ -0,0 +1,12 @@
+-- Migration: Remove Message Conversation
+-- Version: 1.0.0
+-- Description: Drops the billing conversation and pricing columns
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_messages_billable_conversations;
+ALTER TABLE messages DROP COLUMN IF EXISTS billable;
+ALTER TABLE messages DROP COLUMN IF EXISTS pricing_category;
+ALTER TABLE messages DROP COLUMN IF EXISTS conversation_id;
+
+COMMIT;
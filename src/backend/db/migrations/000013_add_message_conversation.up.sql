@@ -0,0 +1,13 @@
+-- Migration: Add Message Conversation
+-- Version: 1.0.0
+-- Description: Stores the billing conversation and pricing reported in status webhooks for cost reconciliation
+
+ALTER TABLE messages ADD COLUMN conversation_id VARCHAR(128);
+ALTER TABLE messages ADD COLUMN pricing_category VARCHAR(32);
+ALTER TABLE messages ADD COLUMN billable BOOLEAN;
+
+CREATE INDEX idx_messages_billable_conversations ON messages(organization_id, created_at) WHERE billable;
+
+COMMENT ON COLUMN messages.conversation_id IS 'WhatsApp conversation the message was delivered in';
+COMMENT ON COLUMN messages.pricing_category IS 'Pricing category the message was charged under, e.g. marketing or utility';
+COMMENT ON COLUMN messages.billable IS 'Whether WhatsApp reported the message as billable';
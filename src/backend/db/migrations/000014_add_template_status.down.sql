@@ -0,0 +1,10 @@
+-- Migration: Remove Template Status
+-- Version: 1.0.0
+-- Description: Drops the polled template review status table
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_template_status_status;
+DROP TABLE IF EXISTS template_status;
+
+COMMIT;
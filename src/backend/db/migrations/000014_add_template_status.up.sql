@@ -0,0 +1,17 @@
+-- Migration: Add Template Status
+-- Version: 1.0.0
+-- Description: Stores the WhatsApp review status of templates referenced by scheduled messages
+
+CREATE TABLE template_status (
+    name varchar(512) NOT NULL,
+    language varchar(16) NOT NULL,
+    status varchar(32) NOT NULL,
+    checked_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (name, language)
+);
+
+CREATE INDEX idx_template_status_status ON template_status(status);
+
+COMMENT ON TABLE template_status IS 'Latest WhatsApp review status of each polled template';
+COMMENT ON COLUMN template_status.status IS 'Review status reported by WhatsApp, e.g. PENDING or APPROVED';
+COMMENT ON COLUMN template_status.checked_at IS 'When the status was last fetched from WhatsApp';
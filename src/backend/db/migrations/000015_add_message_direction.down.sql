@@ -0,0 +1,21 @@
+-- Migration: Remove Message Direction
+-- Version: 1.0.0
+-- Description: Drops stored inbound messages and the direction columns
+
+BEGIN;
+
+DELETE FROM messages WHERE direction = 'inbound';
+
+DROP INDEX IF EXISTS idx_messages_outbound_recipient;
+DROP INDEX IF EXISTS idx_messages_inbound_sender;
+DROP INDEX IF EXISTS idx_messages_inbound_wa_id;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_outbound_organization;
+ALTER TABLE messages ALTER COLUMN organization_id SET NOT NULL;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS raw_payload;
+ALTER TABLE messages DROP COLUMN IF EXISTS wa_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS sender_phone;
+ALTER TABLE messages DROP COLUMN IF EXISTS direction;
+
+COMMIT;
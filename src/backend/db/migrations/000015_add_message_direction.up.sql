@@ -0,0 +1,23 @@
+-- Migration: Add Message Direction
+-- Version: 1.0.0
+-- Description: Stores inbound customer messages alongside sent ones so conversation threads can be retrieved
+
+ALTER TABLE messages ADD COLUMN direction VARCHAR(8) NOT NULL DEFAULT 'outbound'
+    CHECK (direction IN ('inbound', 'outbound'));
+ALTER TABLE messages ADD COLUMN sender_phone VARCHAR(20);
+ALTER TABLE messages ADD COLUMN wa_message_id TEXT;
+ALTER TABLE messages ADD COLUMN raw_payload JSONB;
+
+-- Inbound webhooks carry no organization, so only sent messages require one
+ALTER TABLE messages ALTER COLUMN organization_id DROP NOT NULL;
+ALTER TABLE messages ADD CONSTRAINT messages_outbound_organization
+    CHECK (direction = 'inbound' OR organization_id IS NOT NULL);
+
+CREATE UNIQUE INDEX idx_messages_inbound_wa_id ON messages(wa_message_id, created_at) WHERE direction = 'inbound';
+CREATE INDEX idx_messages_inbound_sender ON messages(sender_phone, created_at) WHERE direction = 'inbound';
+CREATE INDEX idx_messages_outbound_recipient ON messages(recipient_phone, created_at) WHERE direction = 'outbound';
+
+COMMENT ON COLUMN messages.direction IS 'Whether the message was sent by the business (outbound) or received from a customer (inbound)';
+COMMENT ON COLUMN messages.sender_phone IS 'Customer phone an inbound message was received from';
+COMMENT ON COLUMN messages.wa_message_id IS 'WhatsApp ID of an inbound message, used to ignore redelivered webhooks';
+COMMENT ON COLUMN messages.raw_payload IS 'Webhook payload an inbound message was received in';
@@ -0,0 +1,12 @@
+-- Migration: Remove Message Read Time
+-- Version: 1.0.0
+-- Description: Drops the read time column and returns read messages to delivered
+
+BEGIN;
+
+UPDATE messages SET status = 'delivered' WHERE status = 'read';
+
+DROP INDEX IF EXISTS idx_messages_read;
+ALTER TABLE messages DROP COLUMN IF EXISTS read_at;
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Migration: Add Message Read Time
+-- Version: 1.0.0
+-- Description: Records when recipients read messages; status may now also be 'read' after 'delivered'
+
+ALTER TABLE messages ADD COLUMN read_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_messages_read ON messages(organization_id, read_at) WHERE read_at IS NOT NULL;
+
+COMMENT ON COLUMN messages.read_at IS 'When WhatsApp first reported the message as read, used for open rates';
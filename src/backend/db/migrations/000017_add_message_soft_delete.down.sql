@@ -0,0 +1,10 @@
+-- Migration: Remove Message Soft Delete
+-- Version: 1.0.0
+-- Description: Drops the soft-delete column, restoring soft-deleted messages
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_messages_deleted_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+
+COMMIT;
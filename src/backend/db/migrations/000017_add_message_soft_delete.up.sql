@@ -0,0 +1,9 @@
+-- Migration: Add Message Soft Delete
+-- Version: 1.0.0
+-- Description: Lets messages be hidden before the retention purge removes them for good
+
+ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_messages_deleted_at ON messages(deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMENT ON COLUMN messages.deleted_at IS 'When the message was soft-deleted; such messages are excluded from reads and purged after the retention period';
@@ -0,0 +1,6 @@
+-- Migration: Remove Message Status Guard
+-- Version: 1.0.0
+-- Description: Drops the functions guarding status updates against stale delivery statuses
+
+DROP FUNCTION IF EXISTS message_status_advances(TEXT, TEXT);
+DROP FUNCTION IF EXISTS message_status_rank(TEXT);
@@ -0,0 +1,30 @@
+-- Migration: Add Message Status Guard
+-- Version: 1.0.0
+-- Description: Keeps delivery statuses from moving backward when webhooks arrive out of order
+
+-- Ranks the delivery lifecycle pending < processing < sent < delivered < read; other
+-- statuses rank -1. Must match statusRank in the message service's models package.
+CREATE OR REPLACE FUNCTION message_status_rank(status TEXT)
+RETURNS INTEGER AS $$
+    SELECT CASE status
+        WHEN 'pending' THEN 0
+        WHEN 'processing' THEN 1
+        WHEN 'sent' THEN 2
+        WHEN 'delivered' THEN 3
+        WHEN 'read' THEN 4
+        ELSE -1
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Reports whether a message may move from from_status to to_status. Once sent, a delivery
+-- status ranked at or below the current one is stale and refused; other moves are allowed.
+CREATE OR REPLACE FUNCTION message_status_advances(from_status TEXT, to_status TEXT)
+RETURNS BOOLEAN AS $$
+    SELECT NOT (
+        message_status_rank(from_status) >= 0
+        AND message_status_rank(to_status) >= message_status_rank('sent')
+        AND message_status_rank(to_status) <= message_status_rank(from_status)
+    )
+$$ LANGUAGE SQL IMMUTABLE;
+
+COMMENT ON FUNCTION message_status_advances(TEXT, TEXT) IS 'Whether a status update is not stale, used to guard status writes against out-of-order webhooks';
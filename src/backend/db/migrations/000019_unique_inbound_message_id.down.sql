@@ -0,0 +1,10 @@
+-- Migration: Revert Unique Inbound Message ID
+-- Version: 1.0.0
+-- Description: Restores the inbound message index keyed by WhatsApp ID and receive time
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_messages_inbound_wa_id;
+CREATE UNIQUE INDEX idx_messages_inbound_wa_id ON messages(wa_message_id, created_at) WHERE direction = 'inbound';
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Migration: Unique Inbound Message ID
+-- Version: 1.0.0
+-- Description: Deduplicates inbound messages by WhatsApp ID alone, since redelivered webhooks may carry a different receive time
+
+BEGIN;
+
+-- Keep the first stored copy of each inbound message
+DELETE FROM messages AS m
+USING messages AS earlier
+WHERE m.direction = 'inbound'
+AND earlier.direction = 'inbound'
+AND m.wa_message_id = earlier.wa_message_id
+AND (earlier.created_at, earlier.id) < (m.created_at, m.id);
+
+DROP INDEX IF EXISTS idx_messages_inbound_wa_id;
+CREATE UNIQUE INDEX idx_messages_inbound_wa_id ON messages(wa_message_id) WHERE direction = 'inbound';
+
+COMMIT;
@@ -17,6 +17,12 @@ type Config struct {
 	WhatsApp     WhatsAppConfig
 	Redis        RedisConfig
 	MessageQueue MessageQueueConfig
+	Webhook      WebhookConfig
+	RateLimit    RateLimitConfig
+	Sandbox      SandboxConfig
+	Retention    RetentionConfig
+	CircuitBreaker CircuitBreakerConfig
+	Media        MediaConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -59,12 +65,93 @@ type RedisConfig struct {
 	PoolSize int    `mapstructure:"pool_size"`
 }
 
-// MessageQueueConfig holds message processing configuration
+// MessageQueueConfig holds message processing configuration.
+// ProcessingInterval is also how often scheduled messages are polled, so it bounds how late
+// a scheduled message can fire. ScheduleLookback is how far back the first poll after
+// startup looks for scheduled messages that were missed while the service was down.
+// VisibilityTimeout is how long a claimed message may stay unacknowledged before it is
+// handed to another consumer; it must exceed the longest send including retries.
 type MessageQueueConfig struct {
 	BatchSize          int           `mapstructure:"batch_size"`
 	ProcessingInterval time.Duration `mapstructure:"processing_interval"`
+	ScheduleLookback   time.Duration `mapstructure:"schedule_lookback"`
 	RetryLimit         int           `mapstructure:"retry_limit"`
 	RetryDelay         time.Duration `mapstructure:"retry_delay"`
+	VisibilityTimeout  time.Duration `mapstructure:"visibility_timeout"`
+}
+
+// WebhookConfig holds asynchronous webhook processing configuration.
+// AutoMarkRead marks inbound messages as read once they have been processed.
+// DedupTTL is how long a received event is remembered to skip its redeliveries.
+type WebhookConfig struct {
+	Workers      int           `mapstructure:"workers"`
+	QueueSize    int           `mapstructure:"queue_size"`
+	AutoMarkRead bool          `mapstructure:"auto_mark_read"`
+	DedupTTL     time.Duration `mapstructure:"dedup_ttl"`
+}
+
+// RateLimitConfig holds per-recipient and per-organization message rate limiting
+// configuration. A zero RecipientMaxMessages disables the recipient limit and a zero
+// OrganizationRate disables the organization limit. OrganizationRate is messages per minute;
+// OrganizationRates overrides it for individual organizations, where zero means unlimited.
+type RateLimitConfig struct {
+	RecipientMaxMessages int            `mapstructure:"recipient_max_messages"`
+	RecipientWindow      time.Duration  `mapstructure:"recipient_window"`
+	RecipientMode        string         `mapstructure:"recipient_mode"`
+	OrganizationRate     int            `mapstructure:"organization_rate"`
+	OrganizationBurst    int            `mapstructure:"organization_burst"`
+	OrganizationRates    map[string]int `mapstructure:"organization_rates"`
+}
+
+// SandboxConfig holds test-traffic routing configuration. When enabled, messages flagged as
+// test or addressed to a listed prefix are delivered to the sandbox number instead.
+type SandboxConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Number   string   `mapstructure:"number"`
+	Prefixes []string `mapstructure:"prefixes"`
+}
+
+// CircuitBreakerConfig holds circuit breaker thresholds. The breaker opens once at least
+// MinRequests calls were made in the current Interval and FailureRatio of them failed, and
+// stays open for Timeout before letting trial calls through. A zero Interval never resets
+// the counts while closed.
+type CircuitBreakerConfig struct {
+	MinRequests  uint32        `mapstructure:"min_requests"`
+	FailureRatio float64       `mapstructure:"failure_ratio"`
+	Interval     time.Duration `mapstructure:"interval"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+}
+
+// Validate checks that the thresholds describe a usable breaker
+func (c CircuitBreakerConfig) Validate() error {
+	if c.MinRequests == 0 {
+		return fmt.Errorf("circuit breaker min requests must be positive")
+	}
+	if c.FailureRatio <= 0 || c.FailureRatio > 1 {
+		return fmt.Errorf("circuit breaker failure ratio must be in (0, 1], got %g", c.FailureRatio)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("circuit breaker interval cannot be negative")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("circuit breaker timeout must be positive")
+	}
+	return nil
+}
+
+// RetentionConfig holds message retention configuration. Messages older than Period, or
+// soft-deleted longer ago than Period, are purged every PurgeInterval. A zero Period
+// keeps messages forever.
+type RetentionConfig struct {
+	Period        time.Duration `mapstructure:"period"`
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+}
+
+// MediaConfig holds media attachment configuration. SizeLimits lowers the size limit in
+// bytes of individual MIME types below the WhatsApp Business API maximum, such as
+// "image/jpeg: 2097152"; types not listed keep the API maximum.
+type MediaConfig struct {
+	SizeLimits map[string]int64 `mapstructure:"size_limits"`
 }
 
 // LoadConfig loads and validates the service configuration from environment variables and config files
@@ -133,8 +220,36 @@ func setDefaults(v *viper.Viper) {
 	// Message queue defaults
 	v.SetDefault("message_queue.batch_size", 100)
 	v.SetDefault("message_queue.processing_interval", "5s")
+	v.SetDefault("message_queue.schedule_lookback", "1m")
 	v.SetDefault("message_queue.retry_limit", 3)
 	v.SetDefault("message_queue.retry_delay", "10s")
+	v.SetDefault("message_queue.visibility_timeout", "5m")
+
+	// Webhook defaults
+	v.SetDefault("webhook.workers", 10)
+	v.SetDefault("webhook.queue_size", 1000)
+	v.SetDefault("webhook.auto_mark_read", false)
+	v.SetDefault("webhook.dedup_ttl", "24h")
+
+	// Rate limit defaults
+	v.SetDefault("rate_limit.recipient_max_messages", 0)
+	v.SetDefault("rate_limit.recipient_window", "1h")
+	v.SetDefault("rate_limit.recipient_mode", "delay")
+	v.SetDefault("rate_limit.organization_rate", 0)
+	v.SetDefault("rate_limit.organization_burst", 0)
+
+	// Sandbox defaults
+	v.SetDefault("sandbox.enabled", false)
+
+	// Circuit breaker defaults
+	v.SetDefault("circuit_breaker.min_requests", 3)
+	v.SetDefault("circuit_breaker.failure_ratio", 0.6)
+	v.SetDefault("circuit_breaker.interval", "5s")
+	v.SetDefault("circuit_breaker.timeout", "30s")
+
+	// Retention defaults
+	v.SetDefault("retention.period", 0)
+	v.SetDefault("retention.purge_interval", "1h")
 }
 
 // validate checks if all required configuration values are present and valid
@@ -178,9 +293,76 @@ func (cfg *Config) validate() error {
 	if cfg.MessageQueue.BatchSize <= 0 {
 		return fmt.Errorf("message queue batch size must be positive")
 	}
+	if cfg.MessageQueue.ProcessingInterval < time.Second {
+		return fmt.Errorf("message queue processing interval must be at least 1s")
+	}
+	if cfg.MessageQueue.ScheduleLookback < 0 {
+		return fmt.Errorf("message queue schedule lookback cannot be negative")
+	}
 	if cfg.MessageQueue.RetryLimit < 0 {
 		return fmt.Errorf("message queue retry limit cannot be negative")
 	}
+	if cfg.MessageQueue.VisibilityTimeout < time.Minute {
+		return fmt.Errorf("message queue visibility timeout must be at least 1m")
+	}
+
+	// Validate Webhook configuration
+	if cfg.Webhook.Workers <= 0 {
+		return fmt.Errorf("webhook workers must be positive")
+	}
+	if cfg.Webhook.QueueSize <= 0 {
+		return fmt.Errorf("webhook queue size must be positive")
+	}
+	if cfg.Webhook.DedupTTL <= 0 {
+		return fmt.Errorf("webhook dedup TTL must be positive")
+	}
+
+	// Validate RateLimit configuration
+	if cfg.RateLimit.RecipientMaxMessages < 0 {
+		return fmt.Errorf("recipient max messages cannot be negative")
+	}
+	if cfg.RateLimit.RecipientMaxMessages > 0 {
+		if cfg.RateLimit.RecipientWindow <= 0 {
+			return fmt.Errorf("recipient rate limit window must be positive")
+		}
+		if cfg.RateLimit.RecipientMode != "delay" && cfg.RateLimit.RecipientMode != "reject" {
+			return fmt.Errorf("invalid recipient rate limit mode: %s", cfg.RateLimit.RecipientMode)
+		}
+	}
+
+	if cfg.RateLimit.OrganizationRate < 0 || cfg.RateLimit.OrganizationBurst < 0 {
+		return fmt.Errorf("organization rate limit and burst cannot be negative")
+	}
+	for org, orgRate := range cfg.RateLimit.OrganizationRates {
+		if orgRate < 0 {
+			return fmt.Errorf("organization rate limit for %s cannot be negative", org)
+		}
+	}
+
+	// Validate Sandbox configuration
+	if cfg.Sandbox.Enabled && cfg.Sandbox.Number == "" {
+		return fmt.Errorf("sandbox number is required when sandbox routing is enabled")
+	}
+
+	// Validate CircuitBreaker configuration
+	if err := cfg.CircuitBreaker.Validate(); err != nil {
+		return err
+	}
+
+	// Validate Retention configuration
+	if cfg.Retention.Period < 0 {
+		return fmt.Errorf("retention period cannot be negative")
+	}
+	if cfg.Retention.Period > 0 && cfg.Retention.PurgeInterval < time.Minute {
+		return fmt.Errorf("retention purge interval must be at least 1m")
+	}
+
+	// Validate Media configuration; limits above the API maximum are rejected when applied
+	for mediaType, limit := range cfg.Media.SizeLimits {
+		if limit <= 0 {
+			return fmt.Errorf("media size limit for %s must be positive", mediaType)
+		}
+	}
 
 	return nil
 }
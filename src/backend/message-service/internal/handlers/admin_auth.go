@@ -0,0 +1,131 @@
+// Package handlers provides authentication for the message service's administrative routes
+// Version: go1.21
+package handlers
+
+import (
+    "crypto/hmac"     // go1.21
+    "crypto/sha256"   // go1.21
+    "encoding/base64" // go1.21
+    "encoding/json"   // go1.21
+    "errors"          // go1.21
+    "net/http"        // go1.21
+    "strings"         // go1.21
+    "time"            // go1.21
+
+    "github.com/gin-gonic/gin" // v1.9.1
+)
+
+// Claims the API gateway puts in the tokens it issues and forwards to this service
+const (
+    adminRole     = "ADMIN"
+    tokenIssuer   = "whatsapp-web-enhancement"
+    tokenAudience = "api-gateway"
+)
+
+// adminUserKey is the gin context key holding the ID of the authenticated admin
+const adminUserKey = "admin_user_id"
+
+var errInvalidToken = errors.New("invalid or expired token")
+
+// gatewayClaims are the token claims checked by RequireAdmin
+type gatewayClaims struct {
+    ID       string          `json:"id"`
+    Role     string          `json:"role"`
+    Issuer   string          `json:"iss"`
+    Audience json.RawMessage `json:"aud"`
+    Expiry   int64           `json:"exp"`
+}
+
+// RequireAdmin authenticates the gateway's HS256 bearer token forwarded with the request
+// and authorizes only the ADMIN role, as the gateway's authenticate and authorize middleware
+// do for its privileged routes. Missing or invalid tokens get 401, other roles 403. An empty
+// secret rejects every request.
+func RequireAdmin(secret []byte) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        header := c.GetHeader("Authorization")
+        if !strings.HasPrefix(header, "Bearer ") {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "no authorization token provided"})
+            return
+        }
+
+        claims, err := verifyGatewayToken(strings.TrimPrefix(header, "Bearer "), secret, time.Now())
+        if err != nil {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+            return
+        }
+        if claims.Role != adminRole {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions for this operation"})
+            return
+        }
+
+        c.Set(adminUserKey, claims.ID)
+        c.Next()
+    }
+}
+
+// verifyGatewayToken checks the signature, issuer, audience and expiry of an HS256 token
+func verifyGatewayToken(token string, secret []byte, now time.Time) (*gatewayClaims, error) {
+    if len(secret) == 0 {
+        return nil, errInvalidToken
+    }
+
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 {
+        return nil, errInvalidToken
+    }
+
+    var header struct {
+        Alg string `json:"alg"`
+    }
+    if err := decodeTokenPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+        return nil, errInvalidToken
+    }
+
+    signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, errInvalidToken
+    }
+    mac := hmac.New(sha256.New, secret)
+    mac.Write([]byte(parts[0] + "." + parts[1]))
+    if !hmac.Equal(signature, mac.Sum(nil)) {
+        return nil, errInvalidToken
+    }
+
+    var claims gatewayClaims
+    if err := decodeTokenPart(parts[1], &claims); err != nil {
+        return nil, errInvalidToken
+    }
+    if claims.Issuer != tokenIssuer || !hasAudience(claims.Audience, tokenAudience) {
+        return nil, errInvalidToken
+    }
+    if claims.Expiry == 0 || !now.Before(time.Unix(claims.Expiry, 0)) {
+        return nil, errInvalidToken
+    }
+    return &claims, nil
+}
+
+func decodeTokenPart(part string, v interface{}) error {
+    data, err := base64.RawURLEncoding.DecodeString(part)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(data, v)
+}
+
+// hasAudience reports whether the aud claim, a string or an array of strings, names want
+func hasAudience(raw json.RawMessage, want string) bool {
+    var single string
+    if json.Unmarshal(raw, &single) == nil {
+        return single == want
+    }
+    var list []string
+    if json.Unmarshal(raw, &list) != nil {
+        return false
+    }
+    for _, aud := range list {
+        if aud == want {
+            return true
+        }
+    }
+    return false
+}
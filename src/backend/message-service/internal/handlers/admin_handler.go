@@ -0,0 +1,123 @@
+// Package handlers provides administrative HTTP handlers for operating the message service
+// Version: go1.21
+package handlers
+
+import (
+    "errors"
+    "log"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "message-service/pkg/whatsapp"
+)
+
+// ConsumerController is the subset of the queue consumer exposed to operators
+type ConsumerController interface {
+    Pause()
+    Resume()
+    IsPaused() bool
+    Health() map[string]interface{}
+}
+
+// CircuitBreakerController is the subset of the WhatsApp client used to inspect and reset
+// its circuit breaker
+type CircuitBreakerController interface {
+    CircuitState() string
+    ResetCircuitBreaker()
+}
+
+// circuitResetRequest must name a reason so forced resets are traceable in the logs
+type circuitResetRequest struct {
+    Reason string `json:"reason" binding:"required"`
+}
+
+// AdminHandler exposes runtime controls for the message consumer and WhatsApp client
+type AdminHandler struct {
+    consumer ConsumerController
+    breaker  CircuitBreakerController
+}
+
+// NewAdminHandler creates a new AdminHandler for the given consumer
+func NewAdminHandler(consumer ConsumerController) (*AdminHandler, error) {
+    if consumer == nil {
+        return nil, errors.New("consumer controller is required")
+    }
+    return &AdminHandler{consumer: consumer}, nil
+}
+
+// RegisterRoutes mounts the admin endpoints on router. Consumer health stays open for
+// readiness probes; every other route is privileged and runs behind auth, normally
+// RequireAdmin.
+func (h *AdminHandler) RegisterRoutes(router gin.IRouter, auth gin.HandlerFunc) {
+    router.GET("/admin/consumer/health", h.HandleConsumerHealth)
+
+    admin := router.Group("/admin", auth)
+    admin.POST("/consumer/pause", h.HandlePauseConsumer)
+    admin.POST("/consumer/resume", h.HandleResumeConsumer)
+    admin.GET("/circuit-breaker", h.HandleCircuitState)
+    admin.POST("/circuit-breaker/reset", h.HandleResetCircuitBreaker)
+}
+
+// SetCircuitBreaker enables the circuit breaker endpoints for the given client
+func (h *AdminHandler) SetCircuitBreaker(breaker CircuitBreakerController) {
+    h.breaker = breaker
+}
+
+// HandlePauseConsumer stops the consumer from fetching new messages
+func (h *AdminHandler) HandlePauseConsumer(c *gin.Context) {
+    h.consumer.Pause()
+    c.JSON(http.StatusOK, h.consumer.Health())
+}
+
+// HandleResumeConsumer resumes message fetching after a pause
+func (h *AdminHandler) HandleResumeConsumer(c *gin.Context) {
+    h.consumer.Resume()
+    c.JSON(http.StatusOK, h.consumer.Health())
+}
+
+// HandleConsumerHealth reports the consumer state. A paused consumer is reported as
+// degraded but still returns 200 so the pod is not restarted during an incident.
+func (h *AdminHandler) HandleConsumerHealth(c *gin.Context) {
+    health := h.consumer.Health()
+    health["status"] = "ok"
+    if h.consumer.IsPaused() {
+        health["status"] = "paused"
+    }
+    c.JSON(http.StatusOK, health)
+}
+
+// HandleCircuitState reports the WhatsApp client's circuit breaker state
+func (h *AdminHandler) HandleCircuitState(c *gin.Context) {
+    if h.breaker == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "circuit breaker control is not configured"})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"state": h.breaker.CircuitState()})
+}
+
+// HandleResetCircuitBreaker moves an open circuit breaker to half-open so the next request
+// probes the API. It requires a reason and refuses to act unless the breaker is open. It is
+// privileged and must only be reached through RegisterRoutes' authenticated group.
+func (h *AdminHandler) HandleResetCircuitBreaker(c *gin.Context) {
+    if h.breaker == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "circuit breaker control is not configured"})
+        return
+    }
+
+    var req circuitResetRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "a reason for the reset is required"})
+        return
+    }
+
+    state := h.breaker.CircuitState()
+    if state != whatsapp.CircuitStateOpen {
+        c.JSON(http.StatusConflict, gin.H{"error": "circuit breaker is not open", "state": state})
+        return
+    }
+
+    h.breaker.ResetCircuitBreaker()
+    log.Printf("Circuit breaker reset from %s by admin %s (%s): %s", state, c.GetString(adminUserKey), c.ClientIP(), req.Reason)
+    c.JSON(http.StatusOK, gin.H{"previous_state": state, "state": h.breaker.CircuitState()})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "message-service/pkg/whatsapp"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+type fakeConsumer struct{ paused bool }
+
+func (f *fakeConsumer) Pause()                         { f.paused = true }
+func (f *fakeConsumer) Resume()                        { f.paused = false }
+func (f *fakeConsumer) IsPaused() bool                 { return f.paused }
+func (f *fakeConsumer) Health() map[string]interface{} { return map[string]interface{}{"paused": f.paused} }
+
+type fakeBreaker struct {
+    state  string
+    resets int
+}
+
+func (f *fakeBreaker) CircuitState() string { return f.state }
+
+func (f *fakeBreaker) ResetCircuitBreaker() {
+    f.resets++
+    f.state = whatsapp.CircuitStateHalfOpen
+}
+
+// signToken issues a token the way the API gateway does
+func signToken(t *testing.T, secret []byte, claims map[string]interface{}) string {
+    t.Helper()
+    encode := func(v interface{}) string {
+        data, err := json.Marshal(v)
+        require.NoError(t, err)
+        return base64.RawURLEncoding.EncodeToString(data)
+    }
+
+    unsigned := encode(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encode(claims)
+    mac := hmac.New(sha256.New, secret)
+    mac.Write([]byte(unsigned))
+    return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func gatewayClaimsFor(role string, expiry time.Time) map[string]interface{} {
+    return map[string]interface{}{
+        "id":   "user-1",
+        "role": role,
+        "iss":  tokenIssuer,
+        "aud":  tokenAudience,
+        "exp":  expiry.Unix(),
+    }
+}
+
+func newAdminRouter(t *testing.T, breaker *fakeBreaker) *gin.Engine {
+    t.Helper()
+    gin.SetMode(gin.TestMode)
+
+    handler, err := NewAdminHandler(&fakeConsumer{})
+    require.NoError(t, err)
+    handler.SetCircuitBreaker(breaker)
+
+    router := gin.New()
+    handler.RegisterRoutes(router, RequireAdmin(testSecret))
+    return router
+}
+
+func resetCircuit(router *gin.Engine, token string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodPost, "/admin/circuit-breaker/reset", strings.NewReader(`{"reason":"incident resolved"}`))
+    req.Header.Set("Content-Type", "application/json")
+    if token != "" {
+        req.Header.Set("Authorization", "Bearer "+token)
+    }
+    rec := httptest.NewRecorder()
+    router.ServeHTTP(rec, req)
+    return rec
+}
+
+func TestResetCircuitBreakerRejectsUnauthorizedCallers(t *testing.T) {
+    valid := time.Now().Add(time.Hour)
+    tests := []struct {
+        name   string
+        token  string
+        status int
+    }{
+        {"missing token", "", http.StatusUnauthorized},
+        {"malformed token", "not-a-token", http.StatusUnauthorized},
+        {"wrong secret", signToken(t, []byte("another-secret-another-secret-00"), gatewayClaimsFor(adminRole, valid)), http.StatusUnauthorized},
+        {"expired token", signToken(t, testSecret, gatewayClaimsFor(adminRole, time.Now().Add(-time.Minute))), http.StatusUnauthorized},
+        {"non-admin role", signToken(t, testSecret, gatewayClaimsFor("MANAGER", valid)), http.StatusForbidden},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            breaker := &fakeBreaker{state: whatsapp.CircuitStateOpen}
+            rec := resetCircuit(newAdminRouter(t, breaker), tt.token)
+
+            assert.Equal(t, tt.status, rec.Code)
+            assert.Equal(t, 0, breaker.resets, "the breaker must not be touched")
+            assert.Equal(t, whatsapp.CircuitStateOpen, breaker.state)
+        })
+    }
+}
+
+func TestResetCircuitBreakerAcceptsAdmin(t *testing.T) {
+    breaker := &fakeBreaker{state: whatsapp.CircuitStateOpen}
+    token := signToken(t, testSecret, gatewayClaimsFor(adminRole, time.Now().Add(time.Hour)))
+
+    rec := resetCircuit(newAdminRouter(t, breaker), token)
+
+    require.Equal(t, http.StatusOK, rec.Code)
+    assert.Equal(t, 1, breaker.resets)
+    assert.JSONEq(t, `{"previous_state":"open","state":"half_open"}`, rec.Body.String())
+}
+
+func TestRequireAdminWithoutSecretRejectsEveryone(t *testing.T) {
+    token := signToken(t, nil, gatewayClaimsFor(adminRole, time.Now().Add(time.Hour)))
+
+    _, err := verifyGatewayToken(token, nil, time.Now())
+
+    assert.ErrorIs(t, err, errInvalidToken)
+}
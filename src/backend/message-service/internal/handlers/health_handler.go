@@ -0,0 +1,44 @@
+// Package handlers provides health and readiness probes for the message service
+// Version: go1.21
+package handlers
+
+import (
+    "context"
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+)
+
+// ReadinessChecker reports whether the service is ready for traffic and why
+type ReadinessChecker interface {
+    Ready(ctx context.Context) (bool, map[string]string)
+}
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+    readiness ReadinessChecker
+}
+
+// NewHealthHandler creates a new HealthHandler backed by the given readiness checker
+func NewHealthHandler(readiness ReadinessChecker) (*HealthHandler, error) {
+    if readiness == nil {
+        return nil, errors.New("readiness checker is required")
+    }
+    return &HealthHandler{readiness: readiness}, nil
+}
+
+// HandleLiveness reports that the process is up
+func (h *HealthHandler) HandleLiveness(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// HandleReadiness returns 503 until warmup has completed and all dependencies respond
+func (h *HealthHandler) HandleReadiness(c *gin.Context) {
+    ready, checks := h.readiness.Ready(c.Request.Context())
+    if !ready {
+        c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "checks": checks})
+        return
+    }
+    c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
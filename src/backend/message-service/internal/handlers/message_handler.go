@@ -5,6 +5,7 @@ package handlers
 import (
     "context"
     "encoding/json"
+    "errors"
     "net/http"
     "sync"
     "time"
@@ -19,6 +20,8 @@ import (
 
     "message-service/internal/models"
     "message-service/internal/services"
+    "message-service/internal/utils"
+    "message-service/pkg/whatsapp/types"
 )
 
 // Metrics collectors
@@ -101,7 +104,7 @@ func (h *MessageHandler) HandleSendMessage(c *gin.Context) {
     // Apply rate limiting
     if err := h.rateLimiter.Wait(ctx); err != nil {
         requestTotal.WithLabelValues("send_message", "rate_limited").Inc()
-        c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+        respondError(c, http.StatusTooManyRequests, "rate limit exceeded", "")
         return
     }
 
@@ -109,7 +112,7 @@ func (h *MessageHandler) HandleSendMessage(c *gin.Context) {
     var msg models.Message
     if err := c.ShouldBindJSON(&msg); err != nil {
         requestTotal.WithLabelValues("send_message", "invalid_request").Inc()
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request format"})
+        respondError(c, http.StatusBadRequest, "invalid request format", "")
         return
     }
 
@@ -117,8 +120,11 @@ func (h *MessageHandler) HandleSendMessage(c *gin.Context) {
     ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
     defer cancel()
 
-    // Process message through circuit breaker
+    // Process message through circuit breaker, splitting over-long text when asked to
     result, err := h.circuitBreaker.Execute(func() (interface{}, error) {
+        if msg.SplitLongText {
+            return nil, h.messageService.SendSplit(ctx, &msg)
+        }
         return nil, h.messageService.ProcessMessage(ctx, &msg)
     })
 
@@ -126,21 +132,22 @@ func (h *MessageHandler) HandleSendMessage(c *gin.Context) {
         requestTotal.WithLabelValues("send_message", "error").Inc()
         span.SetTag("error", true)
         span.LogKV("error.message", err.Error())
+
+        if respondValidationError(c, err) {
+            return
+        }
         
         status := http.StatusInternalServerError
         if err == gobreaker.ErrOpenState {
             status = http.StatusServiceUnavailable
         }
         
-        c.JSON(status, gin.H{"error": err.Error()})
+        respondError(c, status, err.Error(), "")
         return
     }
 
     requestTotal.WithLabelValues("send_message", "success").Inc()
-    c.JSON(http.StatusAccepted, gin.H{
-        "message_id": msg.ID,
-        "status": "accepted",
-    })
+    c.JSON(http.StatusAccepted, serializerFor(c).MessageAccepted(&msg))
 }
 
 // HandleSendBatchMessages handles batch message processing with enhanced reliability
@@ -155,17 +162,17 @@ func (h *MessageHandler) HandleSendBatchMessages(c *gin.Context) {
     var messages []*models.Message
     if err := c.ShouldBindJSON(&messages); err != nil {
         requestTotal.WithLabelValues("send_batch", "invalid_request").Inc()
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch format"})
+        respondError(c, http.StatusBadRequest, "invalid batch format", "")
         return
     }
 
     // Validate batch size
     if len(messages) == 0 {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "empty batch"})
+        respondError(c, http.StatusBadRequest, "empty batch", "")
         return
     }
     if len(messages) > maxBatchSize {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "batch size exceeds limit"})
+        respondError(c, http.StatusBadRequest, "batch size exceeds limit", "")
         return
     }
 
@@ -184,21 +191,22 @@ func (h *MessageHandler) HandleSendBatchMessages(c *gin.Context) {
         requestTotal.WithLabelValues("send_batch", "error").Inc()
         span.SetTag("error", true)
         span.LogKV("error.message", err.Error())
+
+        if respondValidationError(c, err) {
+            return
+        }
         
         status := http.StatusInternalServerError
         if err == gobreaker.ErrOpenState {
             status = http.StatusServiceUnavailable
         }
         
-        c.JSON(status, gin.H{"error": err.Error()})
+        respondError(c, status, err.Error(), "")
         return
     }
 
     requestTotal.WithLabelValues("send_batch", "success").Inc()
-    c.JSON(http.StatusAccepted, gin.H{
-        "batch_size": len(messages),
-        "status": "accepted",
-    })
+    c.JSON(http.StatusAccepted, serializerFor(c).BatchAccepted(len(messages)))
 }
 
 // HandleScheduleMessage handles message scheduling with validation
@@ -212,16 +220,24 @@ func (h *MessageHandler) HandleScheduleMessage(c *gin.Context) {
     var msg models.Message
     if err := c.ShouldBindJSON(&msg); err != nil {
         requestTotal.WithLabelValues("schedule", "invalid_request").Inc()
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message format"})
+        respondError(c, http.StatusBadRequest, "invalid message format", "")
         return
     }
 
     if msg.ScheduledAt == nil || msg.ScheduledAt.Before(time.Now()) {
         requestTotal.WithLabelValues("schedule", "invalid_time").Inc()
-        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule time"})
+        respondError(c, http.StatusBadRequest, "invalid schedule time", "")
         return
     }
 
+    // Store and report the schedule in UTC at database precision so the response matches
+    // the stored time exactly
+    scheduledAt := msg.ScheduledAt.UTC().Truncate(time.Microsecond)
+    msg.ScheduledAt = &scheduledAt
+    if msg.Recurrence != nil && msg.Recurrence.Until != nil {
+        until := msg.Recurrence.Until.UTC()
+        msg.Recurrence.Until = &until
+    }
     msg.Status = models.MessageStatusScheduled
 
     ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
@@ -235,15 +251,53 @@ func (h *MessageHandler) HandleScheduleMessage(c *gin.Context) {
         requestTotal.WithLabelValues("schedule", "error").Inc()
         span.SetTag("error", true)
         span.LogKV("error.message", err.Error())
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        if respondValidationError(c, err) {
+            return
+        }
+        respondError(c, http.StatusInternalServerError, err.Error(), "")
         return
     }
 
     requestTotal.WithLabelValues("schedule", "success").Inc()
-    c.JSON(http.StatusAccepted, gin.H{
-        "message_id": msg.ID,
-        "scheduled_for": msg.ScheduledAt,
-        "status": "scheduled",
+    c.JSON(http.StatusAccepted, serializerFor(c).MessageScheduled(&msg))
+}
+
+// renderTemplateRequest is the body of a template preview request
+type renderTemplateRequest struct {
+    Template   *types.Template   `json:"template" binding:"required"`
+    Parameters map[string]string `json:"parameters"`
+}
+
+// HandleRenderTemplate previews a template's body with parameters substituted, without sending
+func (h *MessageHandler) HandleRenderTemplate(c *gin.Context) {
+    timer := prometheus.NewTimer(requestDuration.WithLabelValues("render_template", ""))
+    defer timer.ObserveDuration()
+
+    span, _ := opentracing.StartSpanFromContext(c.Request.Context(), "HandleRenderTemplate")
+    defer span.Finish()
+
+    var req renderTemplateRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        requestTotal.WithLabelValues("render_template", "invalid_request").Inc()
+        respondError(c, http.StatusBadRequest, "invalid request format", "")
+        return
+    }
+
+    rendered, err := utils.RenderTemplate(req.Template, req.Parameters)
+    if err != nil {
+        requestTotal.WithLabelValues("render_template", "invalid_template").Inc()
+        if respondValidationError(c, err) {
+            return
+        }
+        respondError(c, http.StatusBadRequest, err.Error(), "")
+        return
+    }
+
+    requestTotal.WithLabelValues("render_template", "success").Inc()
+    c.JSON(http.StatusOK, gin.H{
+        "name":     req.Template.Name,
+        "language": req.Template.Language,
+        "body":     rendered,
     })
 }
 
@@ -257,4 +311,19 @@ func (h *MessageHandler) GetMetrics() map[string]interface{} {
         "rate_limiter_limit": h.rateLimiter.Limit(),
         "rate_limiter_burst": h.rateLimiter.Burst(),
     }
+}
+
+// respondValidationError writes a coded validation error in the locale negotiated from the
+// Accept-Language header. It reports false if err does not carry a validation error.
+func respondValidationError(c *gin.Context, err error) bool {
+    var validationErr *utils.ValidationError
+    if !errors.As(err, &validationErr) {
+        return false
+    }
+
+    locale := utils.ResolveLocale(c.GetHeader("Accept-Language"))
+    c.Header("Content-Language", locale)
+
+    respondError(c, http.StatusBadRequest, validationErr.Localize(locale), validationErr.Code)
+    return true
 }
\ No newline at end of file
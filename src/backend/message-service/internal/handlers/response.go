@@ -0,0 +1,155 @@
+// Package handlers provides versioned response serialization for the message service API
+// Version: go1.21
+package handlers
+
+import (
+    "strings"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "message-service/internal/models"
+)
+
+// Supported API versions
+const (
+    APIVersionV1      = "v1"
+    APIVersionV2      = "v2"
+    DefaultAPIVersion = APIVersionV1
+
+    apiVersionHeader     = "API-Version"
+    apiPathPrefix        = "/api/"
+    versionedMediaPrefix = "application/vnd.message-service."
+)
+
+// ResponseSerializer shapes handler responses for one API version. Scheduled responses carry
+// the stored UTC schedule time and, for recurring messages, the following occurrence.
+type ResponseSerializer interface {
+    MessageAccepted(msg *models.Message) gin.H
+    BatchAccepted(count int) gin.H
+    MessageScheduled(msg *models.Message) gin.H
+    Error(message, code string) gin.H
+}
+
+// serializers maps each supported API version to its response shape
+var serializers = map[string]ResponseSerializer{
+    APIVersionV1: v1Serializer{},
+    APIVersionV2: v2Serializer{},
+}
+
+// resolveAPIVersion selects the API version from a leading /api/vN URL prefix, falling back
+// to an Accept header of the form application/vnd.message-service.vN+json, then the default.
+// Later path segments are resource IDs and never select a version.
+func resolveAPIVersion(c *gin.Context) string {
+    if rest, ok := strings.CutPrefix(c.Request.URL.Path, apiPathPrefix); ok {
+        version, _, _ := strings.Cut(rest, "/")
+        if _, ok := serializers[version]; ok {
+            return version
+        }
+    }
+
+    for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+        accept = strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+        if !strings.HasPrefix(accept, versionedMediaPrefix) {
+            continue
+        }
+        version := strings.TrimSuffix(strings.TrimPrefix(accept, versionedMediaPrefix), "+json")
+        if _, ok := serializers[version]; ok {
+            return version
+        }
+    }
+
+    return DefaultAPIVersion
+}
+
+// serializerFor returns the serializer for the request's API version and advertises the version
+func serializerFor(c *gin.Context) ResponseSerializer {
+    version := resolveAPIVersion(c)
+    c.Header(apiVersionHeader, version)
+    return serializers[version]
+}
+
+// respondError writes an error response in the request's API version
+func respondError(c *gin.Context, status int, message, code string) {
+    c.JSON(status, serializerFor(c).Error(message, code))
+}
+
+// v1Serializer produces the original flat response shape existing clients rely on;
+// external_ref is only returned from v2
+type v1Serializer struct{}
+
+func (v1Serializer) MessageAccepted(msg *models.Message) gin.H {
+    return gin.H{
+        "message_id": msg.ID,
+        "status": "accepted",
+    }
+}
+
+func (v1Serializer) BatchAccepted(count int) gin.H {
+    return gin.H{
+        "batch_size": count,
+        "status": "accepted",
+    }
+}
+
+func (v1Serializer) MessageScheduled(msg *models.Message) gin.H {
+    body := gin.H{
+        "message_id": msg.ID,
+        "scheduled_for": msg.ScheduledAt,
+        "status": "scheduled",
+    }
+    if next := msg.NextOccurrenceAt(); next != nil {
+        body["next_occurrence"] = next
+    }
+    return body
+}
+
+func (v1Serializer) Error(message, code string) gin.H {
+    body := gin.H{"error": message}
+    if code != "" {
+        body["code"] = code
+    }
+    return body
+}
+
+// v2Serializer wraps resources in a data envelope and errors in an error object
+type v2Serializer struct{}
+
+func (v2Serializer) MessageAccepted(msg *models.Message) gin.H {
+    return gin.H{
+        "data": gin.H{
+            "id":           msg.ID,
+            "external_ref": msg.ExternalRef,
+            "status":       "accepted",
+        },
+    }
+}
+
+func (v2Serializer) BatchAccepted(count int) gin.H {
+    return gin.H{
+        "data": gin.H{
+            "accepted": count,
+            "status":   "accepted",
+        },
+    }
+}
+
+func (v2Serializer) MessageScheduled(msg *models.Message) gin.H {
+    data := gin.H{
+        "id":            msg.ID,
+        "external_ref":  msg.ExternalRef,
+        "status":        "scheduled",
+        "scheduled_for": msg.ScheduledAt,
+    }
+    if next := msg.NextOccurrenceAt(); next != nil {
+        data["next_occurrence"] = next
+    }
+    return gin.H{"data": data}
+}
+
+func (v2Serializer) Error(message, code string) gin.H {
+    errBody := gin.H{"message": message}
+    if code != "" {
+        errBody["code"] = code
+    }
+    return gin.H{"error": errBody}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/stretchr/testify/assert"
+
+    "message-service/internal/models"
+)
+
+// respond serializes a response for a request to path, as a handler would
+func respond(path, accept string, body func(ResponseSerializer) gin.H) *httptest.ResponseRecorder {
+    gin.SetMode(gin.TestMode)
+    rec := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(rec)
+    c.Request = httptest.NewRequest(http.MethodPost, path, nil)
+    if accept != "" {
+        c.Request.Header.Set("Accept", accept)
+    }
+    c.JSON(http.StatusAccepted, body(serializerFor(c)))
+    return rec
+}
+
+func TestResolveAPIVersion(t *testing.T) {
+    tests := []struct {
+        name   string
+        path   string
+        accept string
+        want   string
+    }{
+        {"v1 prefix", "/api/v1/messages", "", APIVersionV1},
+        {"v2 prefix", "/api/v2/messages", "", APIVersionV2},
+        {"bare prefix", "/api/v2", "", APIVersionV2},
+        {"resource named like a version", "/api/v1/messages/v2", "", APIVersionV1},
+        {"unprefixed resource named like a version", "/messages/v2", "", DefaultAPIVersion},
+        {"version without the api prefix", "/v2/messages", "", DefaultAPIVersion},
+        {"accept header", "/messages", "application/vnd.message-service.v2+json", APIVersionV2},
+        {"accept header with parameters", "/messages", "text/plain, application/vnd.message-service.v2+json; q=0.9", APIVersionV2},
+        {"prefix wins over accept header", "/api/v1/messages", "application/vnd.message-service.v2+json", APIVersionV1},
+        {"unknown prefix falls back to accept header", "/api/v9/messages", "application/vnd.message-service.v2+json", APIVersionV2},
+        {"unknown accept version", "/messages", "application/vnd.message-service.v9+json", DefaultAPIVersion},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := respond(tt.path, tt.accept, func(s ResponseSerializer) gin.H { return s.BatchAccepted(1) })
+            assert.Equal(t, tt.want, rec.Header().Get(apiVersionHeader))
+        })
+    }
+}
+
+// TestV1ResponsesMatchOriginalShape pins the v1 bodies to the responses the API returned
+// before versioning was introduced
+func TestV1ResponsesMatchOriginalShape(t *testing.T) {
+    scheduled := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+    msg := &models.Message{ID: "msg-1", ExternalRef: "order-42", ScheduledAt: &scheduled}
+
+    tests := []struct {
+        name   string
+        body   func(ResponseSerializer) gin.H
+        golden string
+    }{
+        {"message accepted", func(s ResponseSerializer) gin.H { return s.MessageAccepted(msg) },
+            `{"message_id":"msg-1","status":"accepted"}`},
+        {"batch accepted", func(s ResponseSerializer) gin.H { return s.BatchAccepted(3) },
+            `{"batch_size":3,"status":"accepted"}`},
+        {"message scheduled", func(s ResponseSerializer) gin.H { return s.MessageScheduled(msg) },
+            `{"message_id":"msg-1","scheduled_for":"2024-03-01T09:30:00Z","status":"scheduled"}`},
+        {"error", func(s ResponseSerializer) gin.H { return s.Error("invalid request format", "") },
+            `{"error":"invalid request format"}`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := respond("/api/v1/messages", "", tt.body)
+            assert.JSONEq(t, tt.golden, rec.Body.String())
+
+            unversioned := respond("/messages", "", tt.body)
+            assert.JSONEq(t, tt.golden, unversioned.Body.String(), "unversioned requests get v1")
+        })
+    }
+}
+
+func TestV2ResponsesUseEnvelope(t *testing.T) {
+    scheduled := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+    msg := &models.Message{ID: "msg-1", ExternalRef: "order-42", ScheduledAt: &scheduled}
+
+    tests := []struct {
+        name string
+        body func(ResponseSerializer) gin.H
+        want string
+    }{
+        {"message accepted", func(s ResponseSerializer) gin.H { return s.MessageAccepted(msg) },
+            `{"data":{"id":"msg-1","external_ref":"order-42","status":"accepted"}}`},
+        {"batch accepted", func(s ResponseSerializer) gin.H { return s.BatchAccepted(3) },
+            `{"data":{"accepted":3,"status":"accepted"}}`},
+        {"message scheduled", func(s ResponseSerializer) gin.H { return s.MessageScheduled(msg) },
+            `{"data":{"id":"msg-1","external_ref":"order-42","scheduled_for":"2024-03-01T09:30:00Z","status":"scheduled"}}`},
+        {"error", func(s ResponseSerializer) gin.H { return s.Error("empty batch", "EMPTY_BATCH") },
+            `{"error":{"message":"empty batch","code":"EMPTY_BATCH"}}`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rec := respond("/api/v2/messages", "", tt.body)
+            assert.JSONEq(t, tt.want, rec.Body.String())
+        })
+    }
+}
@@ -0,0 +1,105 @@
+// Package handlers provides deduplication of redelivered WhatsApp webhook events
+// Version: go1.21
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/go-redis/redis/v8" // v8.11.5
+
+    "github.com/yourdomain/message-service/pkg/whatsapp"
+)
+
+const (
+    // defaultWebhookDedupTTL is how long a processed event is remembered
+    defaultWebhookDedupTTL = 24 * time.Hour
+
+    webhookDedupKeyPrefix = "webhook:seen:"
+)
+
+// WebhookDedupStore remembers which webhook events have been accepted so redeliveries can
+// be skipped
+type WebhookDedupStore interface {
+    // MarkSeen records key for ttl and reports whether it was not already recorded
+    MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+    // Forget removes key so a redelivery of the event is accepted again
+    Forget(ctx context.Context, key string) error
+}
+
+// RedisDedupStore is a WebhookDedupStore shared by all service instances through Redis
+type RedisDedupStore struct {
+    client *redis.Client
+}
+
+// NewRedisDedupStore creates a dedup store backed by client
+func NewRedisDedupStore(client *redis.Client) (*RedisDedupStore, error) {
+    if client == nil {
+        return nil, fmt.Errorf("redis client is required")
+    }
+    return &RedisDedupStore{client: client}, nil
+}
+
+// MarkSeen records key with SETNX so only the first delivery is accepted
+func (s *RedisDedupStore) MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+    added, err := s.client.SetNX(ctx, webhookDedupKeyPrefix+key, 1, ttl).Result()
+    if err != nil {
+        return false, fmt.Errorf("mark webhook seen: %w", err)
+    }
+    return added, nil
+}
+
+// Forget deletes key
+func (s *RedisDedupStore) Forget(ctx context.Context, key string) error {
+    if err := s.client.Del(ctx, webhookDedupKeyPrefix+key).Err(); err != nil {
+        return fmt.Errorf("forget webhook: %w", err)
+    }
+    return nil
+}
+
+// MemoryDedupStore is a WebhookDedupStore local to one process, for tests and single
+// instance deployments
+type MemoryDedupStore struct {
+    mu      sync.Mutex
+    expires map[string]time.Time
+}
+
+// NewMemoryDedupStore creates an empty in-memory dedup store
+func NewMemoryDedupStore() *MemoryDedupStore {
+    return &MemoryDedupStore{expires: make(map[string]time.Time)}
+}
+
+// MarkSeen records key unless it is recorded and unexpired, dropping expired keys
+func (s *MemoryDedupStore) MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for k, expiresAt := range s.expires {
+        if !now.Before(expiresAt) {
+            delete(s.expires, k)
+        }
+    }
+
+    if _, ok := s.expires[key]; ok {
+        return false, nil
+    }
+    s.expires[key] = now.Add(ttl)
+    return true, nil
+}
+
+// Forget deletes key
+func (s *MemoryDedupStore) Forget(ctx context.Context, key string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.expires, key)
+    return nil
+}
+
+// webhookDedupKey identifies a delivery of an event: a message reaches each status once,
+// so a repeat of the same message, status and time is a redelivery
+func webhookDedupKey(event *whatsapp.WebhookEvent) string {
+    return fmt.Sprintf("%s:%s:%d", event.MessageID, event.Status, event.Timestamp.UnixNano())
+}
@@ -8,15 +8,20 @@ import (
     "fmt"
     "io"
     "net/http"
+    "strconv"
     "sync"
     "time"
 
     "github.com/gin-gonic/gin" // v1.9.1
+    "github.com/prometheus/client_golang/prometheus" // v1.16.0
+    "github.com/prometheus/client_golang/prometheus/promauto"
     "go.opentelemetry.io/otel" // v1.19.0
     "go.opentelemetry.io/otel/attribute"
     "go.opentelemetry.io/otel/trace"
 
     "github.com/yourdomain/message-service/pkg/whatsapp"
+    "github.com/yourdomain/message-service/internal/config"
+    "github.com/yourdomain/message-service/internal/repository"
     "github.com/yourdomain/message-service/internal/services"
 )
 
@@ -33,18 +38,64 @@ const (
 
     // retryBackoffDuration defines the base duration for retry backoff
     retryBackoffDuration = time.Second
+
+    // maxRetryDuration caps the total time spent retrying a single webhook event
+    maxRetryDuration = 30 * time.Second
+
+    // webhookProcessingTimeout bounds a queued event: the full retry budget plus time to
+    // mark the message as read afterwards
+    webhookProcessingTimeout = maxRetryDuration + webhookVerificationTimeout
+
+    // defaultWebhookWorkers and defaultWebhookQueueSize bound asynchronous processing
+    defaultWebhookWorkers   = 10
+    defaultWebhookQueueSize = 1000
+)
+
+// markReadTotal counts attempts to mark inbound messages as read by outcome
+var markReadTotal = promauto.NewCounterVec(
+    prometheus.CounterOpts{
+        Name: "webhook_mark_read_total",
+        Help: "Total number of inbound messages marked as read",
+    },
+    []string{"status"},
+)
+
+// webhookDuplicatesTotal counts redelivered webhook events that were skipped
+var webhookDuplicatesTotal = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "webhook_duplicates_total",
+        Help: "Total number of redelivered webhook events skipped",
+    },
 )
 
+// WebhookEventProcessor applies a webhook event, such as a status update, to the service.
+// services.WhatsAppService implements it.
+type WebhookEventProcessor interface {
+    ProcessWebhookEvent(ctx context.Context, event *whatsapp.WebhookEvent) error
+}
+
 // WebhookHandler handles incoming WhatsApp webhook events
 type WebhookHandler struct {
     whatsappClient  *whatsapp.Client
-    whatsappService *services.WhatsAppService
+    whatsappService WebhookEventProcessor
+    webhooks        *repository.WebhookRepository
     payloadPool     sync.Pool
     tracer         trace.Tracer
+    queue           chan *whatsapp.WebhookEvent
+    workers         sync.WaitGroup
+    queueMu         sync.RWMutex
+    closed          bool
+    autoMarkRead    bool
+    dedup           WebhookDedupStore
+    dedupTTL        time.Duration
 }
 
-// NewWebhookHandler creates a new WebhookHandler instance
-func NewWebhookHandler(whatsappClient *whatsapp.Client, whatsappService *services.WhatsAppService) (*WebhookHandler, error) {
+// NewWebhookHandler creates a new WebhookHandler instance. When webhooks is non-nil every
+// received event is persisted with its verification status for later replay. Accepted
+// events are processed by cfg.Workers background workers from a queue of cfg.QueueSize.
+// With cfg.AutoMarkRead inbound messages are marked read once processed. Redelivered events
+// are skipped for cfg.DedupTTL once a store is set with SetDedupStore.
+func NewWebhookHandler(whatsappClient *whatsapp.Client, whatsappService *services.WhatsAppService, webhooks *repository.WebhookRepository, cfg config.WebhookConfig) (*WebhookHandler, error) {
     if whatsappClient == nil {
         return nil, fmt.Errorf("whatsapp client is required")
     }
@@ -55,12 +106,32 @@ func NewWebhookHandler(whatsappClient *whatsapp.Client, whatsappService *service
     handler := &WebhookHandler{
         whatsappClient:  whatsappClient,
         whatsappService: whatsappService,
+        webhooks:        webhooks,
         payloadPool: sync.Pool{
             New: func() interface{} {
                 return make([]byte, 0, maxWebhookPayloadSize)
             },
         },
-        tracer: otel.Tracer("webhook-handler"),
+        tracer:       otel.Tracer("webhook-handler"),
+        autoMarkRead: cfg.AutoMarkRead,
+        dedupTTL:     cfg.DedupTTL,
+    }
+
+    if handler.dedupTTL <= 0 {
+        handler.dedupTTL = defaultWebhookDedupTTL
+    }
+
+    if cfg.Workers <= 0 {
+        cfg.Workers = defaultWebhookWorkers
+    }
+    if cfg.QueueSize <= 0 {
+        cfg.QueueSize = defaultWebhookQueueSize
+    }
+    handler.queue = make(chan *whatsapp.WebhookEvent, cfg.QueueSize)
+
+    handler.workers.Add(cfg.Workers)
+    for i := 0; i < cfg.Workers; i++ {
+        go handler.runWorker()
     }
 
     return handler, nil
@@ -97,34 +168,49 @@ func (h *WebhookHandler) HandleWebhook(c *gin.Context) {
     }
 
     // Verify webhook signature
-    if !h.whatsappClient.VerifySignature(body, signature) {
-        span.SetAttributes(attribute.String("error", "invalid_signature"))
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
-        return
-    }
+    verified := h.whatsappClient.VerifySignature(body, signature)
 
     // Parse webhook event
     var event whatsapp.WebhookEvent
     if err := json.Unmarshal(body, &event); err != nil {
+        if !verified {
+            span.SetAttributes(attribute.String("error", "invalid_signature"))
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+            return
+        }
         span.SetAttributes(attribute.String("error", "invalid_payload"))
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
         return
     }
 
-    // Process webhook event with timeout and retries
-    timeoutCtx, cancel := context.WithTimeout(ctx, webhookVerificationTimeout)
-    defer cancel()
+    if !verified {
+        span.SetAttributes(attribute.String("error", "invalid_signature"))
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+        return
+    }
 
-    if err := h.processWebhookWithRetry(timeoutCtx, &event); err != nil {
-        span.SetAttributes(
-            attribute.String("error", "processing_failed"),
-            attribute.String("error_details", err.Error()),
-        )
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process webhook"})
+    // Record the event so support can audit a message's webhook history. Unsigned requests
+    // are never stored, so callers without the secret cannot fill the table.
+    h.storeWebhook(ctx, &event, body)
+
+    // Meta retries deliveries until acknowledged, so acknowledge repeats without processing them
+    dedupKey, duplicate := h.markSeen(ctx, &event)
+    if duplicate {
+        webhookDuplicatesTotal.Inc()
+        c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"status": "processed"})
+    // Hand the event to the worker pool; shed load rather than queue without bound
+    if !h.enqueue(&event) {
+        // The rejected delivery will be retried and must not be mistaken for a duplicate
+        h.forgetSeen(ctx, dedupKey)
+        span.SetAttributes(attribute.String("error", "queue_full"))
+        c.JSON(http.StatusServiceUnavailable, gin.H{"error": "webhook queue full"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"status": "accepted"})
 }
 
 // VerifyWebhook handles WhatsApp webhook verification requests
@@ -158,26 +244,211 @@ func (h *WebhookHandler) VerifyWebhook(c *gin.Context) {
     c.String(http.StatusOK, challenge)
 }
 
+// HandleGetMessageWebhooks returns the webhook events received for a message in received order.
+// Supports limit and offset query parameters for paging through long histories.
+func (h *WebhookHandler) HandleGetMessageWebhooks(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "get_message_webhooks")
+    defer span.End()
+
+    if h.webhooks == nil {
+        c.JSON(http.StatusNotImplemented, gin.H{"error": "webhook history is not enabled"})
+        return
+    }
+
+    messageID := c.Param("id")
+    if messageID == "" {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "message ID is required"})
+        return
+    }
+
+    limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+    if err != nil || limit < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+        return
+    }
+    offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+    if err != nil || offset < 0 {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+        return
+    }
+
+    events, err := h.webhooks.GetByMessageIDPage(ctx, messageID, limit, offset)
+    if err != nil {
+        span.SetAttributes(attribute.String("error", "history_lookup_failed"))
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load webhook history"})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "message_id": messageID,
+        "events":     events,
+        "count":      len(events),
+        "offset":     offset,
+    })
+}
+
+// SetDedupStore enables skipping of redelivered events using store, or disables it when
+// store is nil. It must be called before the handler serves requests.
+func (h *WebhookHandler) SetDedupStore(store WebhookDedupStore) {
+    h.dedup = store
+}
+
+// markSeen records the event's dedup key and reports whether it was seen before. Store
+// failures let the event through, since processing twice is safer than dropping it.
+func (h *WebhookHandler) markSeen(ctx context.Context, event *whatsapp.WebhookEvent) (string, bool) {
+    if h.dedup == nil || event.MessageID == "" {
+        return "", false
+    }
+
+    key := webhookDedupKey(event)
+    added, err := h.dedup.MarkSeen(ctx, key, h.dedupTTL)
+    if err != nil {
+        trace.SpanFromContext(ctx).SetAttributes(attribute.String("webhook_dedup_error", err.Error()))
+        return "", false
+    }
+    return key, !added
+}
+
+// forgetSeen removes a dedup key recorded by markSeen
+func (h *WebhookHandler) forgetSeen(ctx context.Context, key string) {
+    if h.dedup == nil || key == "" {
+        return
+    }
+    if err := h.dedup.Forget(ctx, key); err != nil {
+        trace.SpanFromContext(ctx).SetAttributes(attribute.String("webhook_dedup_error", err.Error()))
+    }
+}
+
+// storeWebhook persists a verified event; persistence failures never block processing
+func (h *WebhookHandler) storeWebhook(ctx context.Context, event *whatsapp.WebhookEvent, body []byte) {
+    if h.webhooks == nil || event.MessageID == "" {
+        return
+    }
+
+    payload := make([]byte, len(body))
+    copy(payload, body)
+
+    err := h.webhooks.Store(ctx, &repository.StoredWebhook{
+        MessageID:  event.MessageID,
+        EventType:  event.Type,
+        Status:     string(event.Status),
+        Verified:   true,
+        Payload:    payload,
+        ReceivedAt: time.Now(),
+    })
+    if err != nil {
+        trace.SpanFromContext(ctx).SetAttributes(attribute.String("webhook_store_error", err.Error()))
+    }
+}
+
+// Shutdown stops accepting queued work and waits for workers to drain the queue
+func (h *WebhookHandler) Shutdown(ctx context.Context) error {
+    h.queueMu.Lock()
+    if !h.closed {
+        h.closed = true
+        close(h.queue)
+    }
+    h.queueMu.Unlock()
+
+    done := make(chan struct{})
+    go func() {
+        h.workers.Wait()
+        close(done)
+    }()
+
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return fmt.Errorf("webhook drain timeout: %w", ctx.Err())
+    }
+}
+
+// enqueue offers an event to the worker pool without blocking, reporting false when
+// the queue is full or the handler is shutting down
+func (h *WebhookHandler) enqueue(event *whatsapp.WebhookEvent) bool {
+    h.queueMu.RLock()
+    defer h.queueMu.RUnlock()
+
+    if h.closed {
+        return false
+    }
+
+    select {
+    case h.queue <- event:
+        return true
+    default:
+        return false
+    }
+}
+
+// runWorker processes queued webhook events until the queue is closed
+func (h *WebhookHandler) runWorker() {
+    defer h.workers.Done()
+
+    for event := range h.queue {
+        ctx, span := h.tracer.Start(context.Background(), "process_webhook",
+            trace.WithAttributes(attribute.String("event_type", event.Type)),
+        )
+        timeoutCtx, cancel := context.WithTimeout(ctx, webhookProcessingTimeout)
+
+        if err := h.processWebhookWithRetry(timeoutCtx, event); err != nil {
+            span.SetAttributes(
+                attribute.String("error", "processing_failed"),
+                attribute.String("error_details", err.Error()),
+            )
+        } else {
+            h.markRead(timeoutCtx, event)
+        }
+
+        cancel()
+        span.End()
+    }
+}
+
+// markRead marks a processed inbound message as read when enabled. A failure only affects
+// the sender's read receipts, so it is counted rather than failing the event.
+func (h *WebhookHandler) markRead(ctx context.Context, event *whatsapp.WebhookEvent) {
+    if !h.autoMarkRead || event.Type != whatsapp.WebhookEventTypeMessage || event.MessageID == "" {
+        return
+    }
+
+    if err := h.whatsappClient.MarkAsRead(ctx, event.MessageID); err != nil {
+        markReadTotal.WithLabelValues("error").Inc()
+        return
+    }
+    markReadTotal.WithLabelValues("success").Inc()
+}
+
 // processWebhookWithRetry attempts to process the webhook event with retries
 func (h *WebhookHandler) processWebhookWithRetry(ctx context.Context, event *whatsapp.WebhookEvent) error {
+    ctx, cancel := context.WithTimeout(ctx, maxRetryDuration)
+    defer cancel()
+
     var lastErr error
 
     for attempt := 0; attempt <= maxRetryAttempts; attempt++ {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+
+        err := h.whatsappService.ProcessWebhookEvent(ctx, event)
+        if err == nil {
+            return nil
+        }
+        lastErr = err
+        if attempt == maxRetryAttempts {
+            break
+        }
+
+        // Wait out the exponential backoff unless the context ends first
+        backoff := retryBackoffDuration * time.Duration(1<<uint(attempt))
+        timer := time.NewTimer(backoff)
         select {
         case <-ctx.Done():
+            timer.Stop()
             return ctx.Err()
-        default:
-            if err := h.whatsappService.ProcessWebhookEvent(ctx, event); err != nil {
-                lastErr = err
-                if attempt < maxRetryAttempts {
-                    // Calculate exponential backoff
-                    backoff := retryBackoffDuration * time.Duration(1<<uint(attempt))
-                    time.Sleep(backoff)
-                    continue
-                }
-            } else {
-                return nil
-            }
+        case <-timer.C:
         }
     }
 
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "message-service/pkg/whatsapp"
+)
+
+// failingProcessor fails every event and signals each attempt
+type failingProcessor struct {
+    calls    atomic.Int32
+    attempts chan struct{}
+}
+
+func (p *failingProcessor) ProcessWebhookEvent(ctx context.Context, event *whatsapp.WebhookEvent) error {
+    p.calls.Add(1)
+    p.attempts <- struct{}{}
+    return errors.New("database unavailable")
+}
+
+func TestProcessWebhookWithRetryStopsWhenCancelledDuringBackoff(t *testing.T) {
+    processor := &failingProcessor{attempts: make(chan struct{}, maxRetryAttempts+1)}
+    h := &WebhookHandler{whatsappService: processor}
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    result := make(chan error, 1)
+    go func() {
+        result <- h.processWebhookWithRetry(ctx, &whatsapp.WebhookEvent{Type: whatsapp.WebhookEventTypeMessage, MessageID: "wamid.1"})
+    }()
+
+    // The first attempt has failed, so the handler is waiting out a backoff of at least
+    // retryBackoffDuration
+    <-processor.attempts
+    cancel()
+
+    select {
+    case err := <-result:
+        assert.ErrorIs(t, err, context.Canceled)
+    case <-time.After(retryBackoffDuration / 2):
+        require.FailNow(t, "processWebhookWithRetry kept waiting after cancellation")
+    }
+    assert.EqualValues(t, 1, processor.calls.Load(), "no attempt is made after cancellation")
+}
@@ -3,6 +3,7 @@
 package models
 
 import (
+    "net/url"
     "regexp"
     "time"
     "encoding/json"
@@ -15,18 +16,22 @@ import (
 
 // Message status constants for comprehensive lifecycle tracking
 const (
-    MessageStatusPending   = "pending"
-    MessageStatusSent      = "sent"
-    MessageStatusDelivered = "delivered"
-    MessageStatusFailed    = "failed"
-    MessageStatusScheduled = "scheduled"
-    MessageStatusCancelled = "cancelled"
+    MessageStatusPending    = "pending"
+    MessageStatusProcessing = "processing"
+    MessageStatusSent       = "sent"
+    MessageStatusDelivered  = "delivered"
+    MessageStatusRead       = "read"
+    MessageStatusFailed     = "failed"
+    MessageStatusScheduled  = "scheduled"
+    MessageStatusCancelled  = "cancelled"
 )
 
 // System configuration constants
 const (
-    MaxRetryAttempts   = 3
-    PhoneNumberPattern = `^\+[1-9]\d{1,14}$`
+    MaxRetryAttempts     = 3
+    PhoneNumberPattern   = `^\+[1-9]\d{1,14}$`
+    MaxExternalRefLength = 512 // WhatsApp limit on biz_opaque_callback_data
+    MaxCallbackURLLength = 2048
 )
 
 // Message represents an enterprise-grade WhatsApp message with comprehensive tracking
@@ -38,11 +43,23 @@ type Message struct {
     Template       *types.Template     `json:"template,omitempty"`
     Status         string             `json:"status"`
     RetryCount     int                `json:"retry_count"`
+    // Priority is the queue priority resolved when the message was enqueued, so retries
+    // and scheduled moves return it to the same queue
+    Priority       string             `json:"priority,omitempty"`
     ScheduledAt    *time.Time         `json:"scheduled_at,omitempty"`
     SentAt         *time.Time         `json:"sent_at,omitempty"`
     DeliveredAt    *time.Time         `json:"delivered_at,omitempty"`
+    ReadAt         *time.Time         `json:"read_at,omitempty"`
     FailedAt       *time.Time         `json:"failed_at,omitempty"`
     ErrorDetails   string             `json:"error_details,omitempty"`
+    ExternalRef    string             `json:"external_ref,omitempty"`
+    CallbackURL    string             `json:"callback_url,omitempty"`
+    Recurrence     *Recurrence        `json:"recurrence,omitempty"`
+    Test           bool               `json:"test,omitempty"`
+    // OriginalRecipient holds the caller's recipient when test traffic was rerouted
+    OriginalRecipient string          `json:"original_recipient,omitempty"`
+    // SplitLongText opts in to sending over-long text as several messages instead of rejecting it
+    SplitLongText  bool               `json:"split_long_text,omitempty"`
     CreatedAt      time.Time          `json:"created_at"`
     UpdatedAt      time.Time          `json:"updated_at"`
 }
@@ -96,8 +113,25 @@ func (m *Message) Validate() error {
         return errors.New("invalid phone number format")
     }
     
-    // Validate content or template presence
-    if m.Content.Text == "" && m.Template == nil {
+    // Validate retry count is within the retry limit
+    if m.RetryCount < 0 || m.RetryCount > MaxRetryAttempts {
+        return errors.Errorf("retry count %d out of range [0, %d]", m.RetryCount, MaxRetryAttempts)
+    }
+    
+    // Validate external reference fits in the outbound callback data
+    if len(m.ExternalRef) > MaxExternalRefLength {
+        return errors.Errorf("external reference exceeds %d characters", MaxExternalRefLength)
+    }
+    
+    // Validate per-message status callback URL
+    if m.CallbackURL != "" {
+        if err := validateCallbackURL(m.CallbackURL); err != nil {
+            return err
+        }
+    }
+    
+    // Validate content or template presence; reactions, locations and contacts carry no text
+    if m.Content.Text == "" && m.Template == nil && m.Content.Reaction == nil && m.Content.Location == nil && len(m.Content.Contacts) == 0 {
         return errors.New("either message content or template is required")
     }
     
@@ -106,14 +140,26 @@ func (m *Message) Validate() error {
         return errors.New("scheduled time must be in the future")
     }
     
+    // Validate recurrence of scheduled messages
+    if m.Recurrence != nil {
+        if m.ScheduledAt == nil {
+            return errors.New("recurring messages require a scheduled time")
+        }
+        if err := m.Recurrence.Validate(); err != nil {
+            return err
+        }
+    }
+    
     // Validate status
     validStatuses := map[string]bool{
-        MessageStatusPending:   true,
-        MessageStatusSent:      true,
-        MessageStatusDelivered: true,
-        MessageStatusFailed:    true,
-        MessageStatusScheduled: true,
-        MessageStatusCancelled: true,
+        MessageStatusPending:    true,
+        MessageStatusProcessing: true,
+        MessageStatusSent:       true,
+        MessageStatusDelivered:  true,
+        MessageStatusRead:       true,
+        MessageStatusFailed:     true,
+        MessageStatusScheduled:  true,
+        MessageStatusCancelled:  true,
     }
     if !validStatuses[m.Status] {
         return errors.New("invalid message status")
@@ -122,25 +168,40 @@ func (m *Message) Validate() error {
     return nil
 }
 
-// UpdateStatus updates message status with comprehensive tracking
-func (m *Message) UpdateStatus(status string, statusError error) error {
-    // Validate status transition
-    if !isValidStatusTransition(m.Status, status) {
+// UpdateStatus moves the message to status as of at, the time the status was reported, or
+// now when at is zero. Delivery statuses arrive out of order, so once a message is sent a
+// status ranked at or below the current one is ignored, and a higher ranked status is
+// accepted even when intermediate statuses were skipped. Other transitions must be valid.
+func (m *Message) UpdateStatus(status string, at time.Time, statusError error) error {
+    if at.IsZero() {
+        at = time.Now()
+    }
+
+    fromRank, fromRanked := statusRank(m.Status)
+    toRank, toRanked := statusRank(status)
+    sentRank, _ := statusRank(MessageStatusSent)
+    if fromRanked && toRanked && toRank >= sentRank {
+        if toRank <= fromRank {
+            // A stale or repeated report must not move the message backward
+            return nil
+        }
+    } else if !isValidStatusTransition(m.Status, status) {
         return errors.New("invalid status transition")
     }
     
     // Update status and timestamps
-    now := time.Now()
     m.Status = status
-    m.UpdatedAt = now
+    m.UpdatedAt = time.Now()
     
     switch status {
     case MessageStatusSent:
-        m.SentAt = &now
+        m.SentAt = &at
     case MessageStatusDelivered:
-        m.DeliveredAt = &now
+        m.DeliveredAt = &at
+    case MessageStatusRead:
+        m.ReadAt = &at
     case MessageStatusFailed:
-        m.FailedAt = &now
+        m.FailedAt = &at
         m.RetryCount++
         if statusError != nil {
             m.ErrorDetails = statusError.Error()
@@ -155,6 +216,68 @@ func (m *Message) UpdateStatus(status string, statusError error) error {
     return nil
 }
 
+// ClampRetryCount bounds the retry count to [0, MaxRetryAttempts] and reports whether it was out of range
+func (m *Message) ClampRetryCount() bool {
+    switch {
+    case m.RetryCount < 0:
+        m.RetryCount = 0
+    case m.RetryCount > MaxRetryAttempts:
+        m.RetryCount = MaxRetryAttempts
+    default:
+        return false
+    }
+    return true
+}
+
+// ToWhatsAppMessage maps a stored message to the WhatsApp API message representation
+func ToWhatsAppMessage(m *Message) *types.Message {
+    if m == nil {
+        return nil
+    }
+
+    msgType := types.MessageTypeText
+    switch {
+    case m.Template != nil:
+        msgType = types.MessageTypeTemplate
+    case m.Content.Reaction != nil:
+        msgType = types.MessageTypeReaction
+    case m.Content.Location != nil:
+        msgType = types.MessageTypeLocation
+    case len(m.Content.Contacts) > 0:
+        msgType = types.MessageTypeContacts
+    case m.Content.MediaURL != "":
+        msgType = types.MessageTypeMedia
+    }
+
+    return &types.Message{
+        ID:           m.ID,
+        To:           m.RecipientPhone,
+        Type:         msgType,
+        Content:      m.Content,
+        Template:     m.Template,
+        Status:       types.MessageStatus(m.Status),
+        CreatedAt:    m.CreatedAt,
+        UpdatedAt:    m.UpdatedAt,
+        ScheduledFor: m.ScheduledAt,
+        DeliveredAt:  m.DeliveredAt,
+        RetryCount:   m.RetryCount,
+        BizOpaqueCallbackData: m.ExternalRef,
+        Metadata:     whatsAppMetadata(m),
+    }
+}
+
+// whatsAppMetadata builds the outbound metadata, recording the original recipient of
+// rerouted test traffic
+func whatsAppMetadata(m *Message) map[string]interface{} {
+    metadata := map[string]interface{}{
+        "organization_id": m.OrganizationID,
+    }
+    if m.OriginalRecipient != "" {
+        metadata["original_recipient"] = m.OriginalRecipient
+    }
+    return metadata
+}
+
 // ToJSON serializes the message to JSON with error handling
 func (m *Message) ToJSON() ([]byte, error) {
     data, err := json.Marshal(m)
@@ -164,13 +287,54 @@ func (m *Message) ToJSON() ([]byte, error) {
     return data, nil
 }
 
+// validateCallbackURL ensures a callback URL is an absolute http(s) URL within the length limit
+func validateCallbackURL(callbackURL string) error {
+    if len(callbackURL) > MaxCallbackURLLength {
+        return errors.Errorf("callback URL exceeds %d characters", MaxCallbackURLLength)
+    }
+    u, err := url.Parse(callbackURL)
+    if err != nil {
+        return errors.Wrap(err, "invalid callback URL")
+    }
+    if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+        return errors.New("callback URL must be an absolute http or https URL")
+    }
+    return nil
+}
+
+// statusRank orders the delivery lifecycle pending < processing < sent < delivered < read,
+// reporting false for statuses outside it
+func statusRank(status string) (int, bool) {
+    switch status {
+    case MessageStatusPending:
+        return 0, true
+    case MessageStatusProcessing:
+        return 1, true
+    case MessageStatusSent:
+        return 2, true
+    case MessageStatusDelivered:
+        return 3, true
+    case MessageStatusRead:
+        return 4, true
+    }
+    return -1, false
+}
+
 // isValidStatusTransition validates message status transitions
 func isValidStatusTransition(from, to string) bool {
     validTransitions := map[string]map[string]bool{
         MessageStatusPending: {
+            MessageStatusProcessing: true,
+            MessageStatusSent:       true,
+            MessageStatusFailed:     true,
+            MessageStatusCancelled:  true,
+            MessageStatusScheduled:  true,
+        },
+        MessageStatusProcessing: {
             MessageStatusSent:      true,
             MessageStatusFailed:    true,
-            MessageStatusCancelled: true,
+            MessageStatusPending:   true,
+            MessageStatusScheduled: true,
         },
         MessageStatusScheduled: {
             MessageStatusPending:   true,
@@ -180,6 +344,9 @@ func isValidStatusTransition(from, to string) bool {
             MessageStatusDelivered: true,
             MessageStatusFailed:    true,
         },
+        MessageStatusDelivered: {
+            MessageStatusRead: true,
+        },
         MessageStatusFailed: {
             MessageStatusPending: true,
         },
@@ -0,0 +1,89 @@
+package models
+
+import (
+    "math"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "message-service/pkg/whatsapp/types"
+)
+
+func TestRetryCountBounds(t *testing.T) {
+    tests := []struct {
+        name        string
+        retryCount  int
+        wantClamped int
+        outOfRange  bool
+    }{
+        {"far below min", math.MinInt32, 0, true},
+        {"below min", -1, 0, true},
+        {"at min", 0, 0, false},
+        {"in range", MaxRetryAttempts - 1, MaxRetryAttempts - 1, false},
+        {"at max", MaxRetryAttempts, MaxRetryAttempts, false},
+        {"above max", MaxRetryAttempts + 1, MaxRetryAttempts, true},
+        {"far above max", math.MaxInt32, MaxRetryAttempts, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            msg := &Message{
+                ID:             "msg-1",
+                OrganizationID: "org-1",
+                RecipientPhone: "+14155550100",
+                Content:        types.MessageContent{Text: "hello"},
+                Status:         MessageStatusPending,
+                RetryCount:     tt.retryCount,
+            }
+
+            err := msg.Validate()
+            if tt.outOfRange {
+                assert.ErrorContains(t, err, "retry count")
+            } else {
+                assert.NoError(t, err)
+            }
+
+            assert.Equal(t, tt.outOfRange, msg.ClampRetryCount())
+            assert.Equal(t, tt.wantClamped, msg.RetryCount)
+            assert.NoError(t, msg.Validate(), "a clamped message is valid")
+        })
+    }
+}
+
+func TestUpdateStatusDeliveredBeforeSent(t *testing.T) {
+    sentAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+    deliveredAt := sentAt.Add(2 * time.Second)
+    msg := &Message{ID: "msg-1", Status: MessageStatusPending}
+
+    // The delivered webhook overtakes the sent one; the message skips ahead
+    require.NoError(t, msg.UpdateStatus(MessageStatusDelivered, deliveredAt, nil))
+    assert.Equal(t, MessageStatusDelivered, msg.Status)
+    assert.Equal(t, deliveredAt, *msg.DeliveredAt)
+
+    // The late sent report is ignored rather than rejected or applied
+    require.NoError(t, msg.UpdateStatus(MessageStatusSent, sentAt, nil))
+    assert.Equal(t, MessageStatusDelivered, msg.Status)
+    assert.Nil(t, msg.SentAt)
+
+    // A repeat of the current status changes nothing; read still moves forward
+    require.NoError(t, msg.UpdateStatus(MessageStatusDelivered, deliveredAt.Add(time.Second), nil))
+    assert.Equal(t, deliveredAt, *msg.DeliveredAt)
+    require.NoError(t, msg.UpdateStatus(MessageStatusRead, deliveredAt.Add(time.Minute), nil))
+    assert.Equal(t, MessageStatusRead, msg.Status)
+}
+
+func TestStatusRankOrdersLifecycle(t *testing.T) {
+    order := []string{MessageStatusPending, MessageStatusSent, MessageStatusDelivered, MessageStatusRead}
+    for i := 1; i < len(order); i++ {
+        prev, ok := statusRank(order[i-1])
+        require.True(t, ok)
+        next, ok := statusRank(order[i])
+        require.True(t, ok)
+        assert.Less(t, prev, next, "%s ranks below %s", order[i-1], order[i])
+    }
+
+    _, ok := statusRank(MessageStatusFailed)
+    assert.False(t, ok, "failed is outside the forward lifecycle")
+}
@@ -0,0 +1,123 @@
+// Package models provides recurrence rules for repeating scheduled messages
+// Version: go1.21
+package models
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/google/uuid"     // v1.3.0
+    "github.com/pkg/errors"      // v0.9.1
+)
+
+// Recurrence limits
+const (
+    MinRecurrenceInterval = time.Minute
+    MaxRecurrenceCount    = 366
+)
+
+// Recurrence repeats a scheduled message every interval. It ends after Count occurrences or
+// once the next occurrence would fall after Until, whichever comes first.
+type Recurrence struct {
+    IntervalSeconds int64      `json:"interval_seconds"`
+    Count           int        `json:"count,omitempty"`
+    Until           *time.Time `json:"until,omitempty"`
+    // SeriesID is the ID of the first message and Occurrence the 1-based position of this
+    // message in the series; both are filled in as occurrences are scheduled
+    SeriesID        string     `json:"series_id,omitempty"`
+    Occurrence      int        `json:"occurrence,omitempty"`
+}
+
+// Interval returns the time between occurrences
+func (r *Recurrence) Interval() time.Duration {
+    return time.Duration(r.IntervalSeconds) * time.Second
+}
+
+// Validate ensures the recurrence has a usable interval and a bounded number of occurrences
+func (r *Recurrence) Validate() error {
+    if r.Interval() < MinRecurrenceInterval {
+        return errors.Errorf("recurrence interval must be at least %s", MinRecurrenceInterval)
+    }
+    if r.Count == 0 && r.Until == nil {
+        return errors.New("recurrence requires a count or an until time")
+    }
+    if r.Count < 0 || r.Count > MaxRecurrenceCount {
+        return errors.Errorf("recurrence count must be between 1 and %d", MaxRecurrenceCount)
+    }
+    if r.Occurrence < 0 || r.Occurrence > MaxRecurrenceCount {
+        return errors.Errorf("recurrence occurrence %d out of range [0, %d]", r.Occurrence, MaxRecurrenceCount)
+    }
+    return nil
+}
+
+// next returns the time of the occurrence after the one at from, or false if the series has ended
+func (r *Recurrence) next(from time.Time) (time.Time, bool) {
+    occurrence := r.Occurrence
+    if occurrence == 0 {
+        occurrence = 1
+    }
+    if occurrence >= MaxRecurrenceCount || (r.Count > 0 && occurrence >= r.Count) {
+        return time.Time{}, false
+    }
+
+    next := from.Add(r.Interval())
+    if r.Until != nil && next.After(*r.Until) {
+        return time.Time{}, false
+    }
+    return next, true
+}
+
+// NextOccurrenceAt returns when the occurrence following this message is due, or nil if the
+// message does not recur or is the last of its series
+func (m *Message) NextOccurrenceAt() *time.Time {
+    if m.Recurrence == nil || m.ScheduledAt == nil {
+        return nil
+    }
+    next, ok := m.Recurrence.next(*m.ScheduledAt)
+    if !ok {
+        return nil
+    }
+    return &next
+}
+
+// NextOccurrence builds the scheduled message for the next occurrence of a recurring message,
+// or returns false when the message does not recur or its series has ended. The new message
+// ID is derived from the series position, so scheduling the same occurrence twice yields
+// the same ID and is deduplicated on insert.
+func (m *Message) NextOccurrence() (*Message, bool) {
+    if m.Recurrence == nil || m.ScheduledAt == nil {
+        return nil, false
+    }
+
+    scheduledAt, ok := m.Recurrence.next(*m.ScheduledAt)
+    if !ok {
+        return nil, false
+    }
+
+    recurrence := *m.Recurrence
+    if recurrence.SeriesID == "" {
+        recurrence.SeriesID = m.ID
+    }
+    if recurrence.Occurrence == 0 {
+        recurrence.Occurrence = 1
+    }
+    recurrence.Occurrence++
+    seriesKey := fmt.Sprintf("%s/%d", recurrence.SeriesID, recurrence.Occurrence)
+
+    now := time.Now()
+    next := &Message{
+        ID:             uuid.NewSHA1(uuid.NameSpaceOID, []byte(seriesKey)).String(),
+        OrganizationID: m.OrganizationID,
+        RecipientPhone: m.RecipientPhone,
+        Content:        m.Content,
+        Template:       m.Template,
+        Status:         MessageStatusScheduled,
+        ScheduledAt:    &scheduledAt,
+        ExternalRef:    m.ExternalRef,
+        CallbackURL:    m.CallbackURL,
+        Recurrence:     &recurrence,
+        CreatedAt:      now,
+        UpdatedAt:      now,
+    }
+    return next, true
+}
@@ -0,0 +1,37 @@
+// Package models provides the pre-send message transformation hook
+// Version: go1.21
+package models
+
+import (
+    "context"
+
+    "github.com/pkg/errors"      // v0.9.1
+)
+
+// ErrTransformAborted marks a send abandoned because a transformer rejected the message
+var ErrTransformAborted = errors.New("message transform aborted")
+
+// MessageTransformer rewrites a message before it is sent, e.g. to append a footer or redact
+// content. Returning an error aborts the send.
+type MessageTransformer interface {
+    Transform(ctx context.Context, msg *Message) error
+}
+
+// MessageTransformerFunc adapts a function to the MessageTransformer interface
+type MessageTransformerFunc func(ctx context.Context, msg *Message) error
+
+// Transform calls f(ctx, msg)
+func (f MessageTransformerFunc) Transform(ctx context.Context, msg *Message) error {
+    return f(ctx, msg)
+}
+
+// ApplyTransformers runs the transformers in order, stopping at the first failure. The
+// returned error wraps ErrTransformAborted and names the failing transformer's position.
+func ApplyTransformers(ctx context.Context, msg *Message, transformers []MessageTransformer) error {
+    for i, transformer := range transformers {
+        if err := transformer.Transform(ctx, msg); err != nil {
+            return errors.Wrapf(ErrTransformAborted, "transformer %d: %v", i, err)
+        }
+    }
+    return nil
+}
@@ -4,8 +4,14 @@ package queue
 
 import (
     "context"
+    "crypto/rand"
+    "encoding/hex"
     "encoding/json"
+    "errors"
+    "fmt"
     "log"
+    "os"
+    "strconv"
     "sync"
     "sync/atomic"
     "time"
@@ -13,6 +19,7 @@ import (
     "github.com/go-redis/redis/v8" // v8.11.5
 
     "message-service/internal/models"
+    "message-service/internal/repository"
     "message-service/pkg/whatsapp"
 )
 
@@ -23,8 +30,13 @@ const (
     lowPriorityQueue    = "messages:low"
     scheduledQueue      = "messages:scheduled"
     deadLetterQueue     = "messages:dead"
+    poisonQueue         = "messages:poison"
 )
 
+// workersSet lists the consumers that have claimed messages so the reaper can find their
+// processing lists after they are gone
+const workersSet = "messages:workers"
+
 // Consumer configuration
 const (
     batchSize            = 100
@@ -33,29 +45,217 @@ const (
     retryDelay           = time.Second * 2
     maxConcurrentBatches = 5
     shutdownTimeout      = time.Second * 30
+    visibilityTimeout    = time.Minute * 5
+    reapInterval         = time.Second * 30
 )
 
+// claimScript atomically moves the message at the front of a queue onto a processing
+// list and records when it was claimed. KEYS[1] is the queue, KEYS[2] the processing list,
+// KEYS[3] its claim times and KEYS[4] the workers set; ARGV[1] is the current Unix time
+// and ARGV[2] the worker ID.
+var claimScript = redis.NewScript(`
+local member = redis.call('LMOVE', KEYS[1], KEYS[2], 'LEFT', 'RIGHT')
+if member then
+    redis.call('ZADD', KEYS[3], ARGV[1], member)
+    redis.call('SADD', KEYS[4], ARGV[2])
+end
+return member
+`)
+
+// reapScript returns claimed messages whose visibility timeout has passed to the front of
+// their queue. KEYS[1] is the queue, KEYS[2] the processing list and KEYS[3] its claim
+// times; ARGV[1] is the cutoff Unix time and ARGV[2] the current Unix time. A message is
+// only requeued if it is still on the processing list, so one acknowledged concurrently is
+// not sent twice. A message on the list without a claim time, left by a worker that died
+// between claiming it and recording the claim, is given one now so it expires in turn.
+var reapScript = redis.NewScript(`
+for _, member in ipairs(redis.call('LRANGE', KEYS[2], 0, -1)) do
+    if not redis.call('ZSCORE', KEYS[3], member) then
+        redis.call('ZADD', KEYS[3], ARGV[2], member)
+    end
+end
+local expired = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', ARGV[1])
+local requeued = 0
+for _, member in ipairs(expired) do
+    redis.call('ZREM', KEYS[3], member)
+    if redis.call('LREM', KEYS[2], 1, member) > 0 then
+        redis.call('LPUSH', KEYS[1], member)
+        requeued = requeued + 1
+    end
+end
+return requeued
+`)
+
+// pruneWorkerScript forgets a worker once all of its processing lists are empty. KEYS[1]
+// is the workers set and the remaining keys are the worker's processing lists; ARGV[1] is
+// the worker ID. A worker that claims again is re-added by claimScript.
+var pruneWorkerScript = redis.NewScript(`
+for i = 2, #KEYS do
+    if redis.call('LLEN', KEYS[i]) > 0 then
+        return 0
+    end
+end
+return redis.call('SREM', KEYS[1], ARGV[1])
+`)
+
+// moveScheduledScript atomically relocates due scheduled messages to their target
+// queues. KEYS[1] is the scheduled set, KEYS[2] the index of scheduled members by message
+// ID and KEYS[i+2] the target queue for ARGV[i]; the message IDs follow the n members in
+// ARGV. Members already removed by another consumer or cancelled are skipped so each
+// message is only pushed once, and a member is only removed from the set once it has
+// been pushed.
+var moveScheduledScript = redis.NewScript(`
+local n = #ARGV / 2
+local moved = 0
+for i = 1, n do
+    local member = ARGV[i]
+    if redis.call('ZSCORE', KEYS[1], member) then
+        redis.call('LPUSH', KEYS[i + 2], member)
+        redis.call('ZREM', KEYS[1], member)
+        if redis.call('HGET', KEYS[2], ARGV[n + i]) == member then
+            redis.call('HDEL', KEYS[2], ARGV[n + i])
+        end
+        moved = moved + 1
+    end
+end
+return moved
+`)
+
+// StatusStore persists message status changes produced by the consumer
+type StatusStore interface {
+    UpdateStatusBatch(ctx context.Context, updates []repository.StatusUpdate) ([]string, error)
+}
+
+// ConsumerConfig weights how the dispatcher divides each batch between the priority
+// queues. With the default 8:2:1, a batch of 100 takes up to 73 high, 19 normal and
+// 10 low priority messages; every queue gets at least one slot per round so none starves.
+//
+// VisibilityTimeout is how long a claimed message may go unacknowledged before any
+// consumer's reaper returns it to its queue. It must exceed the longest send including
+// retries, or a slow send is delivered twice.
+//
+// WorkerID names the consumer's processing lists and must differ between running
+// consumers; empty keeps the generated ID, unique to the process.
+type ConsumerConfig struct {
+    HighWeight        int
+    NormalWeight      int
+    LowWeight         int
+    BatchSize         int
+    VisibilityTimeout time.Duration
+    WorkerID          string
+}
+
+// DefaultConsumerConfig returns the default 8:2:1 weighting over a full batch with a
+// five minute visibility timeout
+func DefaultConsumerConfig() ConsumerConfig {
+    return ConsumerConfig{
+        HighWeight:        8,
+        NormalWeight:      2,
+        LowWeight:         1,
+        BatchSize:         batchSize,
+        VisibilityTimeout: visibilityTimeout,
+    }
+}
+
+// Validate checks that the weights and batch size are usable
+func (cfg ConsumerConfig) Validate() error {
+    if cfg.HighWeight < 1 || cfg.NormalWeight < 1 || cfg.LowWeight < 1 {
+        return fmt.Errorf("priority weights must be at least 1, got %d:%d:%d", cfg.HighWeight, cfg.NormalWeight, cfg.LowWeight)
+    }
+    if cfg.BatchSize < 1 {
+        return fmt.Errorf("batch size must be at least 1, got %d", cfg.BatchSize)
+    }
+    if cfg.VisibilityTimeout < time.Minute {
+        return fmt.Errorf("visibility timeout must be at least 1m, got %s", cfg.VisibilityTimeout)
+    }
+    return nil
+}
+
+// shares returns the per-round message limit of the high, normal and low queues
+func (cfg ConsumerConfig) shares() [3]int {
+    weights := [3]int{cfg.HighWeight, cfg.NormalWeight, cfg.LowWeight}
+    total := cfg.HighWeight + cfg.NormalWeight + cfg.LowWeight
+
+    var shares [3]int
+    for i, weight := range weights {
+        shares[i] = (cfg.BatchSize*weight + total - 1) / total
+    }
+    return shares
+}
+
+// rateLimitedSender is a sender that exposes the limiter pacing its requests
+type rateLimitedSender interface {
+    RateLimiter() whatsapp.Limiter
+}
+
 // MessageConsumer handles consuming and processing messages from Redis queues
 type MessageConsumer struct {
     redisClient    *redis.Client
-    whatsappClient whatsapp.Client
+    whatsappClient whatsapp.MessageSender
+    statusStore    StatusStore
+    metrics        ConsumerMetrics
     ctx            context.Context
     cancel         context.CancelFunc
+    sendCtx        context.Context
+    sendCancel     context.CancelFunc
+    workerID       string
     running        atomic.Bool
+    paused         atomic.Bool
     wg             sync.WaitGroup
-    rateLimiter    *whatsapp.RateLimiter
+    rateLimiter    whatsapp.Limiter
+    transformers   []models.MessageTransformer
+    schedulePoll   time.Duration
+    config         ConsumerConfig
 }
 
-// NewMessageConsumer creates a new message consumer instance
-func NewMessageConsumer(redisClient *redis.Client, whatsappClient whatsapp.Client) *MessageConsumer {
+// NewMessageConsumer creates a new message consumer instance. Status changes are
+// flushed to statusStore once per fetched batch; a nil store skips persistence.
+// A nil metrics recorder disables consumer metrics. Sends are paced by the sender's
+// rate limiter when it exposes one, unless another limiter is set with SetRateLimiter.
+func NewMessageConsumer(redisClient *redis.Client, whatsappClient whatsapp.MessageSender, statusStore StatusStore, metrics ConsumerMetrics) *MessageConsumer {
     ctx, cancel := context.WithCancel(context.Background())
+    sendCtx, sendCancel := context.WithCancel(context.Background())
+
+    if metrics == nil {
+        metrics = noopConsumerMetrics{}
+    }
+
+    var limiter whatsapp.Limiter
+    if limited, ok := whatsappClient.(rateLimitedSender); ok {
+        limiter = limited.RateLimiter()
+    }
     
     return &MessageConsumer{
         redisClient:    redisClient,
         whatsappClient: whatsappClient,
+        statusStore:    statusStore,
+        metrics:        metrics,
+        rateLimiter:    limiter,
+        schedulePoll:   pollInterval,
+        config:         DefaultConsumerConfig(),
         ctx:           ctx,
         cancel:        cancel,
+        sendCtx:       sendCtx,
+        sendCancel:    sendCancel,
+        workerID:      defaultWorkerID(),
+    }
+}
+
+// defaultWorkerID names this worker's processing lists by host, process ID and a random
+// suffix, so consumers sharing a host or a reused PID never share lists. Messages left on
+// the lists of a consumer that died are recovered by the reaper of any running consumer
+// once their visibility timeout passes.
+func defaultWorkerID() string {
+    hostname, err := os.Hostname()
+    if err != nil || hostname == "" {
+        hostname = "default"
+    }
+
+    suffix := make([]byte, 4)
+    if _, err := rand.Read(suffix); err != nil {
+        return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
     }
+    return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix))
 }
 
 // Start begins processing messages from all priority queues
@@ -66,19 +266,15 @@ func (c *MessageConsumer) Start() error {
 
     c.running.Store(true)
 
-    // Start processing each priority queue in separate goroutines
-    c.wg.Add(4)
+    // A single dispatcher serves the priority queues by weight
+    c.wg.Add(3)
     go func() {
         defer c.wg.Done()
-        c.processQueue(highPriorityQueue)
+        c.dispatch()
     }()
     go func() {
         defer c.wg.Done()
-        c.processQueue(normalPriorityQueue)
-    }()
-    go func() {
-        defer c.wg.Done()
-        c.processQueue(lowPriorityQueue)
+        c.reapExpiredClaims()
     }()
     go func() {
         defer c.wg.Done()
@@ -88,7 +284,74 @@ func (c *MessageConsumer) Start() error {
     return nil
 }
 
-// Stop gracefully shuts down the consumer
+// SetRateLimiter replaces the limiter used to pace sends. It must be called before Start.
+func (c *MessageConsumer) SetRateLimiter(limiter whatsapp.Limiter) {
+    c.rateLimiter = limiter
+}
+
+// SetSchedulePollInterval sets how often due scheduled messages are moved to their queues,
+// which bounds how late they are sent. Scheduled times have second granularity, so
+// intervals below a second are rejected. It must be called before Start.
+func (c *MessageConsumer) SetSchedulePollInterval(interval time.Duration) error {
+    if interval < time.Second {
+        return fmt.Errorf("schedule poll interval must be at least 1s, got %s", interval)
+    }
+    c.schedulePoll = interval
+    return nil
+}
+
+// SetConfig replaces the priority weighting, visibility timeout and, when set, the worker
+// ID. It must be called before Start.
+func (c *MessageConsumer) SetConfig(cfg ConsumerConfig) error {
+    if err := cfg.Validate(); err != nil {
+        return err
+    }
+    c.config = cfg
+    if cfg.WorkerID != "" {
+        c.workerID = cfg.WorkerID
+    }
+    return nil
+}
+
+// AddTransformer registers a transformer run on every message before it is sent, in
+// registration order. It must be called before Start.
+func (c *MessageConsumer) AddTransformer(transformer models.MessageTransformer) {
+    c.transformers = append(c.transformers, transformer)
+}
+
+// Pause stops fetching new messages without tearing down the processing goroutines.
+// A batch already in flight is finished before the consumer goes idle.
+func (c *MessageConsumer) Pause() {
+    if c.paused.CompareAndSwap(false, true) {
+        log.Printf("Message consumer paused")
+        c.metrics.SetPaused(true)
+    }
+}
+
+// Resume restarts fetching after Pause
+func (c *MessageConsumer) Resume() {
+    if c.paused.CompareAndSwap(true, false) {
+        log.Printf("Message consumer resumed")
+        c.metrics.SetPaused(false)
+    }
+}
+
+// IsPaused reports whether the consumer is currently paused
+func (c *MessageConsumer) IsPaused() bool {
+    return c.paused.Load()
+}
+
+// Health returns the consumer's running and paused state
+func (c *MessageConsumer) Health() map[string]interface{} {
+    return map[string]interface{}{
+        "running": c.running.Load(),
+        "paused":  c.paused.Load(),
+    }
+}
+
+// Stop gracefully shuts down the consumer. No new messages are claimed, and a message
+// already being sent is given up to shutdownTimeout to finish and be acknowledged. Any
+// message still unacknowledged after that is returned to the front of its queue.
 func (c *MessageConsumer) Stop() error {
     if !c.running.Load() {
         return nil
@@ -104,53 +367,145 @@ func (c *MessageConsumer) Stop() error {
         close(done)
     }()
 
+    var err error
     select {
     case <-done:
-        return nil
     case <-time.After(shutdownTimeout):
-        return context.DeadlineExceeded
+        err = context.DeadlineExceeded
     }
+
+    // Abandon sends that outlived the drain and hand their messages back
+    c.sendCancel()
+    ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    c.requeueUnacknowledged(ctx)
+
+    return err
 }
 
-// processQueue handles message processing for a specific priority queue
-func (c *MessageConsumer) processQueue(queueName string) {
+// dispatch pulls from the priority queues in weighted rounds. Each round takes up to the
+// queue's share of a batch from high, then normal, then low, so under sustained load high
+// priority messages are sent in proportion to their weight. Shares left unused by an
+// empty queue are not carried over; the next round simply starts sooner.
+func (c *MessageConsumer) dispatch() {
+    queues := []string{highPriorityQueue, normalPriorityQueue, lowPriorityQueue}
+    shares := c.config.shares()
+
     for c.running.Load() {
         select {
         case <-c.ctx.Done():
             return
         default:
-            // Process messages in batches
-            messages, err := c.fetchMessageBatch(queueName)
-            if err != nil {
-                log.Printf("Error fetching messages from %s: %v", queueName, err)
+            if c.paused.Load() {
                 time.Sleep(pollInterval)
                 continue
             }
 
-            if len(messages) == 0 {
-                time.Sleep(pollInterval)
-                continue
+            claimed := 0
+            for i, queueName := range queues {
+                if c.ctx.Err() != nil {
+                    return
+                }
+                claimed += c.processBatch(queueName, shares[i])
             }
 
-            // Process each message in the batch
-            for _, msgData := range messages {
-                var msg models.Message
-                if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
-                    log.Printf("Error unmarshaling message: %v", err)
-                    continue
-                }
+            if claimed == 0 {
+                c.awaitHighPriority()
+            }
+        }
+    }
+}
 
-                if err := c.processMessage(&msg); err != nil {
-                    log.Printf("Error processing message %s: %v", msg.ID, err)
-                    c.handleFailedMessage(&msg, err)
-                    continue
-                }
+// awaitHighPriority idles until the next poll while picking up a high priority message
+// as soon as one arrives
+func (c *MessageConsumer) awaitHighPriority() {
+    msgData, err := c.awaitMessage(highPriorityQueue, pollInterval)
+    if err != nil {
+        if err != redis.Nil && c.ctx.Err() == nil {
+            log.Printf("Error waiting for messages on %s: %v", highPriorityQueue, err)
+            time.Sleep(pollInterval)
+        }
+        return
+    }
 
-                // Remove successfully processed message from queue
-                c.redisClient.LRem(c.ctx, queueName, 1, msgData)
-            }
+    if update, ok := c.handleClaimed(highPriorityQueue, msgData); ok {
+        c.flushStatusUpdates([]repository.StatusUpdate{update})
+    }
+}
+
+// processBatch processes up to limit messages from a priority queue and returns how many
+// were claimed. Each message is moved to this worker's processing list as it is claimed
+// and only removed from it once it has been sent, requeued or dead-lettered, so a message
+// interrupted by a crash is returned to its queue by the reaper.
+func (c *MessageConsumer) processBatch(queueName string, limit int) int {
+    // Accumulate status changes for a single flush
+    updates := make([]repository.StatusUpdate, 0, limit)
+    claimed := 0
+    for claimed < limit && c.running.Load() {
+        // Leave the rest of the batch in the queue until the rate limit allows another send
+        if !c.waitForRateLimit() {
+            break
+        }
+
+        msgData, err := c.claimMessage(queueName)
+        if err == redis.Nil {
+            break
+        }
+        if err != nil {
+            log.Printf("Error claiming message from %s: %v", queueName, err)
+            break
+        }
+        claimed++
+
+        if update, ok := c.handleClaimed(queueName, msgData); ok {
+            updates = append(updates, update)
         }
     }
+
+    c.flushStatusUpdates(updates)
+    return claimed
+}
+
+// handleClaimed processes one claimed message and acknowledges it, returning the status
+// change to persist
+func (c *MessageConsumer) handleClaimed(queueName, msgData string) (repository.StatusUpdate, bool) {
+    defer c.acknowledge(queueName, msgData)
+
+    var msg models.Message
+    if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
+        log.Printf("Error unmarshaling message: %v", err)
+        return repository.StatusUpdate{}, false
+    }
+
+    // Guard against tampered or corrupted retry counts bypassing the retry limit
+    if msg.ClampRetryCount() {
+        log.Printf("Message %s had out-of-range retry count, clamped to %d", msg.ID, msg.RetryCount)
+    }
+    if msg.RetryCount >= maxRetries {
+        log.Printf("Message %s already exhausted its retries, moving to dead letter queue", msg.ID)
+        c.moveToDeadLetter(&msg)
+        return repository.StatusUpdate{}, false
+    }
+
+    start := time.Now()
+    err := c.processMessage(&msg)
+    c.metrics.ObserveMessageDuration(queuePriority(queueName), processingOutcome(err), time.Since(start))
+
+    if err != nil {
+        log.Printf("Error processing message %s: %v", msg.ID, err)
+        c.handleFailedMessage(&msg, err)
+        return repository.StatusUpdate{
+            ID:           msg.ID,
+            Status:       msg.Status,
+            ErrorDetails: err.Error(),
+        }, true
+    }
+
+    return repository.StatusUpdate{
+        ID:     msg.ID,
+        Status: msg.Status,
+        SentAt: msg.SentAt,
+    }, true
 }
 
 // processScheduledMessages handles messages scheduled for future delivery
@@ -160,46 +515,209 @@ func (c *MessageConsumer) processScheduledMessages() {
         case <-c.ctx.Done():
             return
         default:
+            if c.paused.Load() {
+                time.Sleep(c.schedulePoll)
+                continue
+            }
+
             now := time.Now()
-            
-            // Fetch due scheduled messages
+
+            // Fetch a batch of due scheduled messages
             messages, err := c.redisClient.ZRangeByScore(c.ctx, scheduledQueue, &redis.ZRangeBy{
-                Min: "0",
-                Max: now.Unix(),
+                Min:   "0",
+                Max:   strconv.FormatInt(now.Unix(), 10),
+                Count: batchSize,
             }).Result()
 
             if err != nil {
                 log.Printf("Error fetching scheduled messages: %v", err)
-                time.Sleep(pollInterval)
+                time.Sleep(c.schedulePoll)
                 continue
             }
 
-            for _, msgData := range messages {
-                var msg models.Message
-                if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
-                    log.Printf("Error unmarshaling scheduled message: %v", err)
-                    continue
+            if len(messages) > 0 {
+                if err := c.moveScheduledMessages(messages); err != nil {
+                    log.Printf("Error moving scheduled messages to queues: %v", err)
                 }
+            }
 
-                // Move message to appropriate priority queue
-                targetQueue := c.determineTargetQueue(&msg)
-                if err := c.redisClient.LPush(c.ctx, targetQueue, msgData).Err(); err != nil {
-                    log.Printf("Error moving scheduled message to queue: %v", err)
-                    continue
-                }
+            time.Sleep(c.schedulePoll)
+        }
+    }
+}
+
+// moveScheduledMessages relocates due scheduled messages to their priority queues in a single round trip
+func (c *MessageConsumer) moveScheduledMessages(messages []string) error {
+    keys := make([]string, 0, len(messages)+2)
+    args := make([]interface{}, 0, len(messages))
+    ids := make([]interface{}, 0, len(messages))
+    keys = append(keys, scheduledQueue, scheduledIndex)
+
+    for _, msgData := range messages {
+        var msg models.Message
+        if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
+            log.Printf("Error unmarshaling scheduled message: %v", err)
+            continue
+        }
+
+        keys = append(keys, c.determineTargetQueue(&msg))
+        args = append(args, msgData)
+        ids = append(ids, msg.ID)
+    }
 
-                // Remove from scheduled queue
-                c.redisClient.ZRem(c.ctx, scheduledQueue, msgData)
+    if len(args) == 0 {
+        return nil
+    }
+
+    return moveScheduledScript.Run(c.ctx, c.redisClient, keys, append(args, ids...)...).Err()
+}
+
+// processingList returns a worker's list of claimed but unacknowledged messages from a queue
+func processingList(workerID, queueName string) string {
+    return queueName + ":processing:" + workerID
+}
+
+// claimsKey returns the sorted set of claim times for a processing list
+func claimsKey(list string) string {
+    return list + ":claims"
+}
+
+// claimMessage atomically moves the message at the front of a queue onto this worker's
+// processing list, returning redis.Nil when the queue is empty
+func (c *MessageConsumer) claimMessage(queueName string) (string, error) {
+    list := processingList(c.workerID, queueName)
+    return claimScript.Run(c.ctx, c.redisClient,
+        []string{queueName, list, claimsKey(list), workersSet},
+        time.Now().Unix(), c.workerID).Text()
+}
+
+// awaitMessage blocks for up to timeout until a message can be claimed from a queue. It is
+// the blocking form of claimMessage (BRPOPLPUSH taking from the front of the queue). A
+// blocking move cannot run inside a script, so the claim time is recorded separately; a
+// message left without one is given a claim time by the reaper and recovered in turn.
+func (c *MessageConsumer) awaitMessage(queueName string, timeout time.Duration) (string, error) {
+    list := processingList(c.workerID, queueName)
+    msgData, err := c.redisClient.BLMove(c.ctx, queueName, list, "LEFT", "RIGHT", timeout).Result()
+    if err != nil {
+        return "", err
+    }
+
+    _, err = c.redisClient.TxPipelined(c.sendCtx, func(pipe redis.Pipeliner) error {
+        pipe.ZAdd(c.sendCtx, claimsKey(list), &redis.Z{Score: float64(time.Now().Unix()), Member: msgData})
+        pipe.SAdd(c.sendCtx, workersSet, c.workerID)
+        return nil
+    })
+    if err != nil {
+        log.Printf("Error recording claim on %s: %v", queueName, err)
+    }
+    return msgData, nil
+}
+
+// acknowledge removes a handled message from the processing list and its claim time
+func (c *MessageConsumer) acknowledge(queueName, msgData string) {
+    list := processingList(c.workerID, queueName)
+    _, err := c.redisClient.TxPipelined(c.sendCtx, func(pipe redis.Pipeliner) error {
+        pipe.LRem(c.sendCtx, list, 1, msgData)
+        pipe.ZRem(c.sendCtx, claimsKey(list), msgData)
+        return nil
+    })
+    if err != nil {
+        log.Printf("Error acknowledging message from %s: %v", queueName, err)
+    }
+}
+
+// requeueUnacknowledged returns messages left on this worker's processing lists by a drain
+// that timed out to the front of their queues in their original order
+func (c *MessageConsumer) requeueUnacknowledged(ctx context.Context) {
+    for _, queueName := range []string{highPriorityQueue, normalPriorityQueue, lowPriorityQueue} {
+        list := processingList(c.workerID, queueName)
+        requeued := 0
+        for {
+            err := c.redisClient.LMove(ctx, list, queueName, "RIGHT", "LEFT").Err()
+            if err == redis.Nil {
+                break
+            }
+            if err != nil {
+                log.Printf("Error requeueing unacknowledged messages to %s: %v", queueName, err)
+                break
             }
+            requeued++
+        }
+        if err := c.redisClient.Del(ctx, claimsKey(list)).Err(); err != nil {
+            log.Printf("Error clearing claims for %s: %v", queueName, err)
+        }
+        if requeued > 0 {
+            log.Printf("Returned %d unacknowledged messages to %s", requeued, queueName)
+        }
+    }
+}
 
-            time.Sleep(pollInterval)
+// reapExpiredClaims periodically returns messages claimed longer than the visibility
+// timeout ago, by any worker, to their queues. This recovers messages held by consumers
+// that died; each consumer has its own worker ID, so none requeues another's messages
+// while they are still being sent.
+func (c *MessageConsumer) reapExpiredClaims() {
+    ticker := time.NewTicker(reapInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-c.ctx.Done():
+            return
+        case <-ticker.C:
+            if err := c.reapOnce(); err != nil && c.ctx.Err() == nil {
+                log.Printf("Error reaping expired claims: %v", err)
+            }
+        }
+    }
+}
+
+// reapOnce requeues every expired claim across all known workers
+func (c *MessageConsumer) reapOnce() error {
+    workers, err := c.redisClient.SMembers(c.ctx, workersSet).Result()
+    if err != nil {
+        return fmt.Errorf("list workers: %w", err)
+    }
+
+    queues := []string{highPriorityQueue, normalPriorityQueue, lowPriorityQueue}
+    cutoff := time.Now().Add(-c.config.VisibilityTimeout).Unix()
+    for _, workerID := range workers {
+        lists := make([]string, 0, len(queues)+1)
+        lists = append(lists, workersSet)
+
+        for _, queueName := range queues {
+            list := processingList(workerID, queueName)
+            lists = append(lists, list)
+
+            requeued, err := reapScript.Run(c.ctx, c.redisClient,
+                []string{queueName, list, claimsKey(list)}, cutoff, time.Now().Unix()).Int()
+            if err != nil {
+                return fmt.Errorf("reap %s: %w", list, err)
+            }
+            if requeued > 0 {
+                log.Printf("Requeued %d messages from %s after visibility timeout", requeued, list)
+            }
+        }
+
+        if workerID != c.workerID {
+            if err := pruneWorkerScript.Run(c.ctx, c.redisClient, lists, workerID).Err(); err != nil {
+                return fmt.Errorf("prune worker %s: %w", workerID, err)
+            }
         }
     }
+    return nil
 }
 
-// fetchMessageBatch retrieves a batch of messages from the specified queue
-func (c *MessageConsumer) fetchMessageBatch(queueName string) ([]string, error) {
-    return c.redisClient.LRange(c.ctx, queueName, 0, batchSize-1).Result()
+// waitForRateLimit blocks until the shared rate limiter has capacity, reporting false if
+// the consumer is shutting down
+func (c *MessageConsumer) waitForRateLimit() bool {
+    if c.rateLimiter == nil {
+        return true
+    }
+    if err := c.rateLimiter.WaitAvailable(c.ctx); err != nil {
+        return false
+    }
+    return true
 }
 
 // processMessage attempts to send a message via WhatsApp
@@ -207,12 +725,13 @@ func (c *MessageConsumer) processMessage(msg *models.Message) error {
     // Update message status to processing
     msg.Status = models.MessageStatusPending
 
+    // Apply pre-send transformations such as footers and redaction
+    if err := models.ApplyTransformers(c.sendCtx, msg, c.transformers); err != nil {
+        return err
+    }
+
     // Attempt to send message via WhatsApp client
-    resp, err := c.whatsappClient.SendMessage(c.ctx, &whatsapp.Message{
-        To:      msg.RecipientPhone,
-        Content: msg.Content,
-        Template: msg.Template,
-    })
+    resp, err := c.whatsappClient.SendMessage(c.sendCtx, models.ToWhatsAppMessage(msg))
 
     if err != nil {
         return err
@@ -227,33 +746,157 @@ func (c *MessageConsumer) processMessage(msg *models.Message) error {
     return nil
 }
 
-// handleFailedMessage processes messages that failed to send
+// flushStatusUpdates persists the status changes of a processed batch in one round trip.
+// If the batch write fails, updates are retried individually so one bad row cannot
+// drop the status of the whole batch.
+func (c *MessageConsumer) flushStatusUpdates(updates []repository.StatusUpdate) {
+    if c.statusStore == nil || len(updates) == 0 {
+        return
+    }
+
+    updated, err := c.statusStore.UpdateStatusBatch(c.sendCtx, updates)
+    if err != nil {
+        log.Printf("Error flushing batch of %d status updates, falling back to individual updates: %v", len(updates), err)
+        for _, update := range updates {
+            // A batch of one keeps the sent time and error details the batch carried
+            if _, err := c.statusStore.UpdateStatusBatch(c.sendCtx, []repository.StatusUpdate{update}); err != nil {
+                log.Printf("Error updating status of message %s: %v", update.ID, err)
+            }
+        }
+        return
+    }
+
+    if len(updated) < len(updates) {
+        found := make(map[string]bool, len(updated))
+        for _, id := range updated {
+            found[id] = true
+        }
+        for _, update := range updates {
+            if !found[update.ID] {
+                log.Printf("Status update for message %s matched no stored message or was stale", update.ID)
+            }
+        }
+    }
+}
+
+// handleFailedMessage dead-letters a failed message or schedules its retry after a delay
+// growing with each attempt
 func (c *MessageConsumer) handleFailedMessage(msg *models.Message, err error) {
     msg.RetryCount++
     msg.Status = models.MessageStatusFailed
+    msg.ErrorDetails = err.Error()
 
-    // Move to dead letter queue if max retries exceeded
-    if msg.RetryCount >= maxRetries {
-        msgData, _ := json.Marshal(msg)
-        c.redisClient.LPush(c.ctx, deadLetterQueue, msgData)
+    // A rejected transform fails the same way on every attempt, so skip the retries.
+    // Otherwise move to dead letter queue if max retries exceeded.
+    if errors.Is(err, models.ErrTransformAborted) || msg.RetryCount >= maxRetries {
+        c.moveToDeadLetter(msg)
         return
     }
 
-    // Otherwise, requeue with delay
-    time.Sleep(retryDelay * time.Duration(msg.RetryCount))
+    // Otherwise retry after a delay through the scheduled set. Sleeping here would stall
+    // the dispatcher, and every priority queue with it, for each failed message.
     msgData, _ := json.Marshal(msg)
-    c.redisClient.LPush(c.ctx, c.determineTargetQueue(msg), msgData)
+    retryAt := time.Now().Add(retryDelay * time.Duration(msg.RetryCount))
+    err = scheduleScript.Run(c.sendCtx, c.redisClient,
+        []string{scheduledQueue, scheduledIndex},
+        msg.ID, float64(retryAt.Unix()), msgData).Err()
+    if err != nil {
+        log.Printf("Error scheduling retry of message %s, requeueing now: %v", msg.ID, err)
+        c.redisClient.LPush(c.sendCtx, c.determineTargetQueue(msg), msgData)
+    }
+}
+
+// moveToDeadLetter pushes a message onto the dead letter queue
+func (c *MessageConsumer) moveToDeadLetter(msg *models.Message) {
+    msgData, _ := json.Marshal(msg)
+    c.redisClient.LPush(c.sendCtx, deadLetterQueue, msgData)
+}
+
+// ReprocessDeadLetters replays up to limit messages from the dead letter queue, oldest
+// first. Each message has its retry count reset and is pushed back onto its priority
+// queue. Entries that no longer unmarshal are moved to the poison queue instead and are
+// not counted. It returns the number of messages re-enqueued.
+func (c *MessageConsumer) ReprocessDeadLetters(ctx context.Context, limit int) (int, error) {
+    if limit <= 0 {
+        return 0, fmt.Errorf("limit must be positive, got %d", limit)
+    }
+
+    reprocessed := 0
+    for i := 0; i < limit; i++ {
+        msgData, err := c.redisClient.RPop(ctx, deadLetterQueue).Result()
+        if err == redis.Nil {
+            break
+        }
+        if err != nil {
+            return reprocessed, fmt.Errorf("pop dead letter: %w", err)
+        }
+
+        var msg models.Message
+        if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
+            log.Printf("Dead letter no longer unmarshals, moving to poison queue: %v", err)
+            if err := c.redisClient.LPush(ctx, poisonQueue, msgData).Err(); err != nil {
+                c.redisClient.RPush(ctx, deadLetterQueue, msgData)
+                return reprocessed, fmt.Errorf("move to poison queue: %w", err)
+            }
+            continue
+        }
+
+        msg.RetryCount = 0
+        msg.Status = models.MessageStatusPending
+        msg.ErrorDetails = ""
+
+        data, err := json.Marshal(&msg)
+        if err != nil {
+            c.redisClient.RPush(ctx, deadLetterQueue, msgData)
+            return reprocessed, fmt.Errorf("marshal message %s: %w", msg.ID, err)
+        }
+
+        // Put the entry back at the oldest end if it cannot be re-enqueued
+        if err := c.redisClient.LPush(ctx, c.determineTargetQueue(&msg), data).Err(); err != nil {
+            c.redisClient.RPush(ctx, deadLetterQueue, msgData)
+            return reprocessed, fmt.Errorf("re-enqueue message %s: %w", msg.ID, err)
+        }
+        reprocessed++
+    }
+
+    return reprocessed, nil
 }
 
-// determineTargetQueue selects the appropriate queue based on message properties
+// DeadLetterSize returns the number of messages waiting in the dead letter queue
+func (c *MessageConsumer) DeadLetterSize(ctx context.Context) (int64, error) {
+    return c.redisClient.LLen(ctx, deadLetterQueue).Result()
+}
+
+// queuePriority returns the priority label of a priority queue
+func queuePriority(queueName string) string {
+    switch queueName {
+    case highPriorityQueue:
+        return "high"
+    case normalPriorityQueue:
+        return "normal"
+    default:
+        return "low"
+    }
+}
+
+// processingOutcome returns the metrics outcome label for a processing result
+func processingOutcome(err error) string {
+    if err != nil {
+        return outcomeFailure
+    }
+    return outcomeSuccess
+}
+
+// determineTargetQueue returns the queue of the priority the producer resolved for a
+// message, so retries and scheduled moves keep its priority. Messages queued without one
+// are routed by the priority their template category implies, as the producer would.
 func (c *MessageConsumer) determineTargetQueue(msg *models.Message) string {
-    // Implement priority queue selection logic
-    switch {
-    case msg.Template != nil:
+    switch resolvePriority(msg, msg.Priority) {
+    case PriorityHigh:
         return highPriorityQueue
-    case msg.Content.MediaURL != "":
-        return normalPriorityQueue
-    default:
+    case PriorityLow:
         return lowPriorityQueue
+    default:
+        return normalPriorityQueue
     }
 }
\ No newline at end of file
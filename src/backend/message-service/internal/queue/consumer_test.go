@@ -0,0 +1,521 @@
+package queue
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/alicebob/miniredis/v2"
+    "github.com/go-redis/redis/v8"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "message-service/internal/models"
+    "message-service/internal/repository"
+    "message-service/pkg/whatsapp"
+    "message-service/pkg/whatsapp/types"
+)
+
+// newTestRedis returns a client for an in-memory Redis that is shut down with the test
+func newTestRedis(t *testing.T) *redis.Client {
+    t.Helper()
+    _, client := newTestRedisServer(t)
+    return client
+}
+
+// newTestRedisServer returns an in-memory Redis and a client for it, for tests that move
+// the server's clock
+func newTestRedisServer(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+    t.Helper()
+    server := miniredis.RunT(t)
+    client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+    t.Cleanup(func() { client.Close() })
+    return server, client
+}
+
+// fakeSender records the messages it is asked to send and answers each with err
+type fakeSender struct {
+    mu   sync.Mutex
+    sent []string
+    err  error
+}
+
+func (s *fakeSender) SendMessage(ctx context.Context, message *whatsapp.Message) (*whatsapp.APIResponse, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.sent = append(s.sent, message.ID)
+    if s.err != nil {
+        return nil, s.err
+    }
+    return &whatsapp.APIResponse{Status: string(whatsapp.MessageStatusSent)}, nil
+}
+
+func (s *fakeSender) sentIDs() []string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]string(nil), s.sent...)
+}
+
+// fakeClock stands still until a limiter waits on it, then jumps ahead by the wait
+type fakeClock struct {
+    mu    sync.Mutex
+    now   time.Time
+    waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.now = c.now.Add(d)
+    c.waits = append(c.waits, d)
+    fired := make(chan time.Time, 1)
+    fired <- c.now
+    return fired
+}
+
+// limitedSender takes a token for every send, as the WhatsApp client does, and exposes
+// its limiter to the consumer
+type limitedSender struct {
+    fakeSender
+    limiter whatsapp.Limiter
+}
+
+func (s *limitedSender) SendMessage(ctx context.Context, message *whatsapp.Message) (*whatsapp.APIResponse, error) {
+    if err := s.limiter.Allow(); err != nil {
+        return nil, err
+    }
+    return s.fakeSender.SendMessage(ctx, message)
+}
+
+func (s *limitedSender) RateLimiter() whatsapp.Limiter {
+    return s.limiter
+}
+
+// rankedStatusStore keeps message statuses in memory and, like the repository's
+// message_status_advances guard, only applies updates that move a message forward
+type rankedStatusStore struct {
+    mu       sync.Mutex
+    messages map[string]*models.Message
+    received []string
+}
+
+func (s *rankedStatusStore) UpdateStatusBatch(ctx context.Context, updates []repository.StatusUpdate) ([]string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    var updated []string
+    for _, update := range updates {
+        s.received = append(s.received, update.Status)
+        msg, ok := s.messages[update.ID]
+        if !ok {
+            continue
+        }
+        before := msg.Status
+        if err := msg.UpdateStatus(update.Status, time.Time{}, nil); err != nil {
+            return updated, err
+        }
+        if msg.Status != before {
+            updated = append(updated, update.ID)
+        }
+    }
+    return updated, nil
+}
+
+func (s *rankedStatusStore) status(id string) string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.messages[id].Status
+}
+
+// claim pushes msg onto queueName and claims it as the consumer would
+func claim(t *testing.T, c *MessageConsumer, queueName string, msg *models.Message) string {
+    t.Helper()
+    data, err := json.Marshal(msg)
+    require.NoError(t, err)
+    require.NoError(t, c.redisClient.RPush(context.Background(), queueName, data).Err())
+
+    claimed, err := c.claimMessage(queueName)
+    require.NoError(t, err)
+    return claimed
+}
+
+func TestHandleClaimedDeadLettersAfterMaxRetries(t *testing.T) {
+    client := newTestRedis(t)
+    sender := &fakeSender{err: errors.New("recipient unavailable")}
+    c := NewMessageConsumer(client, sender, nil, nil)
+
+    msg := &models.Message{
+        ID:             "msg-last-try",
+        RecipientPhone: "+14155550100",
+        RetryCount:     maxRetries - 1,
+        Status:         models.MessageStatusPending,
+    }
+    update, ok := c.handleClaimed(lowPriorityQueue, claim(t, c, lowPriorityQueue, msg))
+
+    require.True(t, ok)
+    assert.Equal(t, models.MessageStatusFailed, update.Status)
+    assert.Equal(t, []string{"msg-last-try"}, sender.sentIDs())
+
+    dead, err := client.LRange(context.Background(), deadLetterQueue, 0, -1).Result()
+    require.NoError(t, err)
+    require.Len(t, dead, 1)
+    var deadMsg models.Message
+    require.NoError(t, json.Unmarshal([]byte(dead[0]), &deadMsg))
+    assert.Equal(t, maxRetries, deadMsg.RetryCount)
+    assert.True(t, strings.Contains(deadMsg.ErrorDetails, "recipient unavailable"))
+
+    queued, err := client.LLen(context.Background(), lowPriorityQueue).Result()
+    require.NoError(t, err)
+    assert.Zero(t, queued, "a message out of retries is not requeued")
+    pending, err := client.LLen(context.Background(), processingList(c.workerID, lowPriorityQueue)).Result()
+    require.NoError(t, err)
+    assert.Zero(t, pending, "the dead-lettered message is acknowledged")
+}
+
+func TestHandleClaimedDeadLettersExhaustedMessageWithoutSending(t *testing.T) {
+    client := newTestRedis(t)
+    sender := &fakeSender{}
+    c := NewMessageConsumer(client, sender, nil, nil)
+
+    msg := &models.Message{ID: "msg-exhausted", RecipientPhone: "+14155550100", RetryCount: maxRetries}
+    _, ok := c.handleClaimed(lowPriorityQueue, claim(t, c, lowPriorityQueue, msg))
+
+    assert.False(t, ok)
+    assert.Empty(t, sender.sentIDs())
+    dead, err := client.LLen(context.Background(), deadLetterQueue).Result()
+    require.NoError(t, err)
+    assert.EqualValues(t, 1, dead)
+}
+
+func TestConsumerConfigShares(t *testing.T) {
+    tests := []struct {
+        name string
+        cfg  ConsumerConfig
+        want [3]int
+    }{
+        {"default weighting", DefaultConsumerConfig(), [3]int{73, 19, 10}},
+        {"equal weights", ConsumerConfig{HighWeight: 1, NormalWeight: 1, LowWeight: 1, BatchSize: 9}, [3]int{3, 3, 3}},
+        {"every queue gets a slot", ConsumerConfig{HighWeight: 100, NormalWeight: 1, LowWeight: 1, BatchSize: 10}, [3]int{10, 1, 1}},
+        {"batch of one", ConsumerConfig{HighWeight: 8, NormalWeight: 2, LowWeight: 1, BatchSize: 1}, [3]int{1, 1, 1}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            assert.Equal(t, tt.want, tt.cfg.shares())
+        })
+    }
+}
+
+func TestDispatchRoundFavoursHighPriorityUnderLoad(t *testing.T) {
+    client := newTestRedis(t)
+    sender := &fakeSender{}
+    c := NewMessageConsumer(client, sender, nil, nil)
+    c.running.Store(true)
+
+    // Flood every queue with more than a whole batch
+    queues := []string{highPriorityQueue, normalPriorityQueue, lowPriorityQueue}
+    for _, queueName := range queues {
+        for i := 0; i < batchSize; i++ {
+            data, err := json.Marshal(&models.Message{ID: queueName + ":" + strconv.Itoa(i), RecipientPhone: "+14155550100"})
+            require.NoError(t, err)
+            require.NoError(t, client.RPush(context.Background(), queueName, data).Err())
+        }
+    }
+
+    // One dispatch round, as dispatch runs it
+    shares := c.config.shares()
+    for i, queueName := range queues {
+        c.processBatch(queueName, shares[i])
+    }
+
+    sent := make(map[string]int)
+    for _, id := range sender.sentIDs() {
+        sent[id[:strings.LastIndex(id, ":")]]++
+    }
+    assert.Equal(t, 73, sent[highPriorityQueue])
+    assert.Equal(t, 19, sent[normalPriorityQueue])
+    assert.Equal(t, 10, sent[lowPriorityQueue])
+    assert.Greater(t, sent[highPriorityQueue], sent[normalPriorityQueue]+sent[lowPriorityQueue])
+}
+
+func TestHandleClaimedSchedulesRetryWithoutBlocking(t *testing.T) {
+    client := newTestRedis(t)
+    sender := &fakeSender{err: errors.New("recipient unavailable")}
+    c := NewMessageConsumer(client, sender, nil, nil)
+
+    msg := &models.Message{ID: "msg-retry", RecipientPhone: "+14155550100", RetryCount: 1}
+    start := time.Now()
+    update, ok := c.handleClaimed(highPriorityQueue, claim(t, c, highPriorityQueue, msg))
+
+    require.True(t, ok)
+    assert.Equal(t, models.MessageStatusFailed, update.Status)
+    assert.Less(t, time.Since(start), retryDelay, "the dispatcher must not sleep out the retry delay")
+
+    ctx := context.Background()
+    scheduled, err := client.ZRangeWithScores(ctx, scheduledQueue, 0, -1).Result()
+    require.NoError(t, err)
+    require.Len(t, scheduled, 1)
+    var retried models.Message
+    require.NoError(t, json.Unmarshal([]byte(scheduled[0].Member.(string)), &retried))
+    assert.Equal(t, 2, retried.RetryCount)
+    assert.InDelta(t, float64(start.Add(2*retryDelay).Unix()), scheduled[0].Score, 1)
+
+    queued, err := client.LLen(ctx, highPriorityQueue).Result()
+    require.NoError(t, err)
+    assert.Zero(t, queued, "the retry waits in the scheduled set")
+    indexed, err := client.HGet(ctx, scheduledIndex, "msg-retry").Result()
+    require.NoError(t, err)
+    assert.Equal(t, scheduled[0].Member, indexed)
+}
+
+func TestRetriedMessageKeepsItsPriority(t *testing.T) {
+    client := newTestRedis(t)
+    sender := &fakeSender{err: errors.New("recipient unavailable")}
+    c := NewMessageConsumer(client, sender, nil, nil)
+
+    // A text message the caller enqueued as high priority
+    msg := newTextMessage("msg-urgent", "your driver has arrived")
+    msg.Priority = PriorityHigh
+    c.handleClaimed(highPriorityQueue, claim(t, c, highPriorityQueue, msg))
+
+    // Move the retry once it is due
+    scheduled, err := client.ZRange(context.Background(), scheduledQueue, 0, -1).Result()
+    require.NoError(t, err)
+    require.Len(t, scheduled, 1)
+    require.NoError(t, c.moveScheduledMessages(scheduled))
+
+    high, err := client.LRange(context.Background(), highPriorityQueue, 0, -1).Result()
+    require.NoError(t, err)
+    require.Len(t, high, 1)
+    var retried models.Message
+    require.NoError(t, json.Unmarshal([]byte(high[0]), &retried))
+    assert.Equal(t, "msg-urgent", retried.ID)
+    assert.Equal(t, PriorityHigh, retried.Priority)
+
+    low, err := client.LLen(context.Background(), lowPriorityQueue).Result()
+    require.NoError(t, err)
+    assert.Zero(t, low)
+}
+
+func TestDetermineTargetQueueWithoutRecordedPriority(t *testing.T) {
+    c := NewMessageConsumer(nil, &fakeSender{}, nil, nil)
+
+    assert.Equal(t, highPriorityQueue, c.determineTargetQueue(newTemplateMessage("msg-1", "AUTHENTICATION")))
+    assert.Equal(t, lowPriorityQueue, c.determineTargetQueue(newTemplateMessage("msg-2", "MARKETING")))
+    assert.Equal(t, normalPriorityQueue, c.determineTargetQueue(newTextMessage("msg-3", "hello")))
+
+    unknown := newTextMessage("msg-4", "hello")
+    unknown.Priority = "urgent"
+    assert.Equal(t, normalPriorityQueue, c.determineTargetQueue(unknown), "an unknown priority falls back to normal")
+}
+
+func TestProcessBatchRespectsRateLimit(t *testing.T) {
+    client := newTestRedis(t)
+    start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+    clock := &fakeClock{now: start}
+    // One send per second with a burst of two
+    sender := &limitedSender{limiter: whatsapp.NewRateLimiter(&whatsapp.RateLimitConfig{Limit: 3600, Burst: 2, Clock: clock})}
+    c := NewMessageConsumer(client, sender, nil, nil)
+    c.running.Store(true)
+
+    for i := 0; i < 5; i++ {
+        data, err := json.Marshal(&models.Message{ID: "msg-" + strconv.Itoa(i), RecipientPhone: "+14155550100"})
+        require.NoError(t, err)
+        require.NoError(t, client.RPush(context.Background(), lowPriorityQueue, data).Err())
+    }
+
+    claimed := c.processBatch(lowPriorityQueue, 5)
+
+    assert.Equal(t, 5, claimed)
+    assert.Equal(t, []string{"msg-0", "msg-1", "msg-2", "msg-3", "msg-4"}, sender.sentIDs(), "no send is rejected by the limiter")
+    assert.Equal(t, []time.Duration{time.Second, time.Second, time.Second}, clock.waits, "the burst goes out at once and the rest one refill apart")
+    assert.Equal(t, start.Add(3*time.Second), clock.Now())
+    retried, err := client.ZCard(context.Background(), scheduledQueue).Result()
+    require.NoError(t, err)
+    assert.Zero(t, retried)
+}
+
+func TestClaimMessageUsesProcessingListUntilAcknowledged(t *testing.T) {
+    client := newTestRedis(t)
+    c := NewMessageConsumer(client, &fakeSender{}, nil, nil)
+    ctx := context.Background()
+
+    first := claim(t, c, normalPriorityQueue, newTextMessage("msg-1", "first"))
+    data, err := json.Marshal(newTextMessage("msg-2", "second"))
+    require.NoError(t, err)
+    require.NoError(t, client.RPush(ctx, normalPriorityQueue, data).Err())
+
+    list := processingList(c.workerID, normalPriorityQueue)
+    pending, err := client.LRange(ctx, list, 0, -1).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{first}, pending, "the claimed message waits on this worker's processing list")
+    queued, err := client.LRange(ctx, normalPriorityQueue, 0, -1).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{string(data)}, queued, "only the claimed message left the queue")
+
+    claimedAt, err := client.ZScore(ctx, claimsKey(list), first).Result()
+    require.NoError(t, err)
+    assert.InDelta(t, float64(time.Now().Unix()), claimedAt, 1)
+    registered, err := client.SIsMember(ctx, workersSet, c.workerID).Result()
+    require.NoError(t, err)
+    assert.True(t, registered, "the reaper can find the worker")
+
+    c.acknowledge(normalPriorityQueue, first)
+
+    remaining, err := client.LLen(ctx, list).Result()
+    require.NoError(t, err)
+    assert.Zero(t, remaining)
+    claims, err := client.ZCard(ctx, claimsKey(list)).Result()
+    require.NoError(t, err)
+    assert.Zero(t, claims)
+}
+
+func TestClaimMessageFromEmptyQueue(t *testing.T) {
+    c := NewMessageConsumer(newTestRedis(t), &fakeSender{}, nil, nil)
+
+    _, err := c.claimMessage(highPriorityQueue)
+
+    assert.Equal(t, redis.Nil, err)
+}
+
+// schedule adds msg to the scheduled set due at the given time, as the producer does
+func schedule(t *testing.T, client *redis.Client, msg *models.Message, at time.Time) string {
+    t.Helper()
+    data, err := json.Marshal(msg)
+    require.NoError(t, err)
+    require.NoError(t, scheduleScript.Run(context.Background(), client,
+        []string{scheduledQueue, scheduledIndex}, msg.ID, float64(at.Unix()), data).Err())
+    return string(data)
+}
+
+func TestProcessScheduledMessagesMovesDueBatch(t *testing.T) {
+    client := newTestRedis(t)
+    c := NewMessageConsumer(client, &fakeSender{}, nil, nil)
+    c.schedulePoll = time.Millisecond
+    ctx := context.Background()
+
+    past := time.Now().Add(-time.Minute)
+    urgent := schedule(t, client, newTemplateMessage("msg-otp", types.TemplateCategoryAuthentication), past)
+    first := schedule(t, client, newTextMessage("msg-1", "first"), past)
+    second := schedule(t, client, newTextMessage("msg-2", "second"), past)
+    future := schedule(t, client, newTextMessage("msg-later", "later"), time.Now().Add(time.Hour))
+
+    c.running.Store(true)
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        c.processScheduledMessages()
+    }()
+    require.Eventually(t, func() bool {
+        return client.ZCard(ctx, scheduledQueue).Val() == 1
+    }, time.Second, time.Millisecond)
+    c.running.Store(false)
+    <-done
+
+    high, err := client.LRange(ctx, highPriorityQueue, 0, -1).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{urgent}, high)
+    normal, err := client.LRange(ctx, normalPriorityQueue, 0, -1).Result()
+    require.NoError(t, err)
+    assert.ElementsMatch(t, []string{first, second}, normal)
+
+    remaining, err := client.ZRange(ctx, scheduledQueue, 0, -1).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{future}, remaining, "the future message is untouched")
+    index, err := client.HGetAll(ctx, scheduledIndex).Result()
+    require.NoError(t, err)
+    assert.Equal(t, map[string]string{"msg-later": future}, index)
+}
+
+func TestMoveScheduledMessagesSkipsMembersAlreadyGone(t *testing.T) {
+    client := newTestRedis(t)
+    c := NewMessageConsumer(client, &fakeSender{}, nil, nil)
+    ctx := context.Background()
+
+    past := time.Now().Add(-time.Minute)
+    due := schedule(t, client, newTextMessage("msg-due", "due"), past)
+    cancelled := schedule(t, client, newTextMessage("msg-cancelled", "cancelled"), past)
+    require.NoError(t, cancelScheduledScript.Run(ctx, client, []string{scheduledQueue, scheduledIndex}, "msg-cancelled").Err())
+
+    // The batch was fetched before the cancellation
+    require.NoError(t, c.moveScheduledMessages([]string{due, cancelled}))
+
+    normal, err := client.LRange(ctx, normalPriorityQueue, 0, -1).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{due}, normal, "a cancelled message is not sent")
+    scheduled, err := client.ZCard(ctx, scheduledQueue).Result()
+    require.NoError(t, err)
+    assert.Zero(t, scheduled)
+}
+
+func TestReapOnceRequeuesExpiredClaimsAtQueueHead(t *testing.T) {
+    client := newTestRedis(t)
+    ctx := context.Background()
+    c := NewMessageConsumer(client, &fakeSender{}, nil, nil)
+    // A consumer that died holding a claim
+    dead := NewMessageConsumer(client, &fakeSender{}, nil, nil)
+
+    stale := claim(t, dead, normalPriorityQueue, newTextMessage("msg-stale", "stale"))
+    fresh := claim(t, c, normalPriorityQueue, newTextMessage("msg-fresh", "fresh"))
+    waiting, err := json.Marshal(newTextMessage("msg-waiting", "waiting"))
+    require.NoError(t, err)
+    require.NoError(t, client.RPush(ctx, normalPriorityQueue, waiting).Err())
+
+    // Backdate the dead consumer's claim past the visibility timeout
+    deadList := processingList(dead.workerID, normalPriorityQueue)
+    expired := time.Now().Add(-c.config.VisibilityTimeout - time.Minute)
+    require.NoError(t, client.ZAdd(ctx, claimsKey(deadList), &redis.Z{Score: float64(expired.Unix()), Member: stale}).Err())
+
+    require.NoError(t, c.reapOnce())
+
+    queued, err := client.LRange(ctx, normalPriorityQueue, 0, -1).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{stale, string(waiting)}, queued, "the expired claim goes back to the head of its queue")
+
+    deadPending, err := client.LLen(ctx, deadList).Result()
+    require.NoError(t, err)
+    assert.Zero(t, deadPending)
+    deadClaims, err := client.ZCard(ctx, claimsKey(deadList)).Result()
+    require.NoError(t, err)
+    assert.Zero(t, deadClaims)
+    workers, err := client.SMembers(ctx, workersSet).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{c.workerID}, workers, "the dead worker is forgotten once its lists are empty")
+
+    pending, err := client.LRange(ctx, processingList(c.workerID, normalPriorityQueue), 0, -1).Result()
+    require.NoError(t, err)
+    assert.Equal(t, []string{fresh}, pending, "a claim within the visibility timeout is left alone")
+}
+
+func TestDeliveredStatusSurvivesLateSentUpdate(t *testing.T) {
+    client := newTestRedis(t)
+    msg := newTextMessage("msg-1", "hello")
+    stored := *msg
+    store := &rankedStatusStore{messages: map[string]*models.Message{msg.ID: &stored}}
+    c := NewMessageConsumer(client, &fakeSender{}, store, nil)
+    c.running.Store(true)
+
+    // The delivery webhook is recorded before the consumer's batch flushes its sent status
+    c.flushStatusUpdates([]repository.StatusUpdate{{ID: msg.ID, Status: models.MessageStatusDelivered}})
+    require.Equal(t, models.MessageStatusDelivered, store.status(msg.ID))
+
+    data, err := json.Marshal(msg)
+    require.NoError(t, err)
+    require.NoError(t, client.RPush(context.Background(), normalPriorityQueue, data).Err())
+    require.Equal(t, 1, c.processBatch(normalPriorityQueue, 1))
+
+    assert.Equal(t, []string{models.MessageStatusDelivered, models.MessageStatusSent}, store.received, "both statuses reach the store out of order")
+    assert.Equal(t, models.MessageStatusDelivered, store.status(msg.ID), "the late sent status must not overwrite delivered")
+    assert.NotNil(t, store.messages[msg.ID].DeliveredAt)
+}
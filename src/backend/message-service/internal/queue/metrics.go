@@ -0,0 +1,75 @@
+// Package queue provides consumer-side metrics for message queue processing
+// Version: go1.21
+package queue
+
+import (
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+    "github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Processing outcomes recorded by consumer metrics
+const (
+    outcomeSuccess = "success"
+    outcomeFailure = "failure"
+)
+
+// ConsumerMetrics records consumer-side message processing metrics
+type ConsumerMetrics interface {
+    ObserveMessageDuration(priority, outcome string, duration time.Duration)
+    SetPaused(paused bool)
+}
+
+// PrometheusConsumerMetrics implements ConsumerMetrics with Prometheus collectors
+type PrometheusConsumerMetrics struct {
+    messageDuration *prometheus.HistogramVec
+    paused          prometheus.Gauge
+}
+
+// NewPrometheusConsumerMetrics registers the consumer collectors with the given registerer,
+// falling back to the default registerer when nil
+func NewPrometheusConsumerMetrics(registerer prometheus.Registerer) *PrometheusConsumerMetrics {
+    if registerer == nil {
+        registerer = prometheus.DefaultRegisterer
+    }
+    factory := promauto.With(registerer)
+
+    return &PrometheusConsumerMetrics{
+        messageDuration: factory.NewHistogramVec(
+            prometheus.HistogramOpts{
+                Name:    "consumer_message_duration_seconds",
+                Help:    "Duration of consumer message processing including WhatsApp API latency",
+                Buckets: prometheus.DefBuckets,
+            },
+            []string{"priority", "outcome"},
+        ),
+        paused: factory.NewGauge(
+            prometheus.GaugeOpts{
+                Name: "consumer_paused",
+                Help: "Whether the message consumer is paused (1) or fetching (0)",
+            },
+        ),
+    }
+}
+
+// ObserveMessageDuration records how long a message took to process
+func (m *PrometheusConsumerMetrics) ObserveMessageDuration(priority, outcome string, duration time.Duration) {
+    m.messageDuration.WithLabelValues(priority, outcome).Observe(duration.Seconds())
+}
+
+// SetPaused records the consumer's paused state
+func (m *PrometheusConsumerMetrics) SetPaused(paused bool) {
+    if paused {
+        m.paused.Set(1)
+        return
+    }
+    m.paused.Set(0)
+}
+
+// noopConsumerMetrics discards all metrics
+type noopConsumerMetrics struct{}
+
+func (noopConsumerMetrics) ObserveMessageDuration(string, string, time.Duration) {}
+
+func (noopConsumerMetrics) SetPaused(bool) {}
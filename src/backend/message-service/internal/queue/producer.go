@@ -4,8 +4,11 @@ package queue
 
 import (
     "context"
+    "crypto/sha256"
+    "encoding/hex"
     "encoding/json"
     "fmt"
+    "strings"
     "time"
 
     "github.com/go-redis/redis/v8"    // v8.11.5
@@ -13,7 +16,9 @@ import (
     "github.com/rs/zerolog"           // v1.30.0
     "github.com/pkg/errors"           // v0.9.1
 
+    "message-service/internal/config"
     "message-service/internal/models"
+    "message-service/pkg/whatsapp/types"
 )
 
 // Queue names for different priority levels
@@ -22,8 +27,52 @@ const (
     normalPriorityQueue = "messages:normal"
     lowPriorityQueue    = "messages:low"
     scheduledQueue      = "messages:scheduled"
+    scheduledIndex      = "scheduled:index"
+    dedupeKeyPrefix     = "messages:dedupe:"
 )
 
+// scheduleScript adds a member to the scheduled set and records it in the index by message
+// ID. KEYS[1] is the scheduled set, KEYS[2] the index; ARGV is the message ID, score and
+// member. A message scheduled again replaces its earlier member.
+var scheduleScript = redis.NewScript(`
+local previous = redis.call('HGET', KEYS[2], ARGV[1])
+if previous then
+    redis.call('ZREM', KEYS[1], previous)
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[3])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+return 1
+`)
+
+// cancelScheduledScript removes a scheduled message by ID using the index. It returns 1
+// when the message was still scheduled and 0 when it was unknown or had already fired.
+var cancelScheduledScript = redis.NewScript(`
+local member = redis.call('HGET', KEYS[2], ARGV[1])
+if not member then
+    return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+return redis.call('ZREM', KEYS[1], member)
+`)
+
+// Message priority levels
+const (
+    PriorityHigh   = "high"
+    PriorityNormal = "normal"
+    PriorityLow    = "low"
+)
+
+// categoryPriorities maps template categories to the priority used when none is given:
+// one-time passcodes are time critical while marketing can wait
+var categoryPriorities = map[string]string{
+    types.TemplateCategoryAuthentication: PriorityHigh,
+    types.TemplateCategoryUtility:        PriorityNormal,
+    types.TemplateCategoryMarketing:      PriorityLow,
+}
+
+// ErrDuplicateMessage is returned when an identical message was already enqueued within the dedupe window
+var ErrDuplicateMessage = errors.New("duplicate message within dedupe window")
+
 // Configuration constants
 const (
     maxBatchSize            = 1000
@@ -31,6 +80,9 @@ const (
     retryDelay             = time.Second * 2
     operationTimeout       = time.Second * 5
     circuitBreakerThreshold = 10
+    circuitBreakerRatio    = 0.6
+    circuitBreakerInterval = time.Minute
+    circuitBreakerTimeout  = time.Minute * 2
     healthCheckInterval    = time.Second * 30
 )
 
@@ -41,7 +93,33 @@ type ProducerConfig struct {
     RetryDelay             time.Duration
     OperationTimeout       time.Duration
     CircuitBreakerThreshold int
+    // CircuitBreaker sets when the breaker opens; zero fields take the producer defaults,
+    // with MinRequests defaulting to CircuitBreakerThreshold
+    CircuitBreaker         config.CircuitBreakerConfig
     HealthCheckInterval    time.Duration
+    // DedupeWindow rejects messages with identical recipient, content and template
+    // enqueued within the window. Zero disables deduplication.
+    DedupeWindow           time.Duration
+    // RollbackPartialBatch removes the messages of a batch that were enqueued when others in
+    // the same batch failed, making EnqueueBatch all-or-nothing. When false the enqueued
+    // messages stay queued and the failures are reported in a PartialBatchError.
+    RollbackPartialBatch   bool
+}
+
+// PartialBatchError reports which messages of a batch could not be enqueued. Enqueued lists
+// the messages left on the queue; it is empty when the successful pushes were rolled back.
+type PartialBatchError struct {
+    Enqueued   []string
+    Failed     map[string]error
+    RolledBack bool
+}
+
+// Error summarizes the failed messages
+func (e *PartialBatchError) Error() string {
+    if e.RolledBack {
+        return fmt.Sprintf("%d batch messages failed to enqueue; batch rolled back", len(e.Failed))
+    }
+    return fmt.Sprintf("%d batch messages failed to enqueue, %d enqueued", len(e.Failed), len(e.Enqueued))
 }
 
 // MessageProducer handles message queue operations with enhanced reliability
@@ -55,9 +133,9 @@ type MessageProducer struct {
 }
 
 // NewMessageProducer creates a new message producer instance with enhanced configuration
-func NewMessageProducer(client *redis.Client, config *ProducerConfig) *MessageProducer {
-    if config == nil {
-        config = &ProducerConfig{
+func NewMessageProducer(client *redis.Client, cfg *ProducerConfig) *MessageProducer {
+    if cfg == nil {
+        cfg = &ProducerConfig{
             MaxBatchSize:            maxBatchSize,
             RetryAttempts:          retryAttempts,
             RetryDelay:             retryDelay,
@@ -70,14 +148,27 @@ func NewMessageProducer(client *redis.Client, config *ProducerConfig) *MessagePr
     ctx, cancel := context.WithCancel(context.Background())
     
     // Configure circuit breaker
+    breaker := cfg.CircuitBreaker
+    if breaker.MinRequests == 0 {
+        breaker.MinRequests = uint32(cfg.CircuitBreakerThreshold)
+    }
+    if breaker.FailureRatio <= 0 || breaker.FailureRatio > 1 {
+        breaker.FailureRatio = circuitBreakerRatio
+    }
+    if breaker.Interval <= 0 {
+        breaker.Interval = circuitBreakerInterval
+    }
+    if breaker.Timeout <= 0 {
+        breaker.Timeout = circuitBreakerTimeout
+    }
     cbSettings := gobreaker.Settings{
         Name:        "redis-producer",
-        MaxRequests: uint32(config.CircuitBreakerThreshold),
-        Interval:    time.Minute,
-        Timeout:     time.Minute * 2,
+        MaxRequests: uint32(cfg.CircuitBreakerThreshold),
+        Interval:    breaker.Interval,
+        Timeout:     breaker.Timeout,
         ReadyToTrip: func(counts gobreaker.Counts) bool {
             failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-            return counts.Requests >= uint32(config.CircuitBreakerThreshold) && failureRatio >= 0.6
+            return counts.Requests >= breaker.MinRequests && failureRatio >= breaker.FailureRatio
         },
         OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
             zerolog.Info().
@@ -94,26 +185,34 @@ func NewMessageProducer(client *redis.Client, config *ProducerConfig) *MessagePr
         cancel:        cancel,
         circuitBreaker: gobreaker.NewCircuitBreaker(cbSettings),
         logger:        zerolog.New(zerolog.NewConsoleWriter()).With().Timestamp().Logger(),
-        config:        config,
+        config:        cfg,
     }
 }
 
-// EnqueueMessage enqueues a single message with priority handling
+// EnqueueMessage enqueues a single message with priority handling. An empty priority is
+// inferred from the template category.
 func (p *MessageProducer) EnqueueMessage(message *models.Message, priority string) error {
     if err := p.validateMessage(message); err != nil {
         return errors.Wrap(err, "message validation failed")
     }
 
+    priority = resolvePriority(message, priority)
     queueName, err := p.getQueueName(priority)
     if err != nil {
         return err
     }
+    message.Priority = priority
 
     data, err := json.Marshal(message)
     if err != nil {
         return errors.Wrap(err, "failed to marshal message")
     }
 
+    dedupeKey, err := p.claimDedupeKey(message)
+    if err != nil {
+        return err
+    }
+
     // Execute through circuit breaker
     _, err = p.circuitBreaker.Execute(func() (interface{}, error) {
         ctx, cancel := context.WithTimeout(p.ctx, p.config.OperationTimeout)
@@ -136,10 +235,15 @@ func (p *MessageProducer) EnqueueMessage(message *models.Message, priority strin
         return nil, errors.New("failed to enqueue message after retries")
     })
 
+    if err != nil {
+        p.releaseDedupeKey(dedupeKey)
+    }
+
     return err
 }
 
-// EnqueueBatch enqueues multiple messages in a batch operation
+// EnqueueBatch enqueues multiple messages in a batch operation. With an empty priority each
+// message's queue is inferred from its template category.
 func (p *MessageProducer) EnqueueBatch(messages []*models.Message, priority string) error {
     if len(messages) == 0 {
         return errors.New("empty message batch")
@@ -148,46 +252,146 @@ func (p *MessageProducer) EnqueueBatch(messages []*models.Message, priority stri
         return fmt.Errorf("batch size exceeds maximum limit of %d", p.config.MaxBatchSize)
     }
 
-    queueName, err := p.getQueueName(priority)
-    if err != nil {
-        return err
+    queueNames := make(map[string]string, len(messages))
+    for _, msg := range messages {
+        if err := p.validateMessage(msg); err != nil {
+            return errors.Wrapf(err, "invalid message in batch: %s", msg.ID)
+        }
+
+        msgPriority := resolvePriority(msg, priority)
+        queueName, err := p.getQueueName(msgPriority)
+        if err != nil {
+            return err
+        }
+        msg.Priority = msgPriority
+        queueNames[msg.ID] = queueName
+    }
+
+    // Coalesce duplicates of recently enqueued messages instead of failing the batch
+    dedupeKeys := make(map[string]string)
+    unique := make([]*models.Message, 0, len(messages))
+    for _, msg := range messages {
+        key, err := p.claimDedupeKey(msg)
+        if errors.Is(err, ErrDuplicateMessage) {
+            continue
+        }
+        if err != nil {
+            p.releaseDedupeKeys(dedupeKeyList(dedupeKeys, nil))
+            return err
+        }
+        if key != "" {
+            dedupeKeys[msg.ID] = key
+        }
+        unique = append(unique, msg)
+    }
+
+    if len(unique) == 0 {
+        return nil
+    }
+    messages = unique
+
+    payloads := make([][]byte, len(messages))
+    for i, msg := range messages {
+        data, err := json.Marshal(msg)
+        if err != nil {
+            p.releaseDedupeKeys(dedupeKeyList(dedupeKeys, nil))
+            return errors.Wrap(err, "failed to marshal message in batch")
+        }
+        payloads[i] = data
     }
 
     // Execute through circuit breaker
-    _, err = p.circuitBreaker.Execute(func() (interface{}, error) {
+    _, err := p.circuitBreaker.Execute(func() (interface{}, error) {
         ctx, cancel := context.WithTimeout(p.ctx, p.config.OperationTimeout)
         defer cancel()
 
         pipe := p.redisClient.Pipeline()
-        for _, msg := range messages {
-            if err := p.validateMessage(msg); err != nil {
-                return nil, errors.Wrapf(err, "invalid message in batch: %s", msg.ID)
-            }
+        for i, msg := range messages {
+            pipe.RPush(ctx, queueNames[msg.ID], payloads[i])
+        }
 
-            data, err := json.Marshal(msg)
-            if err != nil {
-                return nil, errors.Wrap(err, "failed to marshal message in batch")
+        // A pipeline is not atomic: inspect each command, as some pushes can fail while
+        // others succeed
+        cmds, execErr := pipe.Exec(ctx)
+        failed := make(map[string]error)
+        var enqueued []int
+        for i, msg := range messages {
+            if i >= len(cmds) {
+                failed[msg.ID] = execErr
+                continue
+            }
+            if err := cmds[i].Err(); err != nil {
+                failed[msg.ID] = err
+                continue
             }
+            enqueued = append(enqueued, i)
+        }
 
-            pipe.RPush(ctx, queueName, data)
+        if len(enqueued) == 0 && execErr != nil {
+            p.releaseDedupeKeys(dedupeKeyList(dedupeKeys, nil))
+            return nil, errors.Wrap(execErr, "failed to execute batch enqueue")
         }
 
-        _, err := pipe.Exec(ctx)
-        if err != nil {
-            return nil, errors.Wrap(err, "failed to execute batch enqueue")
+        if len(failed) > 0 {
+            return nil, p.handlePartialBatch(ctx, messages, payloads, queueNames, enqueued, failed, dedupeKeys)
         }
 
         p.logger.Info().
             Int("batch_size", len(messages)).
-            Str("queue", queueName).
+            Str("priority", priority).
             Msg("Batch enqueued successfully")
 
         return nil, nil
     })
 
+    // The pipeline never ran, so no message of the batch was enqueued
+    if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+        p.releaseDedupeKeys(dedupeKeyList(dedupeKeys, nil))
+    }
+
     return err
 }
 
+// handlePartialBatch either rolls back the messages that were enqueued or leaves them queued,
+// depending on configuration, and returns a PartialBatchError naming the failed messages.
+// Dedupe keys of messages that are not left on the queue are released so they can be retried.
+func (p *MessageProducer) handlePartialBatch(ctx context.Context, messages []*models.Message, payloads [][]byte, queueNames map[string]string, enqueued []int, failed map[string]error, dedupeKeys map[string]string) error {
+    partial := &PartialBatchError{Failed: failed}
+
+    if p.config.RollbackPartialBatch {
+        // A consumer may already have popped a message; LREM then removes nothing for it
+        pipe := p.redisClient.Pipeline()
+        for _, i := range enqueued {
+            pipe.LRem(ctx, queueNames[messages[i].ID], 1, payloads[i])
+        }
+        if _, err := pipe.Exec(ctx); err != nil {
+            p.logger.Error().
+                Err(err).
+                Int("enqueued", len(enqueued)).
+                Msg("Failed to roll back partially enqueued batch")
+        } else {
+            partial.RolledBack = true
+        }
+    }
+
+    if partial.RolledBack {
+        p.releaseDedupeKeys(dedupeKeyList(dedupeKeys, nil))
+    } else {
+        for _, i := range enqueued {
+            partial.Enqueued = append(partial.Enqueued, messages[i].ID)
+        }
+        p.releaseDedupeKeys(dedupeKeyList(dedupeKeys, failed))
+    }
+
+    p.logger.Warn().
+        Int("failed", len(failed)).
+        Int("enqueued", len(partial.Enqueued)).
+        Bool("rolled_back", partial.RolledBack).
+        Msg("Batch partially enqueued")
+
+    return partial
+}
+
 // ScheduleMessage schedules a message for future delivery
 func (p *MessageProducer) ScheduleMessage(message *models.Message, scheduledTime time.Time) error {
     if err := p.validateMessage(message); err != nil {
@@ -198,6 +402,9 @@ func (p *MessageProducer) ScheduleMessage(message *models.Message, scheduledTime
         return errors.New("scheduled time must be in the future")
     }
 
+    // Record the inferred priority so the consumer moves the message to its queue when due
+    message.Priority = resolvePriority(message, "")
+
     data, err := json.Marshal(message)
     if err != nil {
         return errors.Wrap(err, "failed to marshal message")
@@ -209,10 +416,9 @@ func (p *MessageProducer) ScheduleMessage(message *models.Message, scheduledTime
         defer cancel()
 
         score := float64(scheduledTime.Unix())
-        err := p.redisClient.ZAdd(ctx, scheduledQueue, &redis.Z{
-            Score:  score,
-            Member: data,
-        }).Err()
+        err := scheduleScript.Run(ctx, p.redisClient,
+            []string{scheduledQueue, scheduledIndex},
+            message.ID, score, data).Err()
 
         if err != nil {
             return nil, errors.Wrap(err, "failed to schedule message")
@@ -229,6 +435,37 @@ func (p *MessageProducer) ScheduleMessage(message *models.Message, scheduledTime
     return err
 }
 
+// CancelScheduled removes a scheduled message before it fires, reporting whether it was
+// still scheduled
+func (p *MessageProducer) CancelScheduled(ctx context.Context, messageID string) (bool, error) {
+    if messageID == "" {
+        return false, errors.New("message ID is required")
+    }
+
+    result, err := p.circuitBreaker.Execute(func() (interface{}, error) {
+        ctx, cancel := context.WithTimeout(ctx, p.config.OperationTimeout)
+        defer cancel()
+
+        removed, err := cancelScheduledScript.Run(ctx, p.redisClient,
+            []string{scheduledQueue, scheduledIndex}, messageID).Int()
+        if err != nil {
+            return false, errors.Wrap(err, "failed to cancel scheduled message")
+        }
+        return removed == 1, nil
+    })
+    if err != nil {
+        return false, err
+    }
+
+    cancelled := result.(bool)
+    if cancelled {
+        p.logger.Info().
+            Str("message_id", messageID).
+            Msg("Scheduled message cancelled")
+    }
+    return cancelled, nil
+}
+
 // Close gracefully shuts down the producer
 func (p *MessageProducer) Close() error {
     p.cancel()
@@ -244,16 +481,123 @@ func (p *MessageProducer) validateMessage(message *models.Message) error {
     return message.Validate()
 }
 
+// claimDedupeKey reserves the content hash of a message for the dedupe window, returning
+// ErrDuplicateMessage if it is already reserved. It returns an empty key when deduplication is disabled.
+func (p *MessageProducer) claimDedupeKey(message *models.Message) (string, error) {
+    if p.config.DedupeWindow <= 0 {
+        return "", nil
+    }
+
+    key, err := dedupeKey(message)
+    if err != nil {
+        return "", err
+    }
+
+    ctx, cancel := context.WithTimeout(p.ctx, p.config.OperationTimeout)
+    defer cancel()
+
+    claimed, err := p.redisClient.SetNX(ctx, key, message.ID, p.config.DedupeWindow).Result()
+    if err != nil {
+        return "", errors.Wrap(err, "failed to check message deduplication")
+    }
+    if !claimed {
+        p.logger.Warn().
+            Str("message_id", message.ID).
+            Msg("Duplicate message rejected")
+        return "", ErrDuplicateMessage
+    }
+
+    return key, nil
+}
+
+// releaseDedupeKey frees a reserved dedupe key so a failed enqueue can be retried
+func (p *MessageProducer) releaseDedupeKey(key string) {
+    if key == "" {
+        return
+    }
+    p.releaseDedupeKeys([]string{key})
+}
+
+// releaseDedupeKeys frees multiple reserved dedupe keys
+func (p *MessageProducer) releaseDedupeKeys(keys []string) {
+    if len(keys) == 0 {
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(p.ctx, p.config.OperationTimeout)
+    defer cancel()
+
+    if err := p.redisClient.Del(ctx, keys...).Err(); err != nil {
+        p.logger.Error().
+            Err(err).
+            Int("keys", len(keys)).
+            Msg("Failed to release dedupe keys")
+    }
+}
+
+// dedupeKeyList returns the dedupe keys of the messages in only, or of all messages if only is nil
+func dedupeKeyList(keys map[string]string, only map[string]error) []string {
+    list := make([]string, 0, len(keys))
+    for id, key := range keys {
+        if only != nil {
+            if _, ok := only[id]; !ok {
+                continue
+            }
+        }
+        list = append(list, key)
+    }
+    return list
+}
+
+// dedupeKey derives the dedupe key from the recipient, content and template of a message
+func dedupeKey(message *models.Message) (string, error) {
+    content, err := json.Marshal(message.Content)
+    if err != nil {
+        return "", errors.Wrap(err, "failed to marshal content for dedupe hash")
+    }
+    template, err := json.Marshal(message.Template)
+    if err != nil {
+        return "", errors.Wrap(err, "failed to marshal template for dedupe hash")
+    }
+
+    hash := sha256.New()
+    hash.Write([]byte(message.RecipientPhone))
+    hash.Write([]byte{0})
+    hash.Write(content)
+    hash.Write([]byte{0})
+    hash.Write(template)
+
+    return dedupeKeyPrefix + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // getQueueName returns the appropriate queue name based on priority
 func (p *MessageProducer) getQueueName(priority string) (string, error) {
     switch priority {
-    case "high":
+    case PriorityHigh:
         return highPriorityQueue, nil
-    case "normal":
+    case PriorityNormal:
         return normalPriorityQueue, nil
-    case "low":
+    case PriorityLow:
         return lowPriorityQueue, nil
     default:
         return "", errors.New("invalid priority level")
     }
+}
+
+// resolvePriority returns the explicit priority if given, otherwise the priority implied by
+// the message's template category, defaulting to normal
+func resolvePriority(message *models.Message, priority string) string {
+    if priority != "" {
+        return priority
+    }
+    // Reactions acknowledge a customer in a live conversation
+    if message.Content.Reaction != nil {
+        return PriorityHigh
+    }
+    if message.Template != nil {
+        if inferred, ok := categoryPriorities[strings.ToUpper(message.Template.Category)]; ok {
+            return inferred
+        }
+    }
+    return PriorityNormal
 }
\ No newline at end of file
@@ -0,0 +1,202 @@
+package queue
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "message-service/internal/models"
+    "message-service/pkg/whatsapp/types"
+)
+
+// newTextMessage returns a valid text message
+func newTextMessage(id, text string) *models.Message {
+    return &models.Message{
+        ID:             id,
+        OrganizationID: "org-1",
+        RecipientPhone: "+14155550100",
+        Content:        types.MessageContent{Text: text},
+        Status:         models.MessageStatusPending,
+    }
+}
+
+// newTemplateMessage returns a valid template message of the given category
+func newTemplateMessage(id, category string) *models.Message {
+    msg := newTextMessage(id, "")
+    msg.Template = &types.Template{Name: "notice", Language: "en_US", Category: category}
+    return msg
+}
+
+// testProducerConfig returns the default producer configuration
+func testProducerConfig() *ProducerConfig {
+    return &ProducerConfig{
+        MaxBatchSize:            maxBatchSize,
+        RetryAttempts:           retryAttempts,
+        RetryDelay:              retryDelay,
+        OperationTimeout:        operationTimeout,
+        CircuitBreakerThreshold: circuitBreakerThreshold,
+        HealthCheckInterval:     healthCheckInterval,
+    }
+}
+
+// queuedMessages returns the messages waiting on a queue, oldest first
+func queuedMessages(t *testing.T, p *MessageProducer, queueName string) []models.Message {
+    t.Helper()
+    entries, err := p.redisClient.LRange(context.Background(), queueName, 0, -1).Result()
+    require.NoError(t, err)
+
+    messages := make([]models.Message, len(entries))
+    for i, entry := range entries {
+        require.NoError(t, json.Unmarshal([]byte(entry), &messages[i]))
+    }
+    return messages
+}
+
+func TestResolvePriority(t *testing.T) {
+    reaction := newTextMessage("msg-reaction", "")
+    reaction.Content.Reaction = &types.ReactionContent{MessageID: "wamid.1", Emoji: "👍"}
+
+    tests := []struct {
+        name     string
+        message  *models.Message
+        priority string
+        want     string
+    }{
+        {"authentication template", newTemplateMessage("msg-1", types.TemplateCategoryAuthentication), "", PriorityHigh},
+        {"utility template", newTemplateMessage("msg-2", types.TemplateCategoryUtility), "", PriorityNormal},
+        {"marketing template", newTemplateMessage("msg-3", types.TemplateCategoryMarketing), "", PriorityLow},
+        {"lowercase category", newTemplateMessage("msg-4", "marketing"), "", PriorityLow},
+        {"unknown category", newTemplateMessage("msg-5", "OTHER"), "", PriorityNormal},
+        {"text message", newTextMessage("msg-6", "hello"), "", PriorityNormal},
+        {"reaction", reaction, "", PriorityHigh},
+        {"explicit priority wins", newTemplateMessage("msg-7", types.TemplateCategoryMarketing), PriorityHigh, PriorityHigh},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            assert.Equal(t, tt.want, resolvePriority(tt.message, tt.priority))
+        })
+    }
+}
+
+func TestEnqueueMessageRecordsResolvedPriority(t *testing.T) {
+    p := NewMessageProducer(newTestRedis(t), nil)
+
+    require.NoError(t, p.EnqueueMessage(newTemplateMessage("msg-otp", types.TemplateCategoryAuthentication), ""))
+    require.NoError(t, p.EnqueueMessage(newTemplateMessage("msg-promo", types.TemplateCategoryMarketing), PriorityHigh))
+
+    high := queuedMessages(t, p, highPriorityQueue)
+    require.Len(t, high, 2)
+    assert.Equal(t, "msg-otp", high[0].ID)
+    assert.Equal(t, PriorityHigh, high[0].Priority)
+    assert.Equal(t, "msg-promo", high[1].ID)
+    assert.Equal(t, PriorityHigh, high[1].Priority, "the explicit priority is recorded")
+    assert.Empty(t, queuedMessages(t, p, lowPriorityQueue))
+}
+
+func TestEnqueueBatchReportsPerCommandFailures(t *testing.T) {
+    tests := []struct {
+        name         string
+        rollback     bool
+        wantEnqueued []string
+        wantHigh     []string
+    }{
+        {"partial success", false, []string{"msg-otp-1", "msg-otp-2"}, []string{"msg-otp-1", "msg-otp-2"}},
+        {"rolled back", true, nil, nil},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            client := newTestRedis(t)
+            cfg := testProducerConfig()
+            cfg.RollbackPartialBatch = tt.rollback
+            p := NewMessageProducer(client, cfg)
+
+            // Pushes to the normal queue fail with WRONGTYPE while the others succeed
+            require.NoError(t, client.Set(context.Background(), normalPriorityQueue, "occupied", 0).Err())
+
+            err := p.EnqueueBatch([]*models.Message{
+                newTemplateMessage("msg-otp-1", types.TemplateCategoryAuthentication),
+                newTextMessage("msg-text", "hello"),
+                newTemplateMessage("msg-otp-2", types.TemplateCategoryAuthentication),
+            }, "")
+
+            var partial *PartialBatchError
+            require.True(t, errors.As(err, &partial), "got %v", err)
+            assert.Equal(t, tt.rollback, partial.RolledBack)
+            assert.Equal(t, tt.wantEnqueued, partial.Enqueued)
+            require.Len(t, partial.Failed, 1)
+            assert.Contains(t, partial.Failed["msg-text"].Error(), "WRONGTYPE")
+
+            var high []string
+            for _, msg := range queuedMessages(t, p, highPriorityQueue) {
+                high = append(high, msg.ID)
+            }
+            assert.Equal(t, tt.wantHigh, high)
+        })
+    }
+}
+
+func TestEnqueueMessageDedupeWindow(t *testing.T) {
+    server, client := newTestRedisServer(t)
+    cfg := testProducerConfig()
+    cfg.DedupeWindow = time.Minute
+    p := NewMessageProducer(client, cfg)
+
+    require.NoError(t, p.EnqueueMessage(newTextMessage("msg-1", "your code is 1234"), ""))
+
+    // The same message enqueued again, under a new ID, inside the window
+    err := p.EnqueueMessage(newTextMessage("msg-2", "your code is 1234"), "")
+    assert.ErrorIs(t, err, ErrDuplicateMessage)
+
+    // Different content is not a duplicate
+    require.NoError(t, p.EnqueueMessage(newTextMessage("msg-3", "your code is 5678"), ""))
+
+    server.FastForward(time.Minute - time.Second)
+    assert.ErrorIs(t, p.EnqueueMessage(newTextMessage("msg-4", "your code is 1234"), ""), ErrDuplicateMessage,
+        "the window has not passed yet")
+
+    server.FastForward(time.Second)
+    require.NoError(t, p.EnqueueMessage(newTextMessage("msg-5", "your code is 1234"), ""),
+        "an identical message after the window is accepted")
+
+    var ids []string
+    for _, msg := range queuedMessages(t, p, normalPriorityQueue) {
+        ids = append(ids, msg.ID)
+    }
+    assert.Equal(t, []string{"msg-1", "msg-3", "msg-5"}, ids)
+}
+
+func TestEnqueueMessageWithoutDedupeWindow(t *testing.T) {
+    p := NewMessageProducer(newTestRedis(t), testProducerConfig())
+
+    require.NoError(t, p.EnqueueMessage(newTextMessage("msg-1", "hello"), ""))
+    require.NoError(t, p.EnqueueMessage(newTextMessage("msg-2", "hello"), ""))
+
+    assert.Len(t, queuedMessages(t, p, normalPriorityQueue), 2, "deduplication is opt-in")
+}
+
+func TestEnqueueBatchCoalescesDuplicates(t *testing.T) {
+    cfg := testProducerConfig()
+    cfg.DedupeWindow = time.Minute
+    p := NewMessageProducer(newTestRedis(t), cfg)
+    require.NoError(t, p.EnqueueMessage(newTextMessage("msg-1", "hello"), ""))
+
+    err := p.EnqueueBatch([]*models.Message{
+        newTextMessage("msg-2", "hello"),
+        newTextMessage("msg-3", "goodbye"),
+        newTextMessage("msg-4", "goodbye"),
+    }, "")
+
+    require.NoError(t, err)
+    var ids []string
+    for _, msg := range queuedMessages(t, p, normalPriorityQueue) {
+        ids = append(ids, msg.ID)
+    }
+    assert.Equal(t, []string{"msg-1", "msg-3"}, ids)
+}
@@ -0,0 +1,125 @@
+// Package repository provides database health checks and prepared statement recovery
+// Version: go1.21
+package repository
+
+import (
+    "context"
+    "database/sql"
+
+    "github.com/pkg/errors"     // v0.9.1
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+)
+
+// pingFailureThreshold is how many consecutive failed pings mark the connection as lost, so
+// that statements are prepared again once it recovers
+const pingFailureThreshold = 3
+
+// preparedStatements are the statements prepared when the repository is created
+var preparedStatements = []struct {
+    name  string
+    query string
+}{
+    {"createMessage", createMessageSQL},
+    {"getMessagesByStatus", getMessagesByStatusSQL},
+    {"updateStatusWithMetadata", updateStatusWithMetadataSQL},
+}
+
+// prepareStatements prepares every statement in preparedStatements, closing those already
+// prepared if one fails
+func prepareStatements(ctx context.Context, db *sql.DB) (map[string]*sql.Stmt, error) {
+    stmts := make(map[string]*sql.Stmt, len(preparedStatements))
+    for _, p := range preparedStatements {
+        stmt, err := db.PrepareContext(ctx, p.query)
+        if err != nil {
+            for _, s := range stmts {
+                s.Close()
+            }
+            return nil, errors.Wrapf(err, "failed to prepare %s statement", p.name)
+        }
+        stmts[p.name] = stmt
+    }
+    return stmts, nil
+}
+
+// statement returns the named prepared statement
+func (r *MessageRepository) statement(name string) *sql.Stmt {
+    r.stmtMu.RLock()
+    defer r.stmtMu.RUnlock()
+    return r.statements[name]
+}
+
+// Ping checks that the database answers a trivial query through the pool. After
+// pingFailureThreshold consecutive failures, the first successful ping prepares the
+// statements again so they do not keep failing against the recovered database.
+func (r *MessageRepository) Ping(ctx context.Context) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("ping"))
+    defer timer.ObserveDuration()
+
+    var one int
+    if err := r.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+        r.stmtMu.Lock()
+        r.pingFailures++
+        r.stmtMu.Unlock()
+
+        messageOps.WithLabelValues("ping", "error").Inc()
+        return errors.Wrap(err, "database ping failed")
+    }
+
+    r.stmtMu.RLock()
+    recovered := r.pingFailures >= pingFailureThreshold
+    r.stmtMu.RUnlock()
+
+    if recovered {
+        if err := r.reprepare(ctx); err != nil {
+            messageOps.WithLabelValues("ping", "error").Inc()
+            return err
+        }
+    }
+
+    r.stmtMu.Lock()
+    r.pingFailures = 0
+    r.stmtMu.Unlock()
+
+    messageOps.WithLabelValues("ping", "success").Inc()
+    return nil
+}
+
+// HealthCheck reports whether the database is reachable and every prepared statement is
+// still valid against the current schema. It is usable as a readiness check.
+func (r *MessageRepository) HealthCheck(ctx context.Context) error {
+    if err := r.Ping(ctx); err != nil {
+        return err
+    }
+
+    for _, p := range preparedStatements {
+        if r.statement(p.name) == nil {
+            return errors.Errorf("%s statement is not prepared", p.name)
+        }
+        // Preparing the text again catches statements broken by a schema change
+        stmt, err := r.db.PrepareContext(ctx, p.query)
+        if err != nil {
+            return errors.Wrapf(err, "%s statement is invalid", p.name)
+        }
+        stmt.Close()
+    }
+    return nil
+}
+
+// reprepare replaces the prepared statements with freshly prepared ones
+func (r *MessageRepository) reprepare(ctx context.Context) error {
+    stmts, err := prepareStatements(ctx, r.db)
+    if err != nil {
+        return err
+    }
+
+    r.stmtMu.Lock()
+    old := r.statements
+    r.statements = stmts
+    r.stmtMu.Unlock()
+
+    for _, stmt := range old {
+        stmt.Close()
+    }
+    messageOps.WithLabelValues("reprepare", "success").Inc()
+    return nil
+}
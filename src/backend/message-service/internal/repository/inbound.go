@@ -0,0 +1,136 @@
+// Package repository provides storage of inbound WhatsApp messages and conversation threads
+// Version: go1.21
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "encoding/json"
+    "time"
+
+    "github.com/pkg/errors"     // v0.9.1
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+)
+
+// Message directions
+const (
+    DirectionInbound  = "inbound"
+    DirectionOutbound = "outbound"
+)
+
+// Conversation thread limits
+const (
+    defaultConversationLimit = 50
+    maxConversationLimit     = 500
+)
+
+// SQL statements for inbound messages
+const (
+    storeInboundSQL = `
+        INSERT INTO messages (
+            direction, sender_phone, wa_message_id, raw_payload, content,
+            status, created_at, updated_at
+        ) VALUES ('inbound', $1, $2, $3, '{}', 'received', $4, $4)
+        ON CONFLICT (wa_message_id) WHERE direction = 'inbound' DO NOTHING`
+
+    getConversationSQL = `
+        SELECT id, direction, status, content, raw_payload, created_at
+        FROM messages
+        WHERE ((direction = 'inbound' AND sender_phone = $1)
+        OR (direction = 'outbound' AND recipient_phone = $1))
+        AND deleted_at IS NULL
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2`
+)
+
+// ConversationEntry is one message in a conversation thread with a customer. Content holds
+// what was sent for outbound messages; Payload holds the received webhook for inbound ones.
+type ConversationEntry struct {
+    ID        string          `json:"id"`
+    Direction string          `json:"direction"`
+    Status    string          `json:"status"`
+    Content   json.RawMessage `json:"content,omitempty"`
+    Payload   json.RawMessage `json:"payload,omitempty"`
+    At        time.Time       `json:"at"`
+}
+
+// StoreInbound persists a message received from a customer with its raw webhook payload.
+// Redelivered webhooks for the same message are ignored, whatever time they carry.
+func (r *MessageRepository) StoreInbound(ctx context.Context, messageID, senderPhone string, payload json.RawMessage, receivedAt time.Time) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("store_inbound"))
+    defer timer.ObserveDuration()
+
+    if messageID == "" {
+        return errors.New("message ID is required")
+    }
+    if senderPhone == "" {
+        return errors.New("sender phone is required")
+    }
+    if len(payload) == 0 {
+        payload = json.RawMessage("{}")
+    }
+
+    if _, err := r.db.ExecContext(ctx, storeInboundSQL, senderPhone, messageID, []byte(payload), receivedAt); err != nil {
+        messageOps.WithLabelValues("store_inbound", "error").Inc()
+        return errors.Wrap(err, "failed to store inbound message")
+    }
+
+    messageOps.WithLabelValues("store_inbound", "success").Inc()
+    return nil
+}
+
+// GetConversation returns the latest messages exchanged with a customer, both received and
+// sent, oldest first. A limit of zero or less returns the default number of messages.
+func (r *MessageRepository) GetConversation(ctx context.Context, phone string, limit int) ([]ConversationEntry, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_conversation"))
+    defer timer.ObserveDuration()
+
+    if phone == "" {
+        return nil, errors.New("phone is required")
+    }
+    if limit <= 0 {
+        limit = defaultConversationLimit
+    }
+    if limit > maxConversationLimit {
+        limit = maxConversationLimit
+    }
+
+    rows, err := r.db.QueryContext(ctx, getConversationSQL, phone, limit)
+    if err != nil {
+        messageOps.WithLabelValues("get_conversation", "error").Inc()
+        return nil, errors.Wrap(err, "failed to query conversation")
+    }
+    defer rows.Close()
+
+    entries := make([]ConversationEntry, 0)
+    for rows.Next() {
+        var entry ConversationEntry
+        var content, payload []byte
+        var status sql.NullString
+        if err := rows.Scan(&entry.ID, &entry.Direction, &status, &content, &payload, &entry.At); err != nil {
+            messageOps.WithLabelValues("get_conversation", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan conversation message")
+        }
+        entry.Status = status.String
+        if entry.Direction == DirectionOutbound {
+            entry.Content = content
+        }
+        if len(payload) > 0 {
+            entry.Payload = payload
+        }
+        entries = append(entries, entry)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("get_conversation", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating conversation")
+    }
+
+    // Rows come newest first so the limit keeps the latest messages
+    for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+        entries[i], entries[j] = entries[j], entries[i]
+    }
+
+    messageOps.WithLabelValues("get_conversation", "success").Inc()
+    return entries, nil
+}
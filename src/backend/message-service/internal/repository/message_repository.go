@@ -7,6 +7,7 @@ import (
     "database/sql"  // go1.21
     "encoding/json"
     "fmt"
+    "sync"
     "time"
 
     "github.com/lib/pq"         // v1.10.9
@@ -16,6 +17,7 @@ import (
 
     "message-service/internal/models"
     "message-service/internal/config"
+    "message-service/pkg/whatsapp/types"
 )
 
 // Repository metrics
@@ -38,6 +40,12 @@ var (
     )
 )
 
+// ErrMessageNotFound is returned when a message to update does not exist
+var ErrMessageNotFound = errors.New("message not found")
+
+// MaxScheduledBatch is the most messages GetScheduledMessages returns per call
+const MaxScheduledBatch = defaultBatchSize
+
 // Operation constants
 const (
     defaultBatchSize    = 1000
@@ -51,34 +59,248 @@ const (
     createMessageSQL = `
         INSERT INTO messages (
             id, organization_id, recipient_phone, content, template,
-            status, retry_count, scheduled_at, created_at, updated_at
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+            status, retry_count, scheduled_at, created_at, updated_at,
+            external_ref, callback_url, recurrence
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        ON CONFLICT (id) DO NOTHING
         RETURNING id`
 
     createBatchMessageSQL = `
         INSERT INTO messages (
             id, organization_id, recipient_phone, content, template,
-            status, retry_count, scheduled_at, created_at, updated_at
+            status, retry_count, scheduled_at, created_at, updated_at,
+            external_ref, callback_url, recurrence
         ) 
         SELECT * FROM UNNEST ($1::uuid[], $2::uuid[], $3::text[], $4::jsonb[], 
                             $5::jsonb[], $6::text[], $7::int[], $8::timestamp[], 
-                            $9::timestamp[], $10::timestamp[])`
+                            $9::timestamp[], $10::timestamp[], $11::text[], $12::text[],
+                            $13::jsonb[])
+        ON CONFLICT (id) DO NOTHING
+        RETURNING id`
 
     getScheduledMessagesSQL = `
         SELECT id, organization_id, recipient_phone, content, template,
-               status, retry_count, scheduled_at, created_at, updated_at
+               status, retry_count, scheduled_at, created_at, updated_at,
+               COALESCE(external_ref, ''), COALESCE(callback_url, ''), recurrence
         FROM messages
         WHERE status = $1 
-        AND scheduled_at BETWEEN $2 AND $3
+        AND scheduled_at > $2 AND scheduled_at <= $3
+        AND deleted_at IS NULL
         ORDER BY scheduled_at ASC
         LIMIT $4`
+
+    updateStatusSQL = `
+        WITH old AS (
+            SELECT status FROM messages WHERE id = $1 FOR UPDATE
+        ), upd AS (
+            UPDATE messages
+            SET status = $2, updated_at = $3,
+                read_at = CASE WHEN $2::text = 'read' THEN COALESCE(read_at, $3) ELSE read_at END
+            WHERE id = $1
+            AND message_status_advances(status, $2::text)
+            RETURNING id
+        ), hist AS (
+            INSERT INTO message_status_history (message_id, from_status, to_status, reason, changed_at)
+            SELECT upd.id, old.status, $2::text, NULLIF($4::text, ''), $3
+            FROM upd, old
+            WHERE old.status IS DISTINCT FROM $2::text
+        )
+        SELECT upd.id FROM old LEFT JOIN upd ON TRUE`
+
+    updateStatusWithMetadataSQL = `
+        WITH old AS (
+            SELECT status FROM messages WHERE id = $1 FOR UPDATE
+        ), upd AS (
+            UPDATE messages
+            SET status = $2, updated_at = $4,
+                metadata = COALESCE(metadata, '{}'::jsonb) || $3::jsonb,
+                read_at = CASE WHEN $2::text = 'read' THEN COALESCE(read_at, $4) ELSE read_at END
+            WHERE id = $1
+            AND message_status_advances(status, $2::text)
+            RETURNING id
+        ), hist AS (
+            INSERT INTO message_status_history (message_id, from_status, to_status, changed_at)
+            SELECT upd.id, old.status, $2::text, $4
+            FROM upd, old
+            WHERE old.status IS DISTINCT FROM $2::text
+        )
+        SELECT upd.id FROM old LEFT JOIN upd ON TRUE`
+
+    updateStatusBatchSQL = `
+        WITH u AS (
+            SELECT * FROM UNNEST ($1::uuid[], $2::text[], $3::timestamp[], $4::text[])
+                AS u(id, status, sent_at, error_details)
+        ), old AS (
+            SELECT m.id, m.status FROM messages AS m
+            JOIN u ON m.id = u.id
+            FOR UPDATE OF m
+        ), upd AS (
+            UPDATE messages AS m
+            SET status = u.status,
+                sent_at = COALESCE(u.sent_at, m.sent_at),
+                error_details = COALESCE(NULLIF(u.error_details, ''), m.error_details),
+                updated_at = $5
+            FROM u
+            WHERE m.id = u.id
+            AND message_status_advances(m.status, u.status)
+            RETURNING m.id, m.status
+        ), hist AS (
+            INSERT INTO message_status_history (message_id, from_status, to_status, reason, changed_at)
+            SELECT upd.id, old.status, upd.status, NULLIF(u.error_details, ''), $5
+            FROM upd
+            JOIN old ON old.id = upd.id
+            JOIN u ON u.id = upd.id
+            WHERE old.status IS DISTINCT FROM upd.status
+        )
+        SELECT id FROM upd`
+
+    softDeleteSQL = `
+        UPDATE messages
+        SET deleted_at = $2, updated_at = $2
+        WHERE id = $1
+        AND deleted_at IS NULL`
+
+    purgeMessagesSQL = `
+        WITH purged AS (
+            DELETE FROM messages
+            WHERE id IN (
+                SELECT id FROM messages
+                WHERE (created_at < $1 AND status IN ('sent', 'delivered', 'read', 'failed', 'cancelled', 'received'))
+                OR deleted_at < $1
+                LIMIT $2
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING id
+        ), hist AS (
+            DELETE FROM message_status_history
+            WHERE message_id IN (SELECT id FROM purged)
+        )
+        SELECT COUNT(*) FROM purged`
+
+    appendStatusHistorySQL = `
+        INSERT INTO message_status_history (message_id, from_status, to_status, reason, changed_at)
+        VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5)`
+
+    getStatusHistorySQL = `
+        SELECT from_status, to_status, reason, changed_at
+        FROM message_status_history
+        WHERE message_id = $1
+        ORDER BY changed_at ASC, id ASC`
+
+    storeReferralSQL = `
+        INSERT INTO message_referrals (
+            message_id, sender_phone, source_url, source_id, source_type,
+            headline, body, media_type, image_url, video_url,
+            thumbnail_url, ctwa_clid, received_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        ON CONFLICT (message_id) DO NOTHING`
+
+    storeOrderSQL = `
+        INSERT INTO message_orders (
+            message_id, sender_phone, catalog_id, text, items, received_at
+        ) VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (message_id) DO NOTHING`
+
+    recordInboundSQL = `
+        INSERT INTO customer_sessions (phone, last_inbound_at)
+        VALUES ($1, $2)
+        ON CONFLICT (phone) DO UPDATE
+        SET last_inbound_at = GREATEST(customer_sessions.last_inbound_at, EXCLUDED.last_inbound_at)`
+
+    getLastInboundSQL = `
+        SELECT last_inbound_at FROM customer_sessions
+        WHERE phone = $1`
+
+    claimPendingMessagesSQL = `
+        UPDATE messages
+        SET status = $1, claimed_by = $2, claimed_at = $3, updated_at = $3
+        WHERE id IN (
+            SELECT id FROM messages
+            WHERE status = $4
+            AND (scheduled_at IS NULL OR scheduled_at <= $3)
+            AND deleted_at IS NULL
+            ORDER BY created_at ASC
+            LIMIT $5
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, organization_id, recipient_phone, content, template,
+                  status, retry_count, scheduled_at, created_at, updated_at,
+                  COALESCE(external_ref, ''), COALESCE(callback_url, ''), recurrence`
+
+    resetStaleProcessingSQL = `
+        WITH reset AS (
+            UPDATE messages
+            SET status = $1, claimed_by = NULL, claimed_at = NULL, updated_at = $2
+            WHERE status = $3
+            AND claimed_at < $4
+            AND deleted_at IS NULL
+            RETURNING id
+        ), hist AS (
+            INSERT INTO message_status_history (message_id, from_status, to_status, reason, changed_at)
+            SELECT id, $3::text, $1::text, 'stale_processing_reset', $2
+            FROM reset
+        )
+        SELECT COUNT(*) FROM reset`
+
+    storeConversationSQL = `
+        UPDATE messages
+        SET conversation_id = COALESCE(NULLIF($2, ''), conversation_id),
+            pricing_category = COALESCE(NULLIF($3, ''), pricing_category),
+            billable = COALESCE($4, billable),
+            updated_at = $5
+        WHERE id = $1`
+
+    getBillableConversationSummarySQL = `
+        SELECT pricing_category, COUNT(DISTINCT conversation_id), COUNT(*)
+        FROM messages
+        WHERE organization_id = $1
+        AND billable
+        AND conversation_id IS NOT NULL
+        AND created_at >= $2 AND created_at < $3
+        AND deleted_at IS NULL
+        GROUP BY pricing_category
+        ORDER BY pricing_category`
+
+    getStatusesByIDsSQL = `
+        SELECT id, status FROM messages
+        WHERE id = ANY($1::uuid[])
+        AND deleted_at IS NULL`
+
+    getCallbackTargetSQL = `
+        SELECT COALESCE(callback_url, ''), COALESCE(external_ref, '')
+        FROM messages
+        WHERE id = $1
+        AND deleted_at IS NULL`
+
+    getMessageIDByExternalRefSQL = `
+        SELECT id FROM messages
+        WHERE external_ref = $1
+        AND deleted_at IS NULL
+        ORDER BY created_at DESC
+        LIMIT 1`
 )
 
+// StatusUpdate describes a single message status change applied by UpdateStatusBatch
+type StatusUpdate struct {
+    ID           string
+    Status       string
+    SentAt       *time.Time
+    ErrorDetails string
+}
+
+// InsertResult reports which message IDs an insert created and which already existed
+type InsertResult struct {
+    Inserted []string
+    Skipped  []string
+}
+
 // MessageRepository provides thread-safe access to message storage
 type MessageRepository struct {
     db        *sql.DB
     cfg       *config.Config
     statements map[string]*sql.Stmt
+    stmtMu     sync.RWMutex
+    pingFailures int
 }
 
 // NewMessageRepository creates a new repository instance with connection pooling
@@ -96,14 +318,12 @@ func NewMessageRepository(db *sql.DB, cfg *config.Config) (*MessageRepository, e
     db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
 
     // Create prepared statements
-    stmts := make(map[string]*sql.Stmt)
     ctx, cancel := context.WithTimeout(context.Background(), defaultQueryTimeout)
     defer cancel()
 
-    var err error
-    stmts["createMessage"], err = db.PrepareContext(ctx, createMessageSQL)
+    stmts, err := prepareStatements(ctx, db)
     if err != nil {
-        return nil, errors.Wrap(err, "failed to prepare createMessage statement")
+        return nil, err
     }
 
     return &MessageRepository{
@@ -113,13 +333,74 @@ func NewMessageRepository(db *sql.DB, cfg *config.Config) (*MessageRepository, e
     }, nil
 }
 
-// CreateBatch efficiently inserts multiple messages in a single transaction
-func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*models.Message) error {
+// Create inserts a single message. It reports false without error if a message with the
+// same ID already exists, so retried enqueues are idempotent.
+func (r *MessageRepository) Create(ctx context.Context, msg *models.Message) (bool, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("create"))
+    defer timer.ObserveDuration()
+
+    if err := msg.Validate(); err != nil {
+        messageOps.WithLabelValues("create", "validation_error").Inc()
+        return false, errors.Wrap(err, "message validation failed")
+    }
+
+    contentJSON, err := json.Marshal(msg.Content)
+    if err != nil {
+        return false, errors.Wrap(err, "failed to marshal content")
+    }
+
+    var templateJSON []byte
+    if msg.Template != nil {
+        templateJSON, err = json.Marshal(msg.Template)
+        if err != nil {
+            return false, errors.Wrap(err, "failed to marshal template")
+        }
+    }
+
+    recurrenceJSON, err := marshalRecurrence(msg.Recurrence)
+    if err != nil {
+        return false, err
+    }
+
+    var id string
+    err = r.statement("createMessage").QueryRowContext(ctx,
+        msg.ID,
+        msg.OrganizationID,
+        msg.RecipientPhone,
+        contentJSON,
+        templateJSON,
+        msg.Status,
+        msg.RetryCount,
+        msg.ScheduledAt,
+        msg.CreatedAt,
+        msg.UpdatedAt,
+        msg.ExternalRef,
+        msg.CallbackURL,
+        recurrenceJSON,
+    ).Scan(&id)
+    if err == sql.ErrNoRows {
+        messageOps.WithLabelValues("create", "duplicate").Inc()
+        return false, nil
+    }
+    if err != nil {
+        messageOps.WithLabelValues("create", "error").Inc()
+        return false, errors.Wrap(err, "failed to insert message")
+    }
+
+    messageOps.WithLabelValues("create", "success").Inc()
+    return true, nil
+}
+
+// CreateBatch efficiently inserts multiple messages in a single transaction. Messages whose
+// ID already exists, including repeats within the batch, are skipped rather than failing
+// the transaction; the result lists which IDs were inserted and which were skipped.
+func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*models.Message) (*InsertResult, error) {
     timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("create_batch"))
     defer timer.ObserveDuration()
 
+    result := &InsertResult{}
     if len(messages) == 0 {
-        return nil
+        return result, nil
     }
 
     // Begin transaction
@@ -129,7 +410,7 @@ func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*models.
     })
     if err != nil {
         messageOps.WithLabelValues("create_batch", "error").Inc()
-        return errors.Wrap(err, "failed to begin transaction")
+        return nil, errors.Wrap(err, "failed to begin transaction")
     }
     defer tx.Rollback()
 
@@ -152,12 +433,15 @@ func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*models.
         scheduledAts := make([]time.Time, len(batch))
         createdAts := make([]time.Time, len(batch))
         updatedAts := make([]time.Time, len(batch))
+        externalRefs := make([]string, len(batch))
+        callbackURLs := make([]string, len(batch))
+        recurrences := make([][]byte, len(batch))
 
         // Populate arrays
         for j, msg := range batch {
             if err := msg.Validate(); err != nil {
                 messageOps.WithLabelValues("create_batch", "validation_error").Inc()
-                return errors.Wrap(err, "message validation failed")
+                return nil, errors.Wrap(err, "message validation failed")
             }
 
             ids[j] = msg.ID
@@ -166,14 +450,14 @@ func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*models.
             
             contentJSON, err := json.Marshal(msg.Content)
             if err != nil {
-                return errors.Wrap(err, "failed to marshal content")
+                return nil, errors.Wrap(err, "failed to marshal content")
             }
             contents[j] = contentJSON
 
             if msg.Template != nil {
                 templateJSON, err := json.Marshal(msg.Template)
                 if err != nil {
-                    return errors.Wrap(err, "failed to marshal template")
+                    return nil, errors.Wrap(err, "failed to marshal template")
                 }
                 templates[j] = templateJSON
             }
@@ -185,10 +469,17 @@ func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*models.
             }
             createdAts[j] = msg.CreatedAt
             updatedAts[j] = msg.UpdatedAt
+            externalRefs[j] = msg.ExternalRef
+            callbackURLs[j] = msg.CallbackURL
+
+            recurrences[j], err = marshalRecurrence(msg.Recurrence)
+            if err != nil {
+                return nil, err
+            }
         }
 
-        // Execute batch insert
-        _, err = tx.ExecContext(ctx, createBatchMessageSQL,
+        // Execute batch insert, collecting the IDs that were actually inserted
+        rows, err := tx.QueryContext(ctx, createBatchMessageSQL,
             pq.Array(ids),
             pq.Array(orgIDs),
             pq.Array(phones),
@@ -199,24 +490,48 @@ func (r *MessageRepository) CreateBatch(ctx context.Context, messages []*models.
             pq.Array(scheduledAts),
             pq.Array(createdAts),
             pq.Array(updatedAts),
+            pq.Array(externalRefs),
+            pq.Array(callbackURLs),
+            pq.Array(recurrences),
         )
         if err != nil {
             messageOps.WithLabelValues("create_batch", "error").Inc()
-            return errors.Wrap(err, "failed to execute batch insert")
+            return nil, errors.Wrap(err, "failed to execute batch insert")
+        }
+
+        inserted, err := collectIDs(rows)
+        if err != nil {
+            messageOps.WithLabelValues("create_batch", "error").Inc()
+            return nil, errors.Wrap(err, "failed to read inserted message IDs")
+        }
+
+        for _, id := range ids {
+            if inserted[id] {
+                result.Inserted = append(result.Inserted, id)
+                // A repeated ID later in the batch is reported as skipped
+                delete(inserted, id)
+            } else {
+                result.Skipped = append(result.Skipped, id)
+            }
         }
     }
 
     // Commit transaction
     if err := tx.Commit(); err != nil {
         messageOps.WithLabelValues("create_batch", "error").Inc()
-        return errors.Wrap(err, "failed to commit transaction")
+        return nil, errors.Wrap(err, "failed to commit transaction")
     }
 
+    if len(result.Skipped) > 0 {
+        messageOps.WithLabelValues("create_batch", "duplicate").Add(float64(len(result.Skipped)))
+    }
     messageOps.WithLabelValues("create_batch", "success").Inc()
-    return nil
+    return result, nil
 }
 
-// GetScheduledMessages retrieves messages scheduled for delivery within a time window
+// GetScheduledMessages retrieves messages scheduled for delivery within the window
+// (startTime, endTime], so consecutive windows sharing a boundary neither overlap nor leave
+// a gap. At most MaxScheduledBatch messages are returned, earliest first.
 func (r *MessageRepository) GetScheduledMessages(ctx context.Context, startTime, endTime time.Time) ([]*models.Message, error) {
     timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_scheduled"))
     defer timer.ObserveDuration()
@@ -239,51 +554,696 @@ func (r *MessageRepository) GetScheduledMessages(ctx context.Context, startTime,
     defer rows.Close()
 
     for rows.Next() {
-        var msg models.Message
-        var contentJSON, templateJSON []byte
-        var scheduledAt sql.NullTime
-
-        err := rows.Scan(
-            &msg.ID,
-            &msg.OrganizationID,
-            &msg.RecipientPhone,
-            &contentJSON,
-            &templateJSON,
-            &msg.Status,
-            &msg.RetryCount,
-            &scheduledAt,
-            &msg.CreatedAt,
-            &msg.UpdatedAt,
-        )
+        msg, err := scanMessage(rows)
         if err != nil {
             messageOps.WithLabelValues("get_scheduled", "error").Inc()
-            return nil, errors.Wrap(err, "failed to scan message row")
+            return nil, err
+        }
+        messages = append(messages, msg)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("get_scheduled", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating message rows")
+    }
+
+    messageOps.WithLabelValues("get_scheduled", "success").Inc()
+    return messages, nil
+}
+
+// ClaimPendingMessages atomically marks up to limit due pending messages as processing
+// and assigns them to workerID. Rows locked by a concurrent claim are skipped, so each
+// message is claimed by exactly one worker.
+func (r *MessageRepository) ClaimPendingMessages(ctx context.Context, workerID string, limit int) ([]*models.Message, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("claim_pending"))
+    defer timer.ObserveDuration()
+
+    if workerID == "" {
+        return nil, errors.New("worker ID is required")
+    }
+    if limit <= 0 || limit > defaultBatchSize {
+        limit = defaultBatchSize
+    }
+
+    rows, err := r.db.QueryContext(ctx, claimPendingMessagesSQL,
+        models.MessageStatusProcessing,
+        workerID,
+        time.Now(),
+        models.MessageStatusPending,
+        limit,
+    )
+    if err != nil {
+        messageOps.WithLabelValues("claim_pending", "error").Inc()
+        return nil, errors.Wrap(err, "failed to claim pending messages")
+    }
+    defer rows.Close()
+
+    var messages []*models.Message
+    for rows.Next() {
+        msg, err := scanMessage(rows)
+        if err != nil {
+            messageOps.WithLabelValues("claim_pending", "error").Inc()
+            return nil, err
         }
+        messages = append(messages, msg)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("claim_pending", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating claimed message rows")
+    }
+
+    messageOps.WithLabelValues("claim_pending", "success").Inc()
+    return messages, nil
+}
+
+// ResetStaleProcessing returns messages claimed longer than olderThan ago, whose worker has
+// presumably died, to pending so another worker claims them. It returns the number reset.
+func (r *MessageRepository) ResetStaleProcessing(ctx context.Context, olderThan time.Duration) (int, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("reset_stale_processing"))
+    defer timer.ObserveDuration()
+
+    if olderThan <= 0 {
+        return 0, errors.New("stale processing timeout must be positive")
+    }
+
+    now := time.Now()
+    var reset int
+    err := r.db.QueryRowContext(ctx, resetStaleProcessingSQL,
+        models.MessageStatusPending,
+        now,
+        models.MessageStatusProcessing,
+        now.Add(-olderThan),
+    ).Scan(&reset)
+    if err != nil {
+        messageOps.WithLabelValues("reset_stale_processing", "error").Inc()
+        return 0, errors.Wrap(err, "failed to reset stale processing messages")
+    }
+
+    messageOps.WithLabelValues("reset_stale_processing", "success").Inc()
+    return reset, nil
+}
 
-        if err := json.Unmarshal(contentJSON, &msg.Content); err != nil {
-            return nil, errors.Wrap(err, "failed to unmarshal content")
+// UpdateStatus sets the status of a message, returning sql.ErrNoRows if it does not exist.
+// It reports false when the status was stale and the message was left as it was.
+func (r *MessageRepository) UpdateStatus(ctx context.Context, id, status string) (bool, error) {
+    return r.UpdateStatusWithReason(ctx, id, status, "")
+}
+
+// UpdateStatusWithReason sets the status of a message and records the transition, with the
+// given reason, in its status history. It returns sql.ErrNoRows if the message does not exist.
+// Delivery statuses arrive out of order, so once a message is sent a status ranked at or
+// below the current one is ignored; it then reports false and records no history.
+func (r *MessageRepository) UpdateStatusWithReason(ctx context.Context, id, status, reason string) (bool, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("update_status"))
+    defer timer.ObserveDuration()
+
+    var updatedID sql.NullString
+    err := r.db.QueryRowContext(ctx, updateStatusSQL, id, status, time.Now(), reason).Scan(&updatedID)
+    if err == sql.ErrNoRows {
+        messageOps.WithLabelValues("update_status", "not_found").Inc()
+        return false, sql.ErrNoRows
+    }
+    if err != nil {
+        messageOps.WithLabelValues("update_status", "error").Inc()
+        return false, errors.Wrap(err, "failed to update message status")
+    }
+    if !updatedID.Valid {
+        messageOps.WithLabelValues("update_status", "stale").Inc()
+        return false, nil
+    }
+
+    messageOps.WithLabelValues("update_status", "success").Inc()
+    return true, nil
+}
+
+// UpdateStatusWithMetadata sets the status of a message and merges metadata into its stored
+// metadata, replacing keys that are already present. It returns ErrMessageNotFound if the
+// message does not exist, and reports false when the status was stale, as for
+// UpdateStatusWithReason, leaving the message and its metadata as they were.
+func (r *MessageRepository) UpdateStatusWithMetadata(ctx context.Context, id, status string, metadata map[string]interface{}) (bool, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("update_status_metadata"))
+    defer timer.ObserveDuration()
+
+    if metadata == nil {
+        metadata = map[string]interface{}{}
+    }
+    metadataJSON, err := json.Marshal(metadata)
+    if err != nil {
+        messageOps.WithLabelValues("update_status_metadata", "error").Inc()
+        return false, errors.Wrap(err, "failed to marshal metadata")
+    }
+
+    ctx, cancel := context.WithTimeout(ctx, defaultQueryTimeout)
+    defer cancel()
+
+    var updatedID sql.NullString
+    err = r.statement("updateStatusWithMetadata").QueryRowContext(ctx, id, status, metadataJSON, time.Now()).Scan(&updatedID)
+    if err == sql.ErrNoRows {
+        messageOps.WithLabelValues("update_status_metadata", "not_found").Inc()
+        return false, ErrMessageNotFound
+    }
+    if err != nil {
+        messageOps.WithLabelValues("update_status_metadata", "error").Inc()
+        return false, errors.Wrap(err, "failed to update message status and metadata")
+    }
+    if !updatedID.Valid {
+        messageOps.WithLabelValues("update_status_metadata", "stale").Inc()
+        return false, nil
+    }
+
+    messageOps.WithLabelValues("update_status_metadata", "success").Inc()
+    return true, nil
+}
+
+// SoftDelete hides a message from all reads until it is purged. It returns sql.ErrNoRows if
+// the message does not exist or is already deleted.
+func (r *MessageRepository) SoftDelete(ctx context.Context, id string) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("soft_delete"))
+    defer timer.ObserveDuration()
+
+    result, err := r.db.ExecContext(ctx, softDeleteSQL, id, time.Now())
+    if err != nil {
+        messageOps.WithLabelValues("soft_delete", "error").Inc()
+        return errors.Wrap(err, "failed to soft-delete message")
+    }
+    affected, err := result.RowsAffected()
+    if err != nil {
+        messageOps.WithLabelValues("soft_delete", "error").Inc()
+        return errors.Wrap(err, "failed to get affected rows")
+    }
+    if affected == 0 {
+        messageOps.WithLabelValues("soft_delete", "not_found").Inc()
+        return sql.ErrNoRows
+    }
+
+    messageOps.WithLabelValues("soft_delete", "success").Inc()
+    return nil
+}
+
+// PurgeOlderThan permanently deletes messages soft-deleted before cutoff, and those created
+// before it that reached a final status or were received from customers, along with their
+// status history, and returns how many messages were deleted. Messages still pending,
+// scheduled or processing are kept. It deletes in
+// batches of defaultBatchSize so no single statement holds locks for long; on error the
+// count covers the batches already committed.
+func (r *MessageRepository) PurgeOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("purge"))
+    defer timer.ObserveDuration()
+
+    var total int64
+    for {
+        if err := ctx.Err(); err != nil {
+            messageOps.WithLabelValues("purge", "error").Inc()
+            return total, err
         }
 
-        if len(templateJSON) > 0 {
-            var template models.Template
-            if err := json.Unmarshal(templateJSON, &template); err != nil {
-                return nil, errors.Wrap(err, "failed to unmarshal template")
-            }
-            msg.Template = &template
+        var purged int64
+        if err := r.db.QueryRowContext(ctx, purgeMessagesSQL, cutoff, defaultBatchSize).Scan(&purged); err != nil {
+            messageOps.WithLabelValues("purge", "error").Inc()
+            return total, errors.Wrap(err, "failed to purge messages")
+        }
+        total += purged
+
+        if purged < defaultBatchSize {
+            break
         }
+    }
+
+    messageOps.WithLabelValues("purge", "success").Inc()
+    return total, nil
+}
 
-        if scheduledAt.Valid {
-            msg.ScheduledAt = &scheduledAt.Time
+// UpdateStatusBatch applies multiple status updates in a single statement and returns the IDs
+// that were updated. IDs missing from the result did not match an existing message or carried
+// a stale status, which is ignored as in UpdateStatusWithReason.
+// Each status change is recorded in the message's status history with its error details as reason.
+func (r *MessageRepository) UpdateStatusBatch(ctx context.Context, updates []StatusUpdate) ([]string, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("update_status_batch"))
+    defer timer.ObserveDuration()
+
+    if len(updates) == 0 {
+        return nil, nil
+    }
+
+    ids := make([]string, len(updates))
+    statuses := make([]string, len(updates))
+    sentAts := make([]sql.NullTime, len(updates))
+    errorDetails := make([]string, len(updates))
+    for i, update := range updates {
+        ids[i] = update.ID
+        statuses[i] = update.Status
+        if update.SentAt != nil {
+            sentAts[i] = sql.NullTime{Time: *update.SentAt, Valid: true}
         }
+        errorDetails[i] = update.ErrorDetails
+    }
 
-        messages = append(messages, &msg)
+    rows, err := r.db.QueryContext(ctx, updateStatusBatchSQL,
+        pq.Array(ids),
+        pq.Array(statuses),
+        pq.Array(sentAts),
+        pq.Array(errorDetails),
+        time.Now(),
+    )
+    if err != nil {
+        messageOps.WithLabelValues("update_status_batch", "error").Inc()
+        return nil, errors.Wrap(err, "failed to execute batch status update")
+    }
+    defer rows.Close()
+
+    updated := make([]string, 0, len(updates))
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            messageOps.WithLabelValues("update_status_batch", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan updated message id")
+        }
+        updated = append(updated, id)
     }
 
     if err := rows.Err(); err != nil {
-        messageOps.WithLabelValues("get_scheduled", "error").Inc()
-        return nil, errors.Wrap(err, "error iterating message rows")
+        messageOps.WithLabelValues("update_status_batch", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating updated rows")
     }
 
-    messageOps.WithLabelValues("get_scheduled", "success").Inc()
-    return messages, nil
-}
\ No newline at end of file
+    messageOps.WithLabelValues("update_status_batch", "success").Inc()
+    return updated, nil
+}
+
+// GetStatusesByIDs returns the stored status of each of the given messages in one query,
+// keyed by message ID. IDs that do not match a message are absent from the result.
+func (r *MessageRepository) GetStatusesByIDs(ctx context.Context, ids []string) (map[string]string, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_statuses_by_ids"))
+    defer timer.ObserveDuration()
+
+    statuses := make(map[string]string, len(ids))
+    if len(ids) == 0 {
+        return statuses, nil
+    }
+
+    rows, err := r.db.QueryContext(ctx, getStatusesByIDsSQL, pq.Array(ids))
+    if err != nil {
+        messageOps.WithLabelValues("get_statuses_by_ids", "error").Inc()
+        return nil, errors.Wrap(err, "failed to query message statuses")
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var id, status string
+        if err := rows.Scan(&id, &status); err != nil {
+            messageOps.WithLabelValues("get_statuses_by_ids", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan message status")
+        }
+        statuses[id] = status
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("get_statuses_by_ids", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating message statuses")
+    }
+
+    messageOps.WithLabelValues("get_statuses_by_ids", "success").Inc()
+    return statuses, nil
+}
+
+// StoreReferral persists click-to-WhatsApp attribution for an inbound message within the sender's conversation
+func (r *MessageRepository) StoreReferral(ctx context.Context, messageID, senderPhone string, referral *types.Referral, receivedAt time.Time) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("store_referral"))
+    defer timer.ObserveDuration()
+
+    if referral == nil {
+        return errors.New("referral is required")
+    }
+
+    _, err := r.db.ExecContext(ctx, storeReferralSQL,
+        messageID,
+        senderPhone,
+        referral.SourceURL,
+        referral.SourceID,
+        referral.SourceType,
+        referral.Headline,
+        referral.Body,
+        referral.MediaType,
+        referral.ImageURL,
+        referral.VideoURL,
+        referral.ThumbnailURL,
+        referral.CtwaClid,
+        receivedAt,
+    )
+    if err != nil {
+        messageOps.WithLabelValues("store_referral", "error").Inc()
+        return errors.Wrap(err, "failed to store referral")
+    }
+
+    messageOps.WithLabelValues("store_referral", "success").Inc()
+    return nil
+}
+
+// StoreOrder persists a catalog order linked to the customer's conversation by sender phone.
+// Redelivered webhooks for the same message are ignored.
+func (r *MessageRepository) StoreOrder(ctx context.Context, order *types.OrderEvent) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("store_order"))
+    defer timer.ObserveDuration()
+
+    if order == nil {
+        return errors.New("order is required")
+    }
+
+    items, err := json.Marshal(order.Items)
+    if err != nil {
+        return errors.Wrap(err, "failed to marshal order items")
+    }
+
+    _, err = r.db.ExecContext(ctx, storeOrderSQL,
+        order.MessageID,
+        order.From,
+        order.CatalogID,
+        order.Text,
+        items,
+        order.Timestamp,
+    )
+    if err != nil {
+        messageOps.WithLabelValues("store_order", "error").Inc()
+        return errors.Wrap(err, "failed to store order")
+    }
+
+    messageOps.WithLabelValues("store_order", "success").Inc()
+    return nil
+}
+
+// RecordInbound notes that a customer messaged us at the given time, opening or extending
+// their customer service window. Out-of-order webhooks never move the time backwards.
+func (r *MessageRepository) RecordInbound(ctx context.Context, phone string, at time.Time) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("record_inbound"))
+    defer timer.ObserveDuration()
+
+    if phone == "" {
+        return errors.New("phone is required")
+    }
+
+    if _, err := r.db.ExecContext(ctx, recordInboundSQL, phone, at); err != nil {
+        messageOps.WithLabelValues("record_inbound", "error").Inc()
+        return errors.Wrap(err, "failed to record inbound message")
+    }
+
+    messageOps.WithLabelValues("record_inbound", "success").Inc()
+    return nil
+}
+
+// GetLastInboundAt returns when the customer last messaged us, or nil if they never have
+func (r *MessageRepository) GetLastInboundAt(ctx context.Context, phone string) (*time.Time, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_last_inbound"))
+    defer timer.ObserveDuration()
+
+    var lastInbound time.Time
+    err := r.db.QueryRowContext(ctx, getLastInboundSQL, phone).Scan(&lastInbound)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            messageOps.WithLabelValues("get_last_inbound", "not_found").Inc()
+            return nil, nil
+        }
+        messageOps.WithLabelValues("get_last_inbound", "error").Inc()
+        return nil, errors.Wrap(err, "failed to get last inbound message")
+    }
+
+    messageOps.WithLabelValues("get_last_inbound", "success").Inc()
+    return &lastInbound, nil
+}
+
+// ConversationSummary counts the billable conversations and messages in one pricing category
+type ConversationSummary struct {
+    Category      string `json:"category"`
+    Conversations int    `json:"conversations"`
+    Messages      int    `json:"messages"`
+}
+
+// StoreConversation records the billing conversation and pricing reported for a sent
+// message. Fields missing from a later status webhook keep their stored values.
+// It returns sql.ErrNoRows if the message does not exist.
+func (r *MessageRepository) StoreConversation(ctx context.Context, messageID string, conversation *types.Conversation, pricing *types.Pricing) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("store_conversation"))
+    defer timer.ObserveDuration()
+
+    if messageID == "" {
+        return errors.New("message ID is required")
+    }
+    if conversation == nil && pricing == nil {
+        return errors.New("conversation or pricing is required")
+    }
+
+    var conversationID, category string
+    var billable sql.NullBool
+    if conversation != nil {
+        conversationID = conversation.ID
+    }
+    if pricing != nil {
+        category = pricing.Category
+        billable = sql.NullBool{Bool: pricing.Billable, Valid: true}
+    }
+
+    result, err := r.db.ExecContext(ctx, storeConversationSQL, messageID, conversationID, category, billable, time.Now())
+    if err != nil {
+        messageOps.WithLabelValues("store_conversation", "error").Inc()
+        return errors.Wrap(err, "failed to store conversation")
+    }
+
+    rows, err := result.RowsAffected()
+    if err != nil {
+        messageOps.WithLabelValues("store_conversation", "error").Inc()
+        return errors.Wrap(err, "failed to get affected rows")
+    }
+    if rows == 0 {
+        messageOps.WithLabelValues("store_conversation", "not_found").Inc()
+        return sql.ErrNoRows
+    }
+
+    messageOps.WithLabelValues("store_conversation", "success").Inc()
+    return nil
+}
+
+// GetBillableConversationSummary counts an organization's billable conversations and
+// messages per pricing category for messages created in [from, to), for cost reconciliation
+func (r *MessageRepository) GetBillableConversationSummary(ctx context.Context, organizationID string, from, to time.Time) ([]ConversationSummary, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("billable_conversation_summary"))
+    defer timer.ObserveDuration()
+
+    if organizationID == "" {
+        return nil, errors.New("organization ID is required")
+    }
+    if !to.After(from) {
+        return nil, errors.New("summary end must be after its start")
+    }
+
+    rows, err := r.db.QueryContext(ctx, getBillableConversationSummarySQL, organizationID, from, to)
+    if err != nil {
+        messageOps.WithLabelValues("billable_conversation_summary", "error").Inc()
+        return nil, errors.Wrap(err, "failed to query billable conversations")
+    }
+    defer rows.Close()
+
+    summary := make([]ConversationSummary, 0)
+    for rows.Next() {
+        var entry ConversationSummary
+        var category sql.NullString
+        if err := rows.Scan(&category, &entry.Conversations, &entry.Messages); err != nil {
+            messageOps.WithLabelValues("billable_conversation_summary", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan billable conversation row")
+        }
+        entry.Category = category.String
+        summary = append(summary, entry)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("billable_conversation_summary", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating billable conversation rows")
+    }
+
+    messageOps.WithLabelValues("billable_conversation_summary", "success").Inc()
+    return summary, nil
+}
+
+// GetCallbackTarget returns the per-message callback URL and external reference,
+// with an empty URL when the message has no callback configured
+func (r *MessageRepository) GetCallbackTarget(ctx context.Context, messageID string) (string, string, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_callback_target"))
+    defer timer.ObserveDuration()
+
+    var callbackURL, externalRef string
+    err := r.db.QueryRowContext(ctx, getCallbackTargetSQL, messageID).Scan(&callbackURL, &externalRef)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            messageOps.WithLabelValues("get_callback_target", "not_found").Inc()
+            return "", "", err
+        }
+        messageOps.WithLabelValues("get_callback_target", "error").Inc()
+        return "", "", errors.Wrap(err, "failed to get callback target")
+    }
+
+    messageOps.WithLabelValues("get_callback_target", "success").Inc()
+    return callbackURL, externalRef, nil
+}
+
+// GetMessageIDByExternalRef resolves the most recent message sent with the given external
+// reference, returning sql.ErrNoRows if none matches
+func (r *MessageRepository) GetMessageIDByExternalRef(ctx context.Context, externalRef string) (string, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_by_external_ref"))
+    defer timer.ObserveDuration()
+
+    if externalRef == "" {
+        return "", errors.New("external reference is required")
+    }
+
+    var id string
+    err := r.db.QueryRowContext(ctx, getMessageIDByExternalRefSQL, externalRef).Scan(&id)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            messageOps.WithLabelValues("get_by_external_ref", "not_found").Inc()
+            return "", err
+        }
+        messageOps.WithLabelValues("get_by_external_ref", "error").Inc()
+        return "", errors.Wrap(err, "failed to resolve external reference")
+    }
+
+    messageOps.WithLabelValues("get_by_external_ref", "success").Inc()
+    return id, nil
+}
+
+// scanMessage scans a message row selected with the standard message column list
+func scanMessage(rows *sql.Rows) (*models.Message, error) {
+    var msg models.Message
+    var contentJSON, templateJSON, recurrenceJSON []byte
+    var scheduledAt sql.NullTime
+
+    err := rows.Scan(
+        &msg.ID,
+        &msg.OrganizationID,
+        &msg.RecipientPhone,
+        &contentJSON,
+        &templateJSON,
+        &msg.Status,
+        &msg.RetryCount,
+        &scheduledAt,
+        &msg.CreatedAt,
+        &msg.UpdatedAt,
+        &msg.ExternalRef,
+        &msg.CallbackURL,
+        &recurrenceJSON,
+    )
+    if err != nil {
+        return nil, errors.Wrap(err, "failed to scan message row")
+    }
+
+    if err := json.Unmarshal(contentJSON, &msg.Content); err != nil {
+        return nil, errors.Wrap(err, "failed to unmarshal content")
+    }
+
+    if len(templateJSON) > 0 {
+        var template types.Template
+        if err := json.Unmarshal(templateJSON, &template); err != nil {
+            return nil, errors.Wrap(err, "failed to unmarshal template")
+        }
+        msg.Template = &template
+    }
+
+    if scheduledAt.Valid {
+        msg.ScheduledAt = &scheduledAt.Time
+    }
+
+    if len(recurrenceJSON) > 0 {
+        var recurrence models.Recurrence
+        if err := json.Unmarshal(recurrenceJSON, &recurrence); err != nil {
+            return nil, errors.Wrap(err, "failed to unmarshal recurrence")
+        }
+        msg.Recurrence = &recurrence
+    }
+
+    return &msg, nil
+}
+
+// marshalRecurrence encodes a recurrence for its JSONB column, storing NULL when absent
+func marshalRecurrence(recurrence *models.Recurrence) ([]byte, error) {
+    if recurrence == nil {
+        return nil, nil
+    }
+    data, err := json.Marshal(recurrence)
+    if err != nil {
+        return nil, errors.Wrap(err, "failed to marshal recurrence")
+    }
+    return data, nil
+}
+
+// collectIDs drains rows of a single id column into a set and closes them
+func collectIDs(rows *sql.Rows) (map[string]bool, error) {
+    defer rows.Close()
+
+    ids := make(map[string]bool)
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids[id] = true
+    }
+    return ids, rows.Err()
+}
+
+// StatusHistoryEntry is a single recorded status transition of a message
+type StatusHistoryEntry struct {
+    From      string    `json:"from,omitempty"`
+    To        string    `json:"to"`
+    Reason    string    `json:"reason,omitempty"`
+    ChangedAt time.Time `json:"changed_at"`
+}
+
+// AppendStatusHistory records a status transition for a message
+func (r *MessageRepository) AppendStatusHistory(ctx context.Context, id, from, to, reason string, at time.Time) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("append_status_history"))
+    defer timer.ObserveDuration()
+
+    if id == "" || to == "" {
+        return errors.New("message ID and target status are required")
+    }
+
+    if _, err := r.db.ExecContext(ctx, appendStatusHistorySQL, id, from, to, reason, at); err != nil {
+        messageOps.WithLabelValues("append_status_history", "error").Inc()
+        return errors.Wrap(err, "failed to append status history")
+    }
+
+    messageOps.WithLabelValues("append_status_history", "success").Inc()
+    return nil
+}
+
+// GetStatusHistory returns the status transitions of a message in the order they occurred
+func (r *MessageRepository) GetStatusHistory(ctx context.Context, id string) ([]StatusHistoryEntry, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_status_history"))
+    defer timer.ObserveDuration()
+
+    rows, err := r.db.QueryContext(ctx, getStatusHistorySQL, id)
+    if err != nil {
+        messageOps.WithLabelValues("get_status_history", "error").Inc()
+        return nil, errors.Wrap(err, "failed to query status history")
+    }
+    defer rows.Close()
+
+    history := make([]StatusHistoryEntry, 0)
+    for rows.Next() {
+        var entry StatusHistoryEntry
+        var from, reason sql.NullString
+        if err := rows.Scan(&from, &entry.To, &reason, &entry.ChangedAt); err != nil {
+            messageOps.WithLabelValues("get_status_history", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan status history row")
+        }
+        entry.From = from.String
+        entry.Reason = reason.String
+        history = append(history, entry)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("get_status_history", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating status history rows")
+    }
+
+    messageOps.WithLabelValues("get_status_history", "success").Inc()
+    return history, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+    "context"
+    "database/sql/driver"
+    "regexp"
+    "testing"
+    "time"
+
+    "github.com/DATA-DOG/go-sqlmock"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "message-service/internal/models"
+)
+
+var messageColumns = []string{
+    "id", "organization_id", "recipient_phone", "content", "template",
+    "status", "retry_count", "scheduled_at", "created_at", "updated_at",
+    "external_ref", "callback_url", "recurrence",
+}
+
+func messageRow(id string, template []byte) []driver.Value {
+    now := time.Now()
+    return []driver.Value{
+        id, "org-1", "+14155550100", []byte(`{"text":"hello"}`), template,
+        models.MessageStatusProcessing, 0, nil, now, now,
+        "", "", nil,
+    }
+}
+
+func TestClaimPendingMessagesReturnsClaimedRows(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := &MessageRepository{db: db}
+    template := []byte(`{"name":"order_update","language":"en_US","category":"UTILITY","components":[{"type":"BODY"}]}`)
+    rows := sqlmock.NewRows(messageColumns).
+        AddRow(messageRow("msg-1", template)...).
+        AddRow(messageRow("msg-2", nil)...)
+
+    mock.ExpectQuery(regexp.QuoteMeta("UPDATE messages") + `(?s).*FOR UPDATE SKIP LOCKED`).
+        WithArgs(models.MessageStatusProcessing, "worker-1", sqlmock.AnyArg(), models.MessageStatusPending, 10).
+        WillReturnRows(rows)
+
+    messages, err := repo.ClaimPendingMessages(context.Background(), "worker-1", 10)
+    require.NoError(t, err)
+    require.Len(t, messages, 2)
+    require.NoError(t, mock.ExpectationsWereMet())
+
+    assert.Equal(t, "msg-1", messages[0].ID)
+    assert.Equal(t, "hello", messages[0].Content.Text)
+    require.NotNil(t, messages[0].Template)
+    assert.Equal(t, "order_update", messages[0].Template.Name)
+    assert.Equal(t, "en_US", messages[0].Template.Language)
+    require.Len(t, messages[0].Template.Components, 1)
+
+    assert.Equal(t, "msg-2", messages[1].ID)
+    assert.Nil(t, messages[1].Template, "a NULL template column leaves the template unset")
+}
+
+func TestClaimPendingMessagesRequiresWorkerID(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := &MessageRepository{db: db}
+    _, err = repo.ClaimPendingMessages(context.Background(), "", 10)
+
+    assert.Error(t, err)
+    assert.NoError(t, mock.ExpectationsWereMet(), "no query is made without a worker ID")
+}
@@ -0,0 +1,264 @@
+// Package repository provides filtered message listing with injection-safe query building
+// Version: go1.21
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "encoding/base64"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/pkg/errors"     // v0.9.1
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+
+    "message-service/internal/models"
+)
+
+// Listing defaults
+const (
+    defaultListLimit = 50
+    maxListLimit     = 500
+    defaultSortBy    = "created_at"
+)
+
+const listMessagesBaseSQL = `
+        SELECT id, organization_id, recipient_phone, content, template,
+               status, retry_count, scheduled_at, created_at, updated_at,
+               COALESCE(external_ref, ''), COALESCE(callback_url, ''), recurrence
+        FROM messages`
+
+// getMessagesByStatusSQL pages newest first by (created_at, id); a NULL cursor starts at the newest
+const getMessagesByStatusSQL = listMessagesBaseSQL + `
+        WHERE organization_id = $1
+        AND status = $2
+        AND direction = 'outbound'
+        AND deleted_at IS NULL
+        AND ($3::timestamptz IS NULL OR (created_at, id) < ($3::timestamptz, $4::uuid))
+        ORDER BY created_at DESC, id DESC
+        LIMIT $5`
+
+// sortableColumns is the allow-list of columns a listing may be ordered by. Sort keys are
+// the only identifiers taken from callers and are never interpolated unless listed here.
+var sortableColumns = map[string]string{
+    "created_at":   "created_at",
+    "updated_at":   "updated_at",
+    "scheduled_at": "scheduled_at",
+    "status":       "status",
+    "retry_count":  "retry_count",
+}
+
+// MessageFilter narrows a message listing. Zero-valued fields are not applied.
+type MessageFilter struct {
+    OrganizationID string
+    Status         string
+    RecipientPhone string
+    ExternalRef    string
+    CreatedAfter   *time.Time
+    CreatedBefore  *time.Time
+    SortBy         string
+    SortDesc       bool
+    Limit          int
+    Offset         int
+}
+
+// ListMessages returns messages matching the filter. Every filter value is bound as a
+// query parameter; only allow-listed sort columns are placed in the SQL text.
+func (r *MessageRepository) ListMessages(ctx context.Context, filter MessageFilter) ([]*models.Message, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("list_messages"))
+    defer timer.ObserveDuration()
+
+    query, args, err := buildListMessagesQuery(filter)
+    if err != nil {
+        messageOps.WithLabelValues("list_messages", "validation_error").Inc()
+        return nil, err
+    }
+
+    rows, err := r.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        messageOps.WithLabelValues("list_messages", "error").Inc()
+        return nil, errors.Wrap(err, "failed to list messages")
+    }
+    defer rows.Close()
+
+    var messages []*models.Message
+    for rows.Next() {
+        msg, err := scanMessage(rows)
+        if err != nil {
+            messageOps.WithLabelValues("list_messages", "error").Inc()
+            return nil, err
+        }
+        messages = append(messages, msg)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("list_messages", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating message rows")
+    }
+
+    messageOps.WithLabelValues("list_messages", "success").Inc()
+    return messages, nil
+}
+
+// buildListMessagesQuery assembles the listing SQL with positional placeholders for all values
+func buildListMessagesQuery(filter MessageFilter) (string, []interface{}, error) {
+    // Inbound messages are read through GetConversation
+    conditions := []string{"direction = 'outbound'", "deleted_at IS NULL"}
+    var args []interface{}
+
+    addCondition := func(column string, op string, value interface{}) {
+        args = append(args, value)
+        conditions = append(conditions, fmt.Sprintf("%s %s $%d", column, op, len(args)))
+    }
+
+    if filter.OrganizationID != "" {
+        addCondition("organization_id", "=", filter.OrganizationID)
+    }
+    if filter.Status != "" {
+        addCondition("status", "=", filter.Status)
+    }
+    if filter.RecipientPhone != "" {
+        addCondition("recipient_phone", "=", filter.RecipientPhone)
+    }
+    if filter.ExternalRef != "" {
+        addCondition("external_ref", "=", filter.ExternalRef)
+    }
+    if filter.CreatedAfter != nil {
+        addCondition("created_at", ">=", *filter.CreatedAfter)
+    }
+    if filter.CreatedBefore != nil {
+        addCondition("created_at", "<", *filter.CreatedBefore)
+    }
+
+    sortBy := filter.SortBy
+    if sortBy == "" {
+        sortBy = defaultSortBy
+    }
+    column, ok := sortableColumns[sortBy]
+    if !ok {
+        return "", nil, errors.Errorf("unsupported sort column %q", filter.SortBy)
+    }
+    direction := "ASC"
+    if filter.SortDesc {
+        direction = "DESC"
+    }
+
+    limit := filter.Limit
+    if limit <= 0 {
+        limit = defaultListLimit
+    }
+    if limit > maxListLimit {
+        limit = maxListLimit
+    }
+    if filter.Offset < 0 {
+        return "", nil, errors.New("offset must not be negative")
+    }
+
+    var query strings.Builder
+    query.WriteString(listMessagesBaseSQL)
+    if len(conditions) > 0 {
+        query.WriteString("\n        WHERE ")
+        query.WriteString(strings.Join(conditions, " AND "))
+    }
+    // id breaks ties so pagination is stable across pages
+    fmt.Fprintf(&query, "\n        ORDER BY %s %s, id %s", column, direction, direction)
+
+    args = append(args, limit)
+    fmt.Fprintf(&query, "\n        LIMIT $%d", len(args))
+    args = append(args, filter.Offset)
+    fmt.Fprintf(&query, " OFFSET $%d", len(args))
+
+    return query.String(), args, nil
+}
+
+// GetMessagesByStatus returns an organization's messages in status, newest first, with a
+// cursor for the next page that is empty on the last page. Pass an empty cursor for the
+// first page. A limit of zero or less returns the default page size.
+func (r *MessageRepository) GetMessagesByStatus(ctx context.Context, orgID, status string, cursor string, limit int) ([]*models.Message, string, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_by_status"))
+    defer timer.ObserveDuration()
+
+    if orgID == "" {
+        return nil, "", errors.New("organization ID is required")
+    }
+    if status == "" {
+        return nil, "", errors.New("status is required")
+    }
+    if limit <= 0 {
+        limit = defaultListLimit
+    }
+    if limit > maxListLimit {
+        limit = maxListLimit
+    }
+
+    var after sql.NullTime
+    var afterID sql.NullString
+    if cursor != "" {
+        createdAt, id, err := decodeStatusCursor(cursor)
+        if err != nil {
+            messageOps.WithLabelValues("get_by_status", "validation_error").Inc()
+            return nil, "", err
+        }
+        after = sql.NullTime{Time: createdAt, Valid: true}
+        afterID = sql.NullString{String: id, Valid: true}
+    }
+
+    ctx, cancel := context.WithTimeout(ctx, defaultQueryTimeout)
+    defer cancel()
+
+    // One extra row tells whether another page follows
+    rows, err := r.statement("getMessagesByStatus").QueryContext(ctx, orgID, status, after, afterID, limit+1)
+    if err != nil {
+        messageOps.WithLabelValues("get_by_status", "error").Inc()
+        return nil, "", errors.Wrap(err, "failed to query messages by status")
+    }
+    defer rows.Close()
+
+    messages := make([]*models.Message, 0, limit)
+    for rows.Next() {
+        msg, err := scanMessage(rows)
+        if err != nil {
+            messageOps.WithLabelValues("get_by_status", "error").Inc()
+            return nil, "", err
+        }
+        messages = append(messages, msg)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("get_by_status", "error").Inc()
+        return nil, "", errors.Wrap(err, "error iterating message rows")
+    }
+
+    var next string
+    if len(messages) > limit {
+        messages = messages[:limit]
+        last := messages[limit-1]
+        next = encodeStatusCursor(last.CreatedAt, last.ID)
+    }
+
+    messageOps.WithLabelValues("get_by_status", "success").Inc()
+    return messages, next, nil
+}
+
+// encodeStatusCursor encodes the position after a message as an opaque page cursor
+func encodeStatusCursor(createdAt time.Time, id string) string {
+    return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + id))
+}
+
+// decodeStatusCursor decodes a cursor produced by encodeStatusCursor
+func decodeStatusCursor(cursor string) (time.Time, string, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return time.Time{}, "", errors.New("invalid cursor")
+    }
+    parts := strings.SplitN(string(raw), "|", 2)
+    if len(parts) != 2 || parts[1] == "" {
+        return time.Time{}, "", errors.New("invalid cursor")
+    }
+    createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+    if err != nil {
+        return time.Time{}, "", errors.New("invalid cursor")
+    }
+    return createdAt, parts[1], nil
+}
@@ -0,0 +1,141 @@
+// Package repository provides storage of polled WhatsApp template review statuses
+// Version: go1.21
+package repository
+
+import (
+    "context"
+    "database/sql"
+
+    "github.com/pkg/errors"     // v0.9.1
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+)
+
+// SQL statements for template statuses
+const (
+    getScheduledTemplateRefsSQL = `
+        SELECT DISTINCT template->>'name', template->>'language'
+        FROM messages
+        WHERE status = 'scheduled'
+        AND template IS NOT NULL
+        AND template->>'name' IS NOT NULL
+        AND deleted_at IS NULL`
+
+    upsertTemplateStatusSQL = `
+        INSERT INTO template_status (name, language, status, checked_at)
+        VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+        ON CONFLICT (name, language)
+        DO UPDATE SET status = EXCLUDED.status, checked_at = EXCLUDED.checked_at`
+
+    getTemplateStatusSQL = `
+        SELECT status FROM template_status
+        WHERE name = $1 AND language = $2`
+
+    countTemplateStatusesSQL = `
+        SELECT status, COUNT(*) FROM template_status GROUP BY status`
+)
+
+// TemplateRef identifies a WhatsApp template by name and language
+type TemplateRef struct {
+    Name     string
+    Language string
+}
+
+// GetScheduledTemplateRefs returns each distinct template referenced by a scheduled message
+func (r *MessageRepository) GetScheduledTemplateRefs(ctx context.Context) ([]TemplateRef, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_scheduled_template_refs"))
+    defer timer.ObserveDuration()
+
+    rows, err := r.db.QueryContext(ctx, getScheduledTemplateRefsSQL)
+    if err != nil {
+        messageOps.WithLabelValues("get_scheduled_template_refs", "error").Inc()
+        return nil, errors.Wrap(err, "failed to query scheduled templates")
+    }
+    defer rows.Close()
+
+    refs := make([]TemplateRef, 0)
+    for rows.Next() {
+        var ref TemplateRef
+        var language sql.NullString
+        if err := rows.Scan(&ref.Name, &language); err != nil {
+            messageOps.WithLabelValues("get_scheduled_template_refs", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan scheduled template")
+        }
+        ref.Language = language.String
+        refs = append(refs, ref)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("get_scheduled_template_refs", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating scheduled templates")
+    }
+
+    messageOps.WithLabelValues("get_scheduled_template_refs", "success").Inc()
+    return refs, nil
+}
+
+// UpsertTemplateStatus records the latest review status of a template
+func (r *MessageRepository) UpsertTemplateStatus(ctx context.Context, ref TemplateRef, status string) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("upsert_template_status"))
+    defer timer.ObserveDuration()
+
+    if _, err := r.db.ExecContext(ctx, upsertTemplateStatusSQL, ref.Name, ref.Language, status); err != nil {
+        messageOps.WithLabelValues("upsert_template_status", "error").Inc()
+        return errors.Wrap(err, "failed to store template status")
+    }
+
+    messageOps.WithLabelValues("upsert_template_status", "success").Inc()
+    return nil
+}
+
+// GetTemplateStatus returns the last polled review status of a template, or sql.ErrNoRows
+// if it has not been polled
+func (r *MessageRepository) GetTemplateStatus(ctx context.Context, ref TemplateRef) (string, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_template_status"))
+    defer timer.ObserveDuration()
+
+    var status string
+    err := r.db.QueryRowContext(ctx, getTemplateStatusSQL, ref.Name, ref.Language).Scan(&status)
+    if err == sql.ErrNoRows {
+        messageOps.WithLabelValues("get_template_status", "not_found").Inc()
+        return "", sql.ErrNoRows
+    }
+    if err != nil {
+        messageOps.WithLabelValues("get_template_status", "error").Inc()
+        return "", errors.Wrap(err, "failed to get template status")
+    }
+
+    messageOps.WithLabelValues("get_template_status", "success").Inc()
+    return status, nil
+}
+
+// CountTemplateStatuses returns the number of polled templates in each review status
+func (r *MessageRepository) CountTemplateStatuses(ctx context.Context) (map[string]int, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("count_template_statuses"))
+    defer timer.ObserveDuration()
+
+    rows, err := r.db.QueryContext(ctx, countTemplateStatusesSQL)
+    if err != nil {
+        messageOps.WithLabelValues("count_template_statuses", "error").Inc()
+        return nil, errors.Wrap(err, "failed to count template statuses")
+    }
+    defer rows.Close()
+
+    counts := make(map[string]int)
+    for rows.Next() {
+        var status string
+        var count int
+        if err := rows.Scan(&status, &count); err != nil {
+            messageOps.WithLabelValues("count_template_statuses", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan template status count")
+        }
+        counts[status] = count
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("count_template_statuses", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating template status counts")
+    }
+
+    messageOps.WithLabelValues("count_template_statuses", "success").Inc()
+    return counts, nil
+}
@@ -0,0 +1,152 @@
+// Package repository provides persistence of received webhook events for auditing and replay
+// Version: go1.21
+package repository
+
+import (
+    "context"
+    "database/sql"  // go1.21
+    "encoding/json"
+    "time"
+
+    "github.com/pkg/errors"     // v0.9.1
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+)
+
+// Webhook history limits
+const (
+    defaultWebhookHistoryLimit = 100
+    maxWebhookHistoryLimit     = 1000
+)
+
+// SQL statements
+const (
+    storeWebhookSQL = `
+        INSERT INTO webhook_events (
+            message_id, event_type, status, verified, payload, received_at
+        ) VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id`
+
+    getWebhooksByMessageIDSQL = `
+        SELECT id, message_id, event_type, status, verified, payload, received_at
+        FROM webhook_events
+        WHERE message_id = $1
+        ORDER BY received_at ASC, id ASC
+        LIMIT $2 OFFSET $3`
+)
+
+// StoredWebhook is a received webhook event as persisted, including its raw payload
+type StoredWebhook struct {
+    ID         int64           `json:"id"`
+    MessageID  string          `json:"message_id"`
+    EventType  string          `json:"event_type"`
+    Status     string          `json:"status,omitempty"`
+    Verified   bool            `json:"verified"`
+    Payload    json.RawMessage `json:"payload"`
+    ReceivedAt time.Time       `json:"received_at"`
+}
+
+// WebhookRepository stores received webhook events
+type WebhookRepository struct {
+    db *sql.DB
+}
+
+// NewWebhookRepository creates a new webhook repository instance
+func NewWebhookRepository(db *sql.DB) (*WebhookRepository, error) {
+    if db == nil {
+        return nil, errors.New("database connection is required")
+    }
+    return &WebhookRepository{db: db}, nil
+}
+
+// Store persists a received webhook event and sets its generated ID
+func (r *WebhookRepository) Store(ctx context.Context, webhook *StoredWebhook) error {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("store_webhook"))
+    defer timer.ObserveDuration()
+
+    if webhook == nil || webhook.MessageID == "" {
+        return errors.New("webhook with message ID is required")
+    }
+    if webhook.ReceivedAt.IsZero() {
+        webhook.ReceivedAt = time.Now()
+    }
+
+    err := r.db.QueryRowContext(ctx, storeWebhookSQL,
+        webhook.MessageID,
+        webhook.EventType,
+        webhook.Status,
+        webhook.Verified,
+        []byte(webhook.Payload),
+        webhook.ReceivedAt,
+    ).Scan(&webhook.ID)
+    if err != nil {
+        messageOps.WithLabelValues("store_webhook", "error").Inc()
+        return errors.Wrap(err, "failed to store webhook")
+    }
+
+    messageOps.WithLabelValues("store_webhook", "success").Inc()
+    return nil
+}
+
+// GetByMessageID returns the webhook history of a message in the order it was received.
+// Unknown message IDs yield an empty history.
+func (r *WebhookRepository) GetByMessageID(ctx context.Context, id string) ([]StoredWebhook, error) {
+    return r.GetByMessageIDPage(ctx, id, maxWebhookHistoryLimit, 0)
+}
+
+// GetByMessageIDPage returns one page of a message's webhook history in received order
+func (r *WebhookRepository) GetByMessageIDPage(ctx context.Context, id string, limit, offset int) ([]StoredWebhook, error) {
+    timer := prometheus.NewTimer(messageOpDuration.WithLabelValues("get_webhooks"))
+    defer timer.ObserveDuration()
+
+    if id == "" {
+        return nil, errors.New("message ID is required")
+    }
+    if limit <= 0 {
+        limit = defaultWebhookHistoryLimit
+    }
+    if limit > maxWebhookHistoryLimit {
+        limit = maxWebhookHistoryLimit
+    }
+    if offset < 0 {
+        offset = 0
+    }
+
+    rows, err := r.db.QueryContext(ctx, getWebhooksByMessageIDSQL, id, limit, offset)
+    if err != nil {
+        messageOps.WithLabelValues("get_webhooks", "error").Inc()
+        return nil, errors.Wrap(err, "failed to query webhooks")
+    }
+    defer rows.Close()
+
+    webhooks := make([]StoredWebhook, 0)
+    for rows.Next() {
+        var webhook StoredWebhook
+        var status sql.NullString
+        var payload []byte
+
+        if err := rows.Scan(
+            &webhook.ID,
+            &webhook.MessageID,
+            &webhook.EventType,
+            &status,
+            &webhook.Verified,
+            &payload,
+            &webhook.ReceivedAt,
+        ); err != nil {
+            messageOps.WithLabelValues("get_webhooks", "error").Inc()
+            return nil, errors.Wrap(err, "failed to scan webhook row")
+        }
+
+        webhook.Status = status.String
+        webhook.Payload = json.RawMessage(payload)
+        webhooks = append(webhooks, webhook)
+    }
+
+    if err := rows.Err(); err != nil {
+        messageOps.WithLabelValues("get_webhooks", "error").Inc()
+        return nil, errors.Wrap(err, "error iterating webhook rows")
+    }
+
+    messageOps.WithLabelValues("get_webhooks", "success").Inc()
+    return webhooks, nil
+}
@@ -0,0 +1,165 @@
+// Package services provides signed delivery of per-message status callbacks
+// Version: go1.21
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// Callback delivery configuration
+const (
+    callbackSignatureHeader = "X-Callback-Signature"
+    callbackTimestampHeader = "X-Callback-Timestamp"
+    callbackSignaturePrefix = "sha256="
+    defaultCallbackAttempts = 5
+    defaultCallbackDelay    = time.Second
+    maxCallbackDelay        = time.Minute
+    defaultCallbackTimeout  = 10 * time.Second
+)
+
+// ErrCallbackRejected is returned when a callback endpoint responds with a non-retryable status
+var ErrCallbackRejected = errors.New("callback rejected by endpoint")
+
+// CallbackNotification is the body POSTed to a message's callback URL on a status change
+type CallbackNotification struct {
+    MessageID   string    `json:"message_id"`
+    ExternalRef string    `json:"external_ref,omitempty"`
+    Status      string    `json:"status"`
+    Timestamp   time.Time `json:"timestamp"`
+}
+
+// CallbackDispatcher delivers signed status notifications with retry and exponential backoff
+type CallbackDispatcher struct {
+    client      *http.Client
+    secret      []byte
+    maxAttempts int
+    baseDelay   time.Duration
+    wg          sync.WaitGroup
+}
+
+// NewCallbackDispatcher creates a dispatcher signing notifications with the given secret
+func NewCallbackDispatcher(secret string, client *http.Client) (*CallbackDispatcher, error) {
+    if secret == "" {
+        return nil, errors.New("callback signing secret is required")
+    }
+    if client == nil {
+        client = &http.Client{Timeout: defaultCallbackTimeout}
+    }
+
+    return &CallbackDispatcher{
+        client:      client,
+        secret:      []byte(secret),
+        maxAttempts: defaultCallbackAttempts,
+        baseDelay:   defaultCallbackDelay,
+    }, nil
+}
+
+// DispatchAsync delivers the notification in the background so webhook processing is not
+// held up by slow tenant endpoints
+func (d *CallbackDispatcher) DispatchAsync(ctx context.Context, callbackURL string, notification CallbackNotification, onError func(error)) {
+    d.wg.Add(1)
+    go func() {
+        defer d.wg.Done()
+        if err := d.Dispatch(ctx, callbackURL, notification); err != nil && onError != nil {
+            onError(err)
+        }
+    }()
+}
+
+// Dispatch POSTs the signed notification, retrying network errors, 429 and 5xx responses
+func (d *CallbackDispatcher) Dispatch(ctx context.Context, callbackURL string, notification CallbackNotification) error {
+    body, err := json.Marshal(notification)
+    if err != nil {
+        return fmt.Errorf("marshal callback notification: %w", err)
+    }
+
+    var lastErr error
+    for attempt := 0; attempt < d.maxAttempts; attempt++ {
+        if attempt > 0 {
+            select {
+            case <-ctx.Done():
+                return ctx.Err()
+            case <-time.After(d.backoff(attempt)):
+            }
+        }
+
+        retry, err := d.post(ctx, callbackURL, body)
+        if err == nil {
+            return nil
+        }
+        lastErr = err
+        if !retry {
+            return err
+        }
+    }
+
+    return fmt.Errorf("callback delivery failed after %d attempts: %w", d.maxAttempts, lastErr)
+}
+
+// Wait blocks until all in-flight asynchronous deliveries finish
+func (d *CallbackDispatcher) Wait() {
+    d.wg.Wait()
+}
+
+// post performs a single delivery attempt and reports whether a failure is retryable
+func (d *CallbackDispatcher) post(ctx context.Context, callbackURL string, body []byte) (bool, error) {
+    timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+    if err != nil {
+        return false, fmt.Errorf("create callback request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set(callbackTimestampHeader, timestamp)
+    req.Header.Set(callbackSignatureHeader, SignCallback(d.secret, timestamp, body))
+
+    resp, err := d.client.Do(req)
+    if err != nil {
+        return true, fmt.Errorf("post callback: %w", err)
+    }
+    resp.Body.Close()
+
+    switch {
+    case resp.StatusCode >= 200 && resp.StatusCode < 300:
+        return false, nil
+    case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+        return true, fmt.Errorf("post callback: unexpected status %d", resp.StatusCode)
+    default:
+        return false, fmt.Errorf("%w: status %d", ErrCallbackRejected, resp.StatusCode)
+    }
+}
+
+func (d *CallbackDispatcher) backoff(attempt int) time.Duration {
+    delay := d.baseDelay * time.Duration(1<<uint(attempt-1))
+    if delay > maxCallbackDelay {
+        delay = maxCallbackDelay
+    }
+    return delay
+}
+
+// SignCallback computes the signature sent in X-Callback-Signature. Tenants verify a
+// callback by recomputing it over the X-Callback-Timestamp header and the raw body.
+func SignCallback(secret []byte, timestamp string, body []byte) string {
+    mac := hmac.New(sha256.New, secret)
+    mac.Write([]byte(timestamp))
+    mac.Write([]byte("."))
+    mac.Write(body)
+    return callbackSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCallbackSignature reports whether signature matches the timestamp and body
+func VerifyCallbackSignature(secret []byte, timestamp string, body []byte, signature string) bool {
+    expected := SignCallback(secret, timestamp, body)
+    return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,137 @@
+// Package services provides sliding-window failure rate monitoring for message delivery
+// Version: go1.21
+package services
+
+import (
+    "sync"
+    "time"
+)
+
+// Default failure rate monitor configuration
+const (
+    defaultFailureWindow     = time.Minute
+    defaultFailureThreshold  = 0.5
+    defaultFailureMinSamples = 20
+    failureWindowBuckets     = 10
+)
+
+// FailureRateConfig configures the failure rate monitor
+type FailureRateConfig struct {
+    // Window is the sliding window over which the failure rate is computed
+    Window time.Duration
+    // Threshold is the failure rate (0-1) above which OnHighFailureRate fires
+    Threshold float64
+    // RecoveryThreshold is the rate the failure rate must drop below before the
+    // callback can fire again; it defaults to half of Threshold to avoid flapping
+    RecoveryThreshold float64
+    // MinSamples is the minimum number of outcomes in the window before alerting
+    MinSamples int
+    // OnHighFailureRate is invoked synchronously when the threshold is crossed and must not block
+    OnHighFailureRate func(rate float64)
+}
+
+// failureBucket counts outcomes within one slice of the sliding window
+type failureBucket struct {
+    start    time.Time
+    total    int
+    failures int
+}
+
+// FailureRateMonitor tracks delivery outcomes over a sliding window and alerts once
+// per excursion above the configured threshold
+type FailureRateMonitor struct {
+    config     FailureRateConfig
+    bucketSize time.Duration
+    buckets    [failureWindowBuckets]failureBucket
+    alerting   bool
+    now        func() time.Time
+    mu         sync.Mutex
+}
+
+// NewFailureRateMonitor creates a monitor, applying defaults for unset configuration
+func NewFailureRateMonitor(config FailureRateConfig) *FailureRateMonitor {
+    if config.Window <= 0 {
+        config.Window = defaultFailureWindow
+    }
+    if config.Threshold <= 0 || config.Threshold > 1 {
+        config.Threshold = defaultFailureThreshold
+    }
+    if config.RecoveryThreshold <= 0 || config.RecoveryThreshold >= config.Threshold {
+        config.RecoveryThreshold = config.Threshold / 2
+    }
+    if config.MinSamples <= 0 {
+        config.MinSamples = defaultFailureMinSamples
+    }
+
+    return &FailureRateMonitor{
+        config:     config,
+        bucketSize: config.Window / failureWindowBuckets,
+        now:        time.Now,
+    }
+}
+
+// Record adds a delivery outcome to the window and fires the callback when the
+// failure rate crosses the threshold
+func (m *FailureRateMonitor) Record(failed bool) {
+    m.mu.Lock()
+
+    now := m.now()
+    bucket := m.bucketFor(now)
+    bucket.total++
+    if failed {
+        bucket.failures++
+    }
+
+    rate, samples := m.rateLocked(now)
+    fire := false
+    switch {
+    case !m.alerting && samples >= m.config.MinSamples && rate > m.config.Threshold:
+        m.alerting = true
+        fire = true
+    case m.alerting && rate < m.config.RecoveryThreshold:
+        m.alerting = false
+    }
+
+    m.mu.Unlock()
+
+    if fire && m.config.OnHighFailureRate != nil {
+        m.config.OnHighFailureRate(rate)
+    }
+}
+
+// Rate returns the current failure rate over the window
+func (m *FailureRateMonitor) Rate() float64 {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    rate, _ := m.rateLocked(m.now())
+    return rate
+}
+
+// bucketFor returns the bucket covering t, resetting it if it holds an expired slice
+func (m *FailureRateMonitor) bucketFor(t time.Time) *failureBucket {
+    start := t.Truncate(m.bucketSize)
+    idx := (start.UnixNano() / int64(m.bucketSize)) % failureWindowBuckets
+    bucket := &m.buckets[idx]
+    if !bucket.start.Equal(start) {
+        *bucket = failureBucket{start: start}
+    }
+    return bucket
+}
+
+// rateLocked computes the failure rate and sample count over buckets still inside the window
+func (m *FailureRateMonitor) rateLocked(now time.Time) (float64, int) {
+    cutoff := now.Add(-m.config.Window)
+    total, failures := 0, 0
+    for _, bucket := range m.buckets {
+        if bucket.total == 0 || !bucket.start.After(cutoff) {
+            continue
+        }
+        total += bucket.total
+        failures += bucket.failures
+    }
+    if total == 0 {
+        return 0, 0
+    }
+    return float64(failures) / float64(total), total
+}
@@ -4,9 +4,13 @@ package services
 
 import (
     "context"
+    "fmt"
+    "log"
     "sync"
+    "sync/atomic"
     "time"
 
+    "github.com/google/uuid"                // v1.3.0
     "github.com/opentracing/opentracing-go" // v1.2.0
     "github.com/sony/gobreaker"             // v0.5.0
     "github.com/prometheus/client_golang/prometheus" // v1.17.0
@@ -16,6 +20,8 @@ import (
     "message-service/internal/models"
     "message-service/internal/repository"
     "message-service/internal/config"
+    "message-service/internal/telemetry"
+    "message-service/internal/utils"
     "message-service/pkg/whatsapp/types"
 )
 
@@ -38,12 +44,50 @@ var (
         []string{"operation"},
     )
 
+    messageSendDuration = promauto.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "message_service_send_duration_seconds",
+            Help:    "Duration of WhatsApp API send calls in seconds",
+            Buckets: prometheus.DefBuckets,
+        },
+        []string{"outcome"},
+    )
+
+    lastScheduledRun = promauto.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "message_service_last_scheduled_run_timestamp_seconds",
+            Help: "Unix time the scheduled message worker last completed a run",
+        },
+    )
+
+    scheduledWorkerPanics = promauto.NewCounter(
+        prometheus.CounterOpts{
+            Name: "message_service_scheduled_worker_panics_total",
+            Help: "Total number of panics recovered in the scheduled message worker",
+        },
+    )
+
     activeBatches = promauto.NewGauge(
         prometheus.GaugeOpts{
             Name: "message_service_active_batches",
             Help: "Number of active message batches being processed",
         },
     )
+
+    messagesPurged = promauto.NewCounter(
+        prometheus.CounterOpts{
+            Name: "message_service_messages_purged_total",
+            Help: "Total number of messages permanently deleted by the retention purge",
+        },
+    )
+
+    templateStatusCount = promauto.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "message_service_template_status_count",
+            Help: "Number of polled templates in each WhatsApp review status",
+        },
+        []string{"status"},
+    )
 )
 
 // Constants for service configuration
@@ -53,6 +97,7 @@ const (
     defaultRetryDelay     = time.Second * 2
     maxConcurrentBatches  = 5
     messageTimeout        = time.Minute * 5
+    scheduledStaleRuns    = 3 // missed intervals before the scheduled worker is unhealthy
 )
 
 // MessageService provides enterprise-grade message processing capabilities
@@ -61,10 +106,18 @@ type MessageService struct {
     producer        MessageProducer
     whatsappService WhatsAppService
     breaker         *gobreaker.CircuitBreaker
+    failureMonitor  *FailureRateMonitor
+    recipientLimit  *RecipientRateLimiter
+    orgLimit        *OrganizationRateLimiter
+    templateStatus  *templateStatusPoll
+    transformers    []models.MessageTransformer
+    lastScheduled   atomic.Int64 // unix nanoseconds of the last completed scheduled run
+    scheduledThrough time.Time   // end of the last polled schedule window; used only by the scheduled worker
+    startedAt       time.Time
     config          *config.Config
     ctx             context.Context
     cancel          context.CancelFunc
-    wg              sync.WaitGroup
+    wg              sync.WaitGroup // lifecycle goroutines only; batches track their own work
     mu              sync.RWMutex
 }
 
@@ -72,6 +125,18 @@ type MessageService struct {
 type MessageProducer interface {
     SendMessage(ctx context.Context, msg *models.Message) error
     SendBatch(ctx context.Context, msgs []*models.Message) error
+    ScheduleMessage(msg *models.Message, scheduledTime time.Time) error
+}
+
+// TemplateStatusSource reports the WhatsApp review status of a submitted template
+type TemplateStatusSource interface {
+    GetTemplateStatus(ctx context.Context, name, language string) (string, error)
+}
+
+// templateStatusPoll is the template status source and how often it is polled
+type templateStatusPoll struct {
+    source   TemplateStatusSource
+    interval time.Duration
 }
 
 // WhatsAppService defines the interface for WhatsApp API operations
@@ -87,17 +152,26 @@ func NewMessageService(repo *repository.MessageRepository, producer MessageProdu
     }
 
     // Create circuit breaker for WhatsApp API calls
+    breakerCfg := cfg.CircuitBreaker
+    if err := breakerCfg.Validate(); err != nil {
+        return nil, errors.Wrap(err, "invalid circuit breaker configuration")
+    }
     breakerSettings := gobreaker.Settings{
         Name:        "whatsapp-api",
         MaxRequests: uint32(cfg.WhatsApp.RetryAttempts),
-        Interval:    cfg.WhatsApp.RetryDelay,
-        Timeout:     cfg.WhatsApp.Timeout,
+        Interval:    breakerCfg.Interval,
+        Timeout:     breakerCfg.Timeout,
         ReadyToTrip: func(counts gobreaker.Counts) bool {
             failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-            return counts.Requests >= 3 && failureRatio >= 0.6
+            return counts.Requests >= breakerCfg.MinRequests && failureRatio >= breakerCfg.FailureRatio
         },
     }
 
+    // Apply configured media size limits to attachment validation
+    if err := utils.SetMediaSizeLimits(cfg.Media.SizeLimits); err != nil {
+        return nil, errors.Wrap(err, "invalid media size limits")
+    }
+
     ctx, cancel := context.WithCancel(context.Background())
 
     service := &MessageService{
@@ -105,9 +179,11 @@ func NewMessageService(repo *repository.MessageRepository, producer MessageProdu
         producer:        producer,
         whatsappService: whatsappService,
         breaker:         gobreaker.NewCircuitBreaker(breakerSettings),
+        orgLimit:        NewOrganizationRateLimiter(cfg.RateLimit),
         config:          cfg,
         ctx:            ctx,
         cancel:         cancel,
+        startedAt:      time.Now(),
     }
 
     // Start background workers
@@ -116,13 +192,166 @@ func NewMessageService(repo *repository.MessageRepository, producer MessageProdu
     return service, nil
 }
 
+// SetFailureRateMonitor enables alerting when the delivery failure rate spikes.
+// It is independent of the circuit breaker and is intended to trigger paging.
+func (s *MessageService) SetFailureRateMonitor(config FailureRateConfig) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.failureMonitor = NewFailureRateMonitor(config)
+}
+
+// SetRecipientRateLimiter caps the number of messages processed per recipient
+func (s *MessageService) SetRecipientRateLimiter(limiter *RecipientRateLimiter) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.recipientLimit = limiter
+}
+
+// EnableTemplateStatusPolling refreshes the review status of templates referenced by
+// scheduled messages every interval. Messages whose template is still pending review are
+// deferred by one interval instead of being sent and failing. It must be called at most once.
+func (s *MessageService) EnableTemplateStatusPolling(source TemplateStatusSource, interval time.Duration) error {
+    if source == nil {
+        return errors.New("template status source is required")
+    }
+    if interval < time.Second {
+        return errors.Errorf("template status poll interval must be at least 1s, got %s", interval)
+    }
+
+    s.mu.Lock()
+    if s.ctx.Err() != nil {
+        s.mu.Unlock()
+        return errors.New("message service is shut down")
+    }
+    if s.templateStatus != nil {
+        s.mu.Unlock()
+        return errors.New("template status polling is already enabled")
+    }
+    poll := &templateStatusPoll{source: source, interval: interval}
+    s.templateStatus = poll
+    s.wg.Add(1)
+    s.mu.Unlock()
+
+    go func() {
+        defer s.wg.Done()
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+
+        s.pollTemplateStatuses(poll.source)
+        for {
+            select {
+            case <-s.ctx.Done():
+                return
+            case <-ticker.C:
+                s.pollTemplateStatuses(poll.source)
+            }
+        }
+    }()
+    return nil
+}
+
+// pollTemplateStatuses stores the current review status of every template referenced by a
+// scheduled message and refreshes the count-by-status metric
+func (s *MessageService) pollTemplateStatuses(source TemplateStatusSource) {
+    ctx, cancel := context.WithTimeout(s.ctx, messageTimeout)
+    defer cancel()
+
+    refs, err := s.repo.GetScheduledTemplateRefs(ctx)
+    if err != nil {
+        messageProcessed.WithLabelValues("template_status_error").Inc()
+        return
+    }
+
+    for _, ref := range refs {
+        status, err := source.GetTemplateStatus(ctx, ref.Name, ref.Language)
+        if err != nil {
+            messageProcessed.WithLabelValues("template_status_error").Inc()
+            continue
+        }
+        if err := s.repo.UpsertTemplateStatus(ctx, ref, status); err != nil {
+            messageProcessed.WithLabelValues("template_status_error").Inc()
+        }
+    }
+
+    counts, err := s.repo.CountTemplateStatuses(ctx)
+    if err != nil {
+        messageProcessed.WithLabelValues("template_status_error").Inc()
+        return
+    }
+    templateStatusCount.Reset()
+    for status, count := range counts {
+        templateStatusCount.WithLabelValues(status).Set(float64(count))
+    }
+}
+
+// deferPendingTemplate re-queues a message whose template is still pending review, reporting
+// deferred. Templates that have not been polled are sent as usual.
+func (s *MessageService) deferPendingTemplate(ctx context.Context, msg *models.Message) (bool, error) {
+    s.mu.RLock()
+    poll := s.templateStatus
+    s.mu.RUnlock()
+
+    if poll == nil || msg.Template == nil {
+        return false, nil
+    }
+
+    status, err := s.repo.GetTemplateStatus(ctx, repository.TemplateRef{
+        Name:     msg.Template.Name,
+        Language: msg.Template.Language,
+    })
+    if err != nil || status != types.TemplateStatusPending {
+        // An unknown status must not hold delivery back
+        return false, nil
+    }
+
+    if err := s.reschedule(ctx, msg, poll.interval, map[string]interface{}{
+        "template_pending": true,
+    }); err != nil {
+        return false, err
+    }
+
+    messageProcessed.WithLabelValues("template_pending_delayed").Inc()
+    return true, nil
+}
+
+// AddTransformer registers a transformer run on every message before it is sent, in
+// registration order
+func (s *MessageService) AddTransformer(transformer models.MessageTransformer) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.transformers = append(s.transformers, transformer)
+}
+
+// recordOutcome feeds a delivery outcome to the failure rate monitor, if configured
+func (s *MessageService) recordOutcome(failed bool) {
+    s.mu.RLock()
+    monitor := s.failureMonitor
+    s.mu.RUnlock()
+
+    if monitor != nil {
+        monitor.Record(failed)
+    }
+}
+
 // ProcessMessage handles the processing of a single message with comprehensive error handling
 func (s *MessageService) ProcessMessage(ctx context.Context, msg *models.Message) error {
     span, ctx := opentracing.StartSpanFromContext(ctx, "MessageService.ProcessMessage")
     defer span.Finish()
 
-    timer := prometheus.NewTimer(messageProcessingDuration.WithLabelValues("process_message"))
-    defer timer.ObserveDuration()
+    start := time.Now()
+    defer func() {
+        telemetry.ObserveWithTraceExemplar(ctx, messageProcessingDuration.WithLabelValues("process_message"), time.Since(start).Seconds())
+    }()
+
+    // Apply pre-send transformations such as footers and redaction,
+    // validating their output along with the rest of the message
+    s.mu.RLock()
+    transformers := s.transformers
+    s.mu.RUnlock()
+    if err := models.ApplyTransformers(ctx, msg, transformers); err != nil {
+        messageProcessed.WithLabelValues("transform_aborted").Inc()
+        return err
+    }
 
     // Validate message
     if err := msg.Validate(); err != nil {
@@ -130,27 +359,48 @@ func (s *MessageService) ProcessMessage(ctx context.Context, msg *models.Message
         return errors.Wrap(err, "message validation failed")
     }
 
-    // Process message with circuit breaker
-    _, err := s.breaker.Execute(func() (interface{}, error) {
-        whatsappMsg := &types.Message{
-            To:      msg.RecipientPhone,
-            Content: msg.Content,
-        }
+    // Enforce the per-recipient rate limit before spending an API call
+    deferred, err := s.enforceRecipientLimit(ctx, msg)
+    if err != nil || deferred {
+        return err
+    }
 
+    // Enforce the organization's quota so one tenant cannot starve the others
+    deferred, err = s.enforceOrganizationLimit(ctx, msg)
+    if err != nil || deferred {
+        return err
+    }
+
+    // Hold messages whose template WhatsApp has not approved yet
+    deferred, err = s.deferPendingTemplate(ctx, msg)
+    if err != nil || deferred {
+        return err
+    }
+
+    // Process message with circuit breaker
+    _, err = s.breaker.Execute(func() (interface{}, error) {
         if msg.Template != nil {
             if err := s.whatsappService.ValidateTemplate(ctx, msg.Template); err != nil {
                 return nil, errors.Wrap(err, "template validation failed")
             }
-            whatsappMsg.Template = msg.Template
         }
 
+        whatsappMsg := models.ToWhatsAppMessage(msg)
+
+        sendStart := time.Now()
         resp, err := s.whatsappService.SendMessage(ctx, whatsappMsg)
+        outcome := "success"
+        if err != nil {
+            outcome = "error"
+        }
+        telemetry.ObserveWithTraceExemplar(ctx, messageSendDuration.WithLabelValues(outcome), time.Since(sendStart).Seconds())
         if err != nil {
             return nil, errors.Wrap(err, "failed to send message")
         }
 
         return resp, nil
     })
+    s.recordOutcome(err != nil)
 
     if err != nil {
         messageProcessed.WithLabelValues("error").Inc()
@@ -164,7 +414,7 @@ func (s *MessageService) ProcessMessage(ctx context.Context, msg *models.Message
     msg.Status = models.MessageStatusSent
     msg.SentAt = ptr(time.Now())
     
-    if err := s.repo.UpdateStatusWithMetadata(ctx, msg.ID, msg.Status, map[string]interface{}{
+    if _, err := s.repo.UpdateStatusWithMetadata(ctx, msg.ID, msg.Status, map[string]interface{}{
         "sent_at": msg.SentAt,
     }); err != nil {
         return errors.Wrap(err, "failed to update message status")
@@ -174,6 +424,46 @@ func (s *MessageService) ProcessMessage(ctx context.Context, msg *models.Message
     return nil
 }
 
+// SendSplit sends a message whose text or caption exceeds the API limits as several
+// messages, one after another so they arrive in order. The first part keeps the message's
+// ID; later parts are stored as their own messages with IDs derived from it, so retrying
+// the split does not duplicate them. Sending stops at the first part that fails.
+func (s *MessageService) SendSplit(ctx context.Context, msg *models.Message) error {
+    span, ctx := opentracing.StartSpanFromContext(ctx, "MessageService.SendSplit")
+    defer span.Finish()
+
+    contents := utils.SplitLongMessage(msg.Content)
+    if len(contents) == 1 {
+        return s.ProcessMessage(ctx, msg)
+    }
+
+    parts := make([]*models.Message, len(contents))
+    for i, content := range contents {
+        part := *msg
+        part.Content = content
+        if i > 0 {
+            part.ID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/part/%d", msg.ID, i))).String()
+            part.Template = nil
+            if _, err := s.repo.Create(ctx, &part); err != nil {
+                return errors.Wrapf(err, "failed to store part %d of message %s", i+1, msg.ID)
+            }
+        }
+        parts[i] = &part
+    }
+
+    for i, part := range parts {
+        if err := s.ProcessMessage(ctx, part); err != nil {
+            messageProcessed.WithLabelValues("split_error").Inc()
+            return errors.Wrapf(err, "failed to send part %d of %d of message %s", i+1, len(parts), msg.ID)
+        }
+    }
+
+    msg.Status = parts[0].Status
+    msg.SentAt = parts[0].SentAt
+    messageProcessed.WithLabelValues("split_sent").Inc()
+    return nil
+}
+
 // ProcessBatch handles batch processing of messages with parallel execution
 func (s *MessageService) ProcessBatch(ctx context.Context, messages []*models.Message) error {
     span, ctx := opentracing.StartSpanFromContext(ctx, "MessageService.ProcessBatch")
@@ -186,16 +476,40 @@ func (s *MessageService) ProcessBatch(ctx context.Context, messages []*models.Me
     activeBatches.Inc()
     defer activeBatches.Dec()
 
-    // Process messages in parallel with bounded concurrency
+    // Process messages in parallel with bounded concurrency. The semaphore is acquired before
+    // each goroutine starts, so at most maxConcurrentBatches goroutines exist at once however
+    // large the batch is. The batch has its own WaitGroup so waiting for it never waits on the
+    // service's background workers.
     errChan := make(chan error, len(messages))
     semaphore := make(chan struct{}, maxConcurrentBatches)
+    var wg sync.WaitGroup
 
-    for _, msg := range messages {
-        s.wg.Add(1)
+    for i, msg := range messages {
+        // select picks at random when both cases are ready, so check for cancellation first
+        if ctx.Err() == nil {
+            select {
+            case semaphore <- struct{}{}:
+            case <-ctx.Done():
+            }
+        }
+        if err := ctx.Err(); err != nil {
+            // Messages not yet started each report the cancellation
+            for _, m := range messages[i:] {
+                errChan <- errors.Wrapf(err, "message %s not processed", m.ID)
+            }
+            break
+        }
+
+        wg.Add(1)
         go func(m *models.Message) {
-            defer s.wg.Done()
-            semaphore <- struct{}{}
+            defer wg.Done()
             defer func() { <-semaphore }()
+            // A panic in one message must not take down the service with the rest of the batch
+            defer func() {
+                if r := recover(); r != nil {
+                    errChan <- s.failPanickedMessage(ctx, m, r)
+                }
+            }()
 
             if err := s.ProcessMessage(ctx, m); err != nil {
                 errChan <- errors.Wrapf(err, "failed to process message %s", m.ID)
@@ -204,7 +518,7 @@ func (s *MessageService) ProcessBatch(ctx context.Context, messages []*models.Me
     }
 
     // Wait for all goroutines to complete
-    s.wg.Wait()
+    wg.Wait()
     close(errChan)
 
     // Collect errors
@@ -220,6 +534,115 @@ func (s *MessageService) ProcessBatch(ctx context.Context, messages []*models.Me
     return nil
 }
 
+// failPanickedMessage marks a message whose processing panicked as failed and returns the
+// error the batch reports for it
+func (s *MessageService) failPanickedMessage(ctx context.Context, msg *models.Message, recovered interface{}) error {
+    messageProcessed.WithLabelValues("panic").Inc()
+    err := errors.Errorf("panic processing message %s: %v", msg.ID, recovered)
+    log.Printf("Recovered from %v", err)
+
+    if _, updateErr := s.repo.UpdateStatusWithMetadata(ctx, msg.ID, models.MessageStatusFailed, map[string]interface{}{
+        "error_details": err.Error(),
+        "failed_at":     time.Now(),
+    }); updateErr != nil {
+        log.Printf("Error marking message %s failed after panic: %v", msg.ID, updateErr)
+    }
+    return err
+}
+
+// enforceRecipientLimit consults the recipient rate limiter, if configured. Over-limit
+// messages are either re-queued for when the window frees up, reporting deferred, or
+// marked failed with ErrRecipientRateLimited, depending on the limiter mode.
+func (s *MessageService) enforceRecipientLimit(ctx context.Context, msg *models.Message) (bool, error) {
+    s.mu.RLock()
+    limiter := s.recipientLimit
+    s.mu.RUnlock()
+
+    if limiter == nil {
+        return false, nil
+    }
+
+    allowed, retryAfter, err := limiter.Allow(ctx, msg.RecipientPhone, msg.ID)
+    if err != nil {
+        // Fail open: an unavailable limiter must not halt delivery
+        messageProcessed.WithLabelValues("rate_limit_error").Inc()
+        return false, nil
+    }
+    if allowed {
+        return false, nil
+    }
+
+    if limiter.Mode() == RecipientLimitModeReject {
+        messageProcessed.WithLabelValues("rate_limited").Inc()
+        if _, err := s.repo.UpdateStatusWithMetadata(ctx, msg.ID, models.MessageStatusFailed, map[string]interface{}{
+            "error_details": ErrRecipientRateLimited.Error(),
+            "failed_at":     time.Now(),
+        }); err != nil {
+            return false, errors.Wrap(err, "failed to update message status")
+        }
+        return false, ErrRecipientRateLimited
+    }
+
+    if err := s.requeueRateLimited(ctx, msg, retryAfter, "recipient"); err != nil {
+        return false, err
+    }
+
+    messageProcessed.WithLabelValues("rate_limit_delayed").Inc()
+    return true, nil
+}
+
+// enforceOrganizationLimit consults the per-organization limiter, if configured. Messages
+// over their organization's quota are re-queued for when a token frees up, reporting deferred.
+func (s *MessageService) enforceOrganizationLimit(ctx context.Context, msg *models.Message) (bool, error) {
+    if s.orgLimit == nil {
+        return false, nil
+    }
+
+    allowed, retryAfter := s.orgLimit.Reserve(msg.OrganizationID)
+    if allowed {
+        return false, nil
+    }
+
+    if err := s.requeueRateLimited(ctx, msg, retryAfter, "organization"); err != nil {
+        return false, err
+    }
+
+    messageProcessed.WithLabelValues("org_rate_limit_delayed").Inc()
+    return true, nil
+}
+
+// requeueRateLimited schedules a rate limited message to be retried after retryAfter and
+// records the limit that deferred it
+func (s *MessageService) requeueRateLimited(ctx context.Context, msg *models.Message, retryAfter time.Duration, limit string) error {
+    return s.reschedule(ctx, msg, retryAfter, map[string]interface{}{
+        "rate_limited": true,
+        "rate_limit":   limit,
+    })
+}
+
+// reschedule defers a message by retryAfter, recording why in its status metadata
+func (s *MessageService) reschedule(ctx context.Context, msg *models.Message, retryAfter time.Duration, reason map[string]interface{}) error {
+    // The scheduled queue has second granularity
+    if retryAfter < time.Second {
+        retryAfter = time.Second
+    }
+    scheduledAt := time.Now().Add(retryAfter)
+    if err := s.producer.ScheduleMessage(msg, scheduledAt); err != nil {
+        return errors.Wrap(err, "failed to re-queue deferred message")
+    }
+    msg.Status = models.MessageStatusScheduled
+    msg.ScheduledAt = &scheduledAt
+
+    metadata := map[string]interface{}{"scheduled_at": scheduledAt}
+    for key, value := range reason {
+        metadata[key] = value
+    }
+    if _, err := s.repo.UpdateStatusWithMetadata(ctx, msg.ID, msg.Status, metadata); err != nil {
+        return errors.Wrap(err, "failed to update message status")
+    }
+    return nil
+}
+
 // handleMessageError handles message processing errors with retry logic
 func (s *MessageService) handleMessageError(ctx context.Context, msg *models.Message, err error) error {
     msg.RetryCount++
@@ -229,11 +652,38 @@ func (s *MessageService) handleMessageError(ctx context.Context, msg *models.Mes
         status = models.MessageStatusFailed
     }
 
-    return s.repo.UpdateStatusWithMetadata(ctx, msg.ID, status, map[string]interface{}{
+    if _, err := s.repo.UpdateStatusWithMetadata(ctx, msg.ID, status, map[string]interface{}{
         "retry_count":   msg.RetryCount,
         "error_details": err.Error(),
         "failed_at":     time.Now(),
-    })
+    }); err != nil {
+        return err
+    }
+    return nil
+}
+
+// Shutdown stops the background workers and waits for them to exit, returning ctx.Err() if
+// ctx ends first. Batches in flight are not waited for; they end when their own contexts do.
+// It is safe to call more than once; later calls wait for the same workers.
+func (s *MessageService) Shutdown(ctx context.Context) error {
+    // Holding mu orders the cancel after any EnableTemplateStatusPolling that already
+    // checked s.ctx, so no worker is added while Shutdown waits
+    s.mu.Lock()
+    s.cancel()
+    s.mu.Unlock()
+
+    done := make(chan struct{})
+    go func() {
+        s.wg.Wait()
+        close(done)
+    }()
+
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
 }
 
 // startWorkers initializes background workers for message processing
@@ -250,29 +700,143 @@ func (s *MessageService) startWorkers() {
             case <-s.ctx.Done():
                 return
             case <-ticker.C:
-                s.processScheduledMessages()
+                s.runScheduledMessages()
             }
         }
     }()
+
+    // Start retention purge
+    if retention := s.config.Retention; retention.Period > 0 {
+        s.wg.Add(1)
+        go func() {
+            defer s.wg.Done()
+            ticker := time.NewTicker(retention.PurgeInterval)
+            defer ticker.Stop()
+
+            for {
+                select {
+                case <-s.ctx.Done():
+                    return
+                case <-ticker.C:
+                    s.purgeExpiredMessages(retention.Period)
+                }
+            }
+        }()
+    }
+}
+
+// purgeExpiredMessages permanently deletes messages past the retention period
+func (s *MessageService) purgeExpiredMessages(period time.Duration) {
+    ctx, cancel := context.WithTimeout(s.ctx, messageTimeout)
+    defer cancel()
+
+    purged, err := s.repo.PurgeOlderThan(ctx, time.Now().Add(-period))
+    messagesPurged.Add(float64(purged))
+    if err != nil {
+        messageProcessed.WithLabelValues("purge_error").Inc()
+    }
 }
 
-// processScheduledMessages processes messages scheduled for delivery
+// runScheduledMessages runs one scheduled pass, recovering from panics so a single bad
+// message cannot stop scheduled delivery, and records a heartbeat on completion
+func (s *MessageService) runScheduledMessages() {
+    defer func() {
+        if r := recover(); r != nil {
+            scheduledWorkerPanics.Inc()
+            messageProcessed.WithLabelValues("scheduled_panic").Inc()
+        }
+    }()
+
+    s.processScheduledMessages()
+
+    now := time.Now()
+    s.lastScheduled.Store(now.UnixNano())
+    lastScheduledRun.Set(float64(now.Unix()))
+}
+
+// LastScheduledRun returns when the scheduled worker last completed, or the zero time if it
+// has not completed a run yet
+func (s *MessageService) LastScheduledRun() time.Time {
+    nanos := s.lastScheduled.Load()
+    if nanos == 0 {
+        return time.Time{}
+    }
+    return time.Unix(0, nanos)
+}
+
+// CheckScheduledWorker reports an error when the scheduled worker has not completed a run
+// within several processing intervals. It is usable as a ReadinessCheck.
+func (s *MessageService) CheckScheduledWorker(ctx context.Context) error {
+    maxAge := time.Duration(scheduledStaleRuns) * s.config.MessageQueue.ProcessingInterval
+    last := s.LastScheduledRun()
+    if last.IsZero() {
+        // Allow the worker its first runs after startup
+        if time.Since(s.startedAt) <= maxAge {
+            return nil
+        }
+        return errors.New("scheduled worker has not completed a run")
+    }
+    if age := time.Since(last); age > maxAge {
+        return errors.Errorf("scheduled worker last ran %s ago", age.Round(time.Second))
+    }
+    return nil
+}
+
+// processScheduledMessages processes messages scheduled for delivery. Each poll picks up
+// where the previous one ended, so a message fires within one processing interval of its
+// scheduled time and a slow or failed poll does not leave a gap.
 func (s *MessageService) processScheduledMessages() {
     ctx, cancel := context.WithTimeout(s.ctx, messageTimeout)
     defer cancel()
 
     now := time.Now()
-    messages, err := s.repo.GetScheduledMessages(ctx, now.Add(-time.Minute), now)
+    from := s.scheduledThrough
+    if from.IsZero() {
+        from = now.Add(-s.config.MessageQueue.ScheduleLookback)
+    }
+
+    messages, err := s.repo.GetScheduledMessages(ctx, from, now)
     if err != nil {
         messageProcessed.WithLabelValues("scheduled_error").Inc()
         return
     }
 
+    // A full batch may have left messages behind; resume just before the last one fetched
+    // so ties at its scheduled time are fetched again rather than skipped
+    s.scheduledThrough = now
+    if len(messages) >= repository.MaxScheduledBatch {
+        if last := messages[len(messages)-1]; last.ScheduledAt != nil {
+            s.scheduledThrough = last.ScheduledAt.Add(-time.Microsecond)
+        }
+    }
+
     if len(messages) > 0 {
         if err := s.ProcessBatch(ctx, messages); err != nil {
             messageProcessed.WithLabelValues("scheduled_batch_error").Inc()
         }
     }
+
+    // Recurring messages schedule their next occurrence whether or not this one was delivered
+    for _, msg := range messages {
+        s.scheduleNextOccurrence(ctx, msg)
+    }
+}
+
+// scheduleNextOccurrence stores the next occurrence of a recurring message until its series
+// ends. Occurrence IDs are deterministic, so a message fetched twice is scheduled once.
+func (s *MessageService) scheduleNextOccurrence(ctx context.Context, msg *models.Message) {
+    next, ok := msg.NextOccurrence()
+    if !ok {
+        return
+    }
+
+    created, err := s.repo.Create(ctx, next)
+    switch {
+    case err != nil:
+        messageProcessed.WithLabelValues("recurrence_error").Inc()
+    case created:
+        messageProcessed.WithLabelValues("recurrence_scheduled").Inc()
+    }
 }
 
 // GetMetrics returns current service metrics
@@ -280,10 +844,15 @@ func (s *MessageService) GetMetrics() map[string]interface{} {
     s.mu.RLock()
     defer s.mu.RUnlock()
 
-    return map[string]interface{}{
+    metrics := map[string]interface{}{
         "active_batches": activeBatches.Get(),
         "circuit_breaker_state": s.breaker.State().String(),
+        "last_scheduled_run": s.LastScheduledRun(),
+    }
+    if s.orgLimit != nil {
+        metrics["organization_quota_remaining"] = s.orgLimit.Remaining()
     }
+    return metrics
 }
 
 // ptr returns a pointer to the given value
@@ -0,0 +1,251 @@
+package services
+
+import (
+    "context"
+    "database/sql"
+    "database/sql/driver"
+    "errors"
+    "io"
+    "runtime"
+    "strconv"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/sony/gobreaker"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "message-service/internal/config"
+    "message-service/internal/models"
+    "message-service/internal/repository"
+    "message-service/pkg/whatsapp/types"
+)
+
+// statusRecorder is a database/sql driver that answers every query with the ID it was given
+// and records the status each message was moved to
+type statusRecorder struct {
+    mu       sync.Mutex
+    statuses map[string]string
+}
+
+func (d *statusRecorder) Open(string) (driver.Conn, error) { return &recorderConn{d}, nil }
+
+func (d *statusRecorder) status(id string) string {
+    d.mu.Lock()
+    defer d.mu.Unlock()
+    return d.statuses[id]
+}
+
+type recorderConn struct{ d *statusRecorder }
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+    return &recorderStmt{d: c.d, query: query}, nil
+}
+func (c *recorderConn) Close() error              { return nil }
+func (c *recorderConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+type recorderStmt struct {
+    d     *statusRecorder
+    query string
+}
+
+func (s *recorderStmt) Close() error  { return nil }
+func (s *recorderStmt) NumInput() int { return -1 }
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+    return driver.RowsAffected(1), nil
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+    if strings.Contains(s.query, "UPDATE messages") && len(args) > 1 {
+        id, _ := args[0].(string)
+        status, _ := args[1].(string)
+        s.d.mu.Lock()
+        s.d.statuses[id] = status
+        s.d.mu.Unlock()
+    }
+    var id driver.Value
+    if len(args) > 0 {
+        id = args[0]
+    }
+    return &recorderRows{values: []driver.Value{id}}, nil
+}
+
+type recorderRows struct {
+    values []driver.Value
+    done   bool
+}
+
+func (r *recorderRows) Columns() []string { return []string{"id"} }
+func (r *recorderRows) Close() error      { return nil }
+
+func (r *recorderRows) Next(dest []driver.Value) error {
+    if r.done {
+        return io.EOF
+    }
+    r.done = true
+    copy(dest, r.values)
+    return nil
+}
+
+// recorder backs the services_status_recorder driver
+var recorder = &statusRecorder{statuses: make(map[string]string)}
+
+func init() {
+    sql.Register("services_status_recorder", recorder)
+}
+
+func TestProcessBatchRecoversFromMessagePanic(t *testing.T) {
+    db, err := sql.Open("services_status_recorder", "")
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo, err := repository.NewMessageRepository(db, &config.Config{})
+    require.NoError(t, err)
+
+    service := &MessageService{
+        repo: repo,
+        transformers: []models.MessageTransformer{
+            models.MessageTransformerFunc(func(ctx context.Context, msg *models.Message) error {
+                panic("transformer bug")
+            }),
+        },
+    }
+
+    err = service.ProcessBatch(context.Background(), []*models.Message{{ID: "msg-1"}, {ID: "msg-2"}})
+
+    require.Error(t, err)
+    assert.Contains(t, err.Error(), "2 errors")
+    assert.Equal(t, models.MessageStatusFailed, recorder.status("msg-1"))
+    assert.Equal(t, models.MessageStatusFailed, recorder.status("msg-2"))
+}
+
+// blockingSender holds every send until released, tracking how many are in flight
+type blockingSender struct {
+    inFlight    atomic.Int32
+    maxInFlight atomic.Int32
+    started     chan struct{}
+    release     chan struct{}
+}
+
+func (s *blockingSender) SendMessage(ctx context.Context, msg *types.Message) (*types.APIResponse, error) {
+    n := s.inFlight.Add(1)
+    defer s.inFlight.Add(-1)
+    for {
+        max := s.maxInFlight.Load()
+        if n <= max || s.maxInFlight.CompareAndSwap(max, n) {
+            break
+        }
+    }
+
+    s.started <- struct{}{}
+    <-s.release
+    return &types.APIResponse{}, nil
+}
+
+func (s *blockingSender) ValidateTemplate(ctx context.Context, template *types.Template) error {
+    return nil
+}
+
+func TestProcessBatchBoundsConcurrentSends(t *testing.T) {
+    db, err := sql.Open("services_status_recorder", "")
+    require.NoError(t, err)
+    defer db.Close()
+    repo, err := repository.NewMessageRepository(db, &config.Config{})
+    require.NoError(t, err)
+
+    const batch = 200
+    sender := &blockingSender{started: make(chan struct{}, batch), release: make(chan struct{})}
+    service := &MessageService{
+        repo:            repo,
+        whatsappService: sender,
+        breaker:         gobreaker.NewCircuitBreaker(gobreaker.Settings{Name: "test"}),
+    }
+
+    messages := make([]*models.Message, batch)
+    for i := range messages {
+        messages[i] = &models.Message{
+            ID:             "msg-" + strconv.Itoa(i),
+            OrganizationID: "org-1",
+            RecipientPhone: "+14155550100",
+            Content:        types.MessageContent{Text: "hello"},
+            Status:         models.MessageStatusPending,
+        }
+    }
+
+    before := runtime.NumGoroutine()
+    done := make(chan error, 1)
+    go func() { done <- service.ProcessBatch(context.Background(), messages) }()
+
+    // Fill every slot, then check that no further send starts while they are held
+    for i := 0; i < maxConcurrentBatches; i++ {
+        <-sender.started
+    }
+    select {
+    case <-sender.started:
+        t.Fatal("a send started beyond the concurrency limit")
+    case <-time.After(50 * time.Millisecond):
+    }
+    // The batch goroutine plus one per held send
+    assert.LessOrEqual(t, runtime.NumGoroutine()-before, maxConcurrentBatches+1)
+
+    close(sender.release)
+    require.NoError(t, <-done)
+    assert.EqualValues(t, maxConcurrentBatches, sender.maxInFlight.Load())
+    assert.Len(t, sender.started, batch-maxConcurrentBatches, "every message was sent")
+}
+
+// newLifecycleService returns a service with its background workers running and no database
+func newLifecycleService() *MessageService {
+    ctx, cancel := context.WithCancel(context.Background())
+    service := &MessageService{
+        config: &config.Config{
+            MessageQueue: config.MessageQueueConfig{ProcessingInterval: time.Hour},
+            Retention:    config.RetentionConfig{Period: time.Hour, PurgeInterval: time.Hour},
+        },
+        ctx:    ctx,
+        cancel: cancel,
+    }
+    service.startWorkers()
+    return service
+}
+
+func TestShutdownStopsBackgroundWorkers(t *testing.T) {
+    before := runtime.NumGoroutine()
+    service := newLifecycleService()
+    require.Greater(t, runtime.NumGoroutine(), before)
+
+    ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+    defer cancel()
+    require.NoError(t, service.Shutdown(ctx))
+
+    // Shutdown's own waiter exits just after wg.Wait returns
+    assert.Eventually(t, func() bool {
+        return runtime.NumGoroutine() <= before
+    }, time.Second, 10*time.Millisecond, "background workers still running after Shutdown")
+
+    // A second call waits for the same, already stopped, workers
+    assert.NoError(t, service.Shutdown(ctx))
+    assert.Error(t, service.EnableTemplateStatusPolling(fakeTemplateStatusSource{}, time.Minute))
+}
+
+func TestShutdownReturnsWhenContextEnds(t *testing.T) {
+    service := newLifecycleService()
+
+    // A worker that ignores cancellation keeps Shutdown waiting
+    service.wg.Add(1)
+    defer service.wg.Done()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    assert.ErrorIs(t, service.Shutdown(ctx), context.DeadlineExceeded)
+}
+
+type fakeTemplateStatusSource struct{}
+
+func (fakeTemplateStatusSource) GetTemplateStatus(ctx context.Context, name, language string) (string, error) {
+    return "APPROVED", nil
+}
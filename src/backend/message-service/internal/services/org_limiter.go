@@ -0,0 +1,103 @@
+// Package services provides per-organization message rate limiting
+// Version: go1.21
+package services
+
+import (
+    "sync"
+    "time"
+
+    "golang.org/x/time/rate" // v0.5.0
+
+    "message-service/internal/config"
+)
+
+// OrganizationRateLimiter keeps a token bucket per organization so one busy tenant cannot
+// starve the others. Buckets are created on first use and live in process memory.
+type OrganizationRateLimiter struct {
+    perMinute int
+    burst     int
+    overrides map[string]int
+    limiters  map[string]*rate.Limiter
+    mu        sync.Mutex
+}
+
+// NewOrganizationRateLimiter creates a limiter from the rate limit configuration, or returns
+// nil when neither a default rate nor any override is configured
+func NewOrganizationRateLimiter(cfg config.RateLimitConfig) *OrganizationRateLimiter {
+    if cfg.OrganizationRate <= 0 && len(cfg.OrganizationRates) == 0 {
+        return nil
+    }
+
+    overrides := make(map[string]int, len(cfg.OrganizationRates))
+    for org, perMinute := range cfg.OrganizationRates {
+        overrides[org] = perMinute
+    }
+
+    return &OrganizationRateLimiter{
+        perMinute: cfg.OrganizationRate,
+        burst:     cfg.OrganizationBurst,
+        overrides: overrides,
+        limiters:  make(map[string]*rate.Limiter),
+    }
+}
+
+// Reserve takes a token for the organization if one is available now. Otherwise it takes
+// nothing and returns how long until a token will be available.
+func (l *OrganizationRateLimiter) Reserve(organizationID string) (bool, time.Duration) {
+    limiter := l.limiter(organizationID)
+    if limiter == nil {
+        return true, 0
+    }
+
+    now := time.Now()
+    reservation := limiter.ReserveN(now, 1)
+    if !reservation.OK() {
+        return false, time.Minute
+    }
+    if delay := reservation.DelayFrom(now); delay > 0 {
+        reservation.CancelAt(now)
+        return false, delay
+    }
+    return true, 0
+}
+
+// Remaining returns the tokens currently available to each organization seen so far
+func (l *OrganizationRateLimiter) Remaining() map[string]float64 {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    remaining := make(map[string]float64, len(l.limiters))
+    for org, limiter := range l.limiters {
+        remaining[org] = limiter.TokensAt(now)
+    }
+    return remaining
+}
+
+// limiter returns the organization's bucket, creating it on first use, or nil when the
+// organization is unlimited
+func (l *OrganizationRateLimiter) limiter(organizationID string) *rate.Limiter {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if limiter, ok := l.limiters[organizationID]; ok {
+        return limiter
+    }
+
+    perMinute, ok := l.overrides[organizationID]
+    if !ok {
+        perMinute = l.perMinute
+    }
+    if perMinute <= 0 {
+        return nil
+    }
+
+    burst := l.burst
+    if burst <= 0 {
+        burst = perMinute
+    }
+
+    limiter := rate.NewLimiter(rate.Limit(float64(perMinute)/time.Minute.Seconds()), burst)
+    l.limiters[organizationID] = limiter
+    return limiter
+}
@@ -0,0 +1,97 @@
+// Package services provides the startup readiness gate for the message service
+// Version: go1.21
+package services
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "time"
+)
+
+// readinessCheckTimeout bounds each dependency check made while answering a readiness probe
+const readinessCheckTimeout = 2 * time.Second
+
+// ReadinessCheck verifies that a dependency such as the database or Redis is reachable
+type ReadinessCheck func(ctx context.Context) error
+
+// ReadinessGate reports the service ready only once warmup has succeeded and every
+// dependency check passes, keeping traffic away while caches and pools are cold
+type ReadinessGate struct {
+    warmup  func(ctx context.Context) error
+    checks  map[string]ReadinessCheck
+    mu      sync.RWMutex
+    warmed  bool
+    lastErr error
+}
+
+// NewReadinessGate creates a gate that runs warmup, e.g. template cache warming, before
+// consulting the named dependency checks
+func NewReadinessGate(warmup func(ctx context.Context) error, checks map[string]ReadinessCheck) (*ReadinessGate, error) {
+    if warmup == nil {
+        return nil, errors.New("warmup function is required")
+    }
+    return &ReadinessGate{warmup: warmup, checks: checks}, nil
+}
+
+// Warmup runs the warmup once, marking the gate warmed on success. A failed warmup leaves
+// the service not ready and may be retried.
+func (g *ReadinessGate) Warmup(ctx context.Context) error {
+    err := g.warmup(ctx)
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    g.lastErr = err
+    if err == nil {
+        g.warmed = true
+    }
+    return err
+}
+
+// WarmupWithRetry retries the warmup every interval until it succeeds or ctx is done
+func (g *ReadinessGate) WarmupWithRetry(ctx context.Context, interval time.Duration) error {
+    for {
+        if err := g.Warmup(ctx); err == nil {
+            return nil
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(interval):
+        }
+    }
+}
+
+// Ready reports whether the service may receive traffic, with the status of each check
+func (g *ReadinessGate) Ready(ctx context.Context) (bool, map[string]string) {
+    g.mu.RLock()
+    warmed, lastErr := g.warmed, g.lastErr
+    g.mu.RUnlock()
+
+    status := make(map[string]string, len(g.checks)+1)
+    ready := warmed
+    switch {
+    case warmed:
+        status["warmup"] = "ok"
+    case lastErr != nil:
+        status["warmup"] = lastErr.Error()
+    default:
+        status["warmup"] = "pending"
+    }
+
+    for name, check := range g.checks {
+        checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+        err := check(checkCtx)
+        cancel()
+
+        if err != nil {
+            ready = false
+            status[name] = err.Error()
+            continue
+        }
+        status[name] = "ok"
+    }
+
+    return ready, status
+}
@@ -0,0 +1,73 @@
+// Package services provides sandbox routing that keeps test traffic away from real users
+// Version: go1.21
+package services
+
+import (
+    "context"
+    "regexp"
+    "strings"
+
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/pkg/errors"                 // v0.9.1
+
+    "message-service/internal/config"
+    "message-service/internal/models"
+)
+
+var sandboxRerouted = promauto.NewCounter(
+    prometheus.CounterOpts{
+        Name: "message_service_sandbox_rerouted_total",
+        Help: "Total number of test messages rerouted to the sandbox number",
+    },
+)
+
+var sandboxNumberRegex = regexp.MustCompile(models.PhoneNumberPattern)
+
+// SandboxRouter is a MessageTransformer that redirects test messages, and messages to
+// configured test prefixes, to a sandbox number while recording the original recipient
+type SandboxRouter struct {
+    number   string
+    prefixes []string
+}
+
+// NewSandboxRouter creates a router from configuration. It returns nil without error when
+// sandbox routing is disabled, so callers register it only if non-nil.
+func NewSandboxRouter(cfg config.SandboxConfig) (*SandboxRouter, error) {
+    if !cfg.Enabled {
+        return nil, nil
+    }
+    if !sandboxNumberRegex.MatchString(cfg.Number) {
+        return nil, errors.Errorf("sandbox number %q must be in E.164 format", cfg.Number)
+    }
+
+    return &SandboxRouter{
+        number:   cfg.Number,
+        prefixes: append([]string(nil), cfg.Prefixes...),
+    }, nil
+}
+
+// Transform reroutes the message if it is flagged as test traffic or its recipient matches
+// a sandbox prefix. Already rerouted messages are left unchanged.
+func (r *SandboxRouter) Transform(ctx context.Context, msg *models.Message) error {
+    if msg.OriginalRecipient != "" || !r.matches(msg) {
+        return nil
+    }
+
+    msg.OriginalRecipient = msg.RecipientPhone
+    msg.RecipientPhone = r.number
+    sandboxRerouted.Inc()
+    return nil
+}
+
+func (r *SandboxRouter) matches(msg *models.Message) bool {
+    if msg.Test {
+        return true
+    }
+    for _, prefix := range r.prefixes {
+        if prefix != "" && strings.HasPrefix(msg.RecipientPhone, prefix) {
+            return true
+        }
+    }
+    return false
+}
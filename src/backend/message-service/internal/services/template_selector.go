@@ -0,0 +1,121 @@
+// Package services provides weighted A/B selection of message template variants
+// Version: go1.21
+package services
+
+import (
+    "hash/fnv"
+
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/pkg/errors"                 // v0.9.1
+
+    "message-service/pkg/whatsapp/types"
+)
+
+// Metadata keys recording the experiment assignment on outbound messages
+const (
+    MetadataExperiment      = "template_experiment"
+    MetadataTemplateVariant = "template_variant"
+)
+
+var templateVariantSelected = promauto.NewCounterVec(
+    prometheus.CounterOpts{
+        Name: "message_service_template_variant_selected_total",
+        Help: "Total number of messages assigned to each template variant",
+    },
+    []string{"experiment", "variant"},
+)
+
+// TemplateVariant is one arm of a template A/B test. Weights are relative, e.g. 70 and 30.
+type TemplateVariant struct {
+    Name     string
+    Template *types.Template
+    Weight   int
+}
+
+// TemplateSelector assigns recipients to template variants in proportion to their weights.
+// Assignment is a pure function of the experiment and recipient, so a recipient always
+// receives the same variant for the lifetime of the experiment.
+type TemplateSelector struct {
+    experiment  string
+    variants    []TemplateVariant
+    totalWeight uint64
+}
+
+// NewTemplateSelector validates the variants and creates a selector for the named experiment
+func NewTemplateSelector(experiment string, variants []TemplateVariant) (*TemplateSelector, error) {
+    if experiment == "" {
+        return nil, errors.New("experiment name is required")
+    }
+    if len(variants) < 2 {
+        return nil, errors.New("at least two template variants are required")
+    }
+
+    seen := make(map[string]bool, len(variants))
+    var total uint64
+    for _, variant := range variants {
+        if variant.Name == "" || variant.Template == nil {
+            return nil, errors.New("each template variant requires a name and template")
+        }
+        if seen[variant.Name] {
+            return nil, errors.Errorf("duplicate template variant %q", variant.Name)
+        }
+        seen[variant.Name] = true
+
+        if variant.Weight < 0 {
+            return nil, errors.Errorf("template variant %q has negative weight %d", variant.Name, variant.Weight)
+        }
+        total += uint64(variant.Weight)
+    }
+    if total == 0 {
+        return nil, errors.New("template variant weights must sum to more than zero")
+    }
+
+    return &TemplateSelector{
+        experiment:  experiment,
+        variants:    append([]TemplateVariant(nil), variants...),
+        totalWeight: total,
+    }, nil
+}
+
+// Select returns the variant assigned to the recipient
+func (s *TemplateSelector) Select(recipient string) *TemplateVariant {
+    h := fnv.New64a()
+    h.Write([]byte(s.experiment))
+    h.Write([]byte{0})
+    h.Write([]byte(recipient))
+    bucket := h.Sum64() % s.totalWeight
+
+    for i := range s.variants {
+        weight := uint64(s.variants[i].Weight)
+        if bucket < weight {
+            return &s.variants[i]
+        }
+        bucket -= weight
+    }
+
+    // Unreachable while weights sum to totalWeight
+    return &s.variants[len(s.variants)-1]
+}
+
+// Apply assigns the message's recipient a variant, sets its template and records the
+// assignment in the message metadata and metrics
+func (s *TemplateSelector) Apply(msg *types.Message) (*TemplateVariant, error) {
+    if msg == nil || msg.To == "" {
+        return nil, errors.New("message with recipient is required")
+    }
+
+    variant := s.Select(msg.To)
+    template := *variant.Template
+    msg.Template = &template
+    msg.Type = types.MessageTypeTemplate
+
+    if msg.Metadata == nil {
+        msg.Metadata = make(map[string]interface{})
+    }
+    msg.Metadata[MetadataExperiment] = s.experiment
+    msg.Metadata[MetadataTemplateVariant] = variant.Name
+
+    templateVariantSelected.WithLabelValues(s.experiment, variant.Name).Inc()
+    return variant, nil
+}
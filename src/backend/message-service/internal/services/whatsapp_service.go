@@ -4,8 +4,10 @@ package services
 
 import (
     "context"
+    "encoding/json"
     "errors"
     "fmt"
+    "os"
     "sync"
     "time"
 
@@ -13,6 +15,7 @@ import (
 
     "github.com/yourdomain/message-service/pkg/whatsapp/client"
     "github.com/yourdomain/message-service/pkg/whatsapp/types"
+    "github.com/yourdomain/message-service/internal/models"
     "github.com/yourdomain/message-service/internal/repository"
     "github.com/yourdomain/message-service/internal/metrics"
 )
@@ -22,15 +25,19 @@ const (
     defaultBatchSize         = 100
     defaultProcessingTimeout = 30 * time.Second
     defaultRetryDelay       = 5 * time.Second
+    maxRetryDelay           = 30 * time.Second
     maxRetryAttempts       = 3
     defaultRateLimit       = rate.Limit(100)
+    staleProcessingTimeout = 10 * time.Minute
+    staleReapInterval      = time.Minute
 )
 
 // Common errors
 var (
-    ErrInvalidMessage     = errors.New("invalid message")
-    ErrProcessingTimeout  = errors.New("message processing timeout")
-    ErrShutdownInProgress = errors.New("service shutdown in progress")
+    ErrInvalidMessage      = errors.New("invalid message")
+    ErrProcessingTimeout   = errors.New("message processing timeout")
+    ErrShutdownInProgress  = errors.New("service shutdown in progress")
+    ErrInvalidWebhookEvent = errors.New("invalid webhook event")
 )
 
 // WhatsAppService handles WhatsApp message processing and delivery
@@ -42,8 +49,17 @@ type WhatsAppService struct {
     rateLimiter *rate.Limiter
     mu          sync.Mutex
     shutdown    context.CancelFunc
+    workerID    string
+    callbacks   *CallbackDispatcher
+
+    handlersMu      sync.RWMutex
+    webhookHandlers map[string]WebhookHandlerFunc
+    defaultHandler  WebhookHandlerFunc
 }
 
+// WebhookHandlerFunc processes one webhook event of the type it was registered for
+type WebhookHandlerFunc func(ctx context.Context, event *types.WebhookEvent) error
+
 // NewWhatsAppService creates a new WhatsApp service instance
 func NewWhatsAppService(client *client.Client, repo *repository.MessageRepository) (*WhatsAppService, error) {
     if client == nil {
@@ -60,7 +76,19 @@ func NewWhatsAppService(client *client.Client, repo *repository.MessageRepositor
         metrics:     metrics.NewCollector("whatsapp_service"),
         rateLimiter: rate.NewLimiter(defaultRateLimit, 1),
         shutdown:    cancel,
+        workerID:    newWorkerID(),
+    }
+
+    // Inbound messages and orders open the customer service window; delivery statuses are
+    // the default. Account and template notices are acknowledged until a handler is registered.
+    service.webhookHandlers = map[string]WebhookHandlerFunc{
+        types.WebhookEventTypeMessage:              service.processInboundEvent,
+        types.WebhookEventTypeOrder:                service.processInboundEvent,
+        types.WebhookEventTypeStatus:               service.processStatusEvent,
+        types.WebhookEventTypeTemplateStatusUpdate: service.ignoreWebhookEvent,
+        types.WebhookEventTypeAccountAlert:         service.ignoreWebhookEvent,
     }
+    service.defaultHandler = service.processStatusEvent
 
     // Start background processing
     go service.processMessages(ctx)
@@ -98,15 +126,28 @@ func (s *WhatsAppService) SendMessage(ctx context.Context, message *types.Messag
     return nil
 }
 
+// SetCallbackDispatcher enables per-message status callbacks for messages with a callback URL
+func (s *WhatsAppService) SetCallbackDispatcher(dispatcher *CallbackDispatcher) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.callbacks = dispatcher
+}
+
 // ProcessPendingMessages processes pending messages in batches
 func (s *WhatsAppService) ProcessPendingMessages(ctx context.Context) error {
     s.metrics.StartTimer("batch_processing")
     defer s.metrics.StopTimer("batch_processing")
 
-    messages, err := s.repository.GetPendingMessages(ctx, defaultBatchSize)
+    // Claim rather than select so concurrent service instances never process the same row
+    claimed, err := s.repository.ClaimPendingMessages(ctx, s.workerID, defaultBatchSize)
     if err != nil {
         s.metrics.IncCounter("fetch_pending_failed")
-        return fmt.Errorf("failed to fetch pending messages: %w", err)
+        return fmt.Errorf("failed to claim pending messages: %w", err)
+    }
+
+    messages := make([]*types.Message, 0, len(claimed))
+    for _, msg := range claimed {
+        messages = append(messages, models.ToWhatsAppMessage(msg))
     }
 
     var processingErrors []error
@@ -149,6 +190,12 @@ func (s *WhatsAppService) Shutdown(ctx context.Context) error {
     done := make(chan struct{})
     go func() {
         s.wg.Wait()
+        s.mu.Lock()
+        dispatcher := s.callbacks
+        s.mu.Unlock()
+        if dispatcher != nil {
+            dispatcher.Wait()
+        }
         close(done)
     }()
 
@@ -160,8 +207,245 @@ func (s *WhatsAppService) Shutdown(ctx context.Context) error {
     }
 }
 
+// ProcessWebhookEvent applies an incoming webhook event to stored messages
+func (s *WhatsAppService) ProcessWebhookEvent(ctx context.Context, event *types.WebhookEvent) error {
+    if event == nil {
+        return ErrInvalidWebhookEvent
+    }
+
+    s.handlersMu.RLock()
+    handler, ok := s.webhookHandlers[event.Type]
+    if !ok {
+        handler = s.defaultHandler
+    }
+    s.handlersMu.RUnlock()
+
+    return handler(ctx, event)
+}
+
+// RegisterWebhookHandler routes webhook events of eventType, such as message, status,
+// template_status_update or account_alert, to h, replacing the current handler. A nil h
+// removes the handler so those events fall back to the default.
+func (s *WhatsAppService) RegisterWebhookHandler(eventType string, h WebhookHandlerFunc) {
+    s.handlersMu.Lock()
+    defer s.handlersMu.Unlock()
+
+    if h == nil {
+        delete(s.webhookHandlers, eventType)
+        return
+    }
+    s.webhookHandlers[eventType] = h
+}
+
+// SetDefaultWebhookHandler replaces the handler for event types without a registered
+// handler. By default they are processed as delivery status updates.
+func (s *WhatsAppService) SetDefaultWebhookHandler(h WebhookHandlerFunc) error {
+    if h == nil {
+        return errors.New("default webhook handler is required")
+    }
+
+    s.handlersMu.Lock()
+    defer s.handlersMu.Unlock()
+    s.defaultHandler = h
+    return nil
+}
+
+// ignoreWebhookEvent acknowledges an event that needs no processing
+func (s *WhatsAppService) ignoreWebhookEvent(ctx context.Context, event *types.WebhookEvent) error {
+    s.metrics.IncCounter("webhook_ignored")
+    return nil
+}
+
 // Internal helper methods
 
+// newWorkerID identifies this service instance when claiming pending messages
+func newWorkerID() string {
+    hostname, err := os.Hostname()
+    if err != nil {
+        hostname = "unknown"
+    }
+    return fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano())
+}
+
+func (s *WhatsAppService) processInboundEvent(ctx context.Context, event *types.WebhookEvent) error {
+    // Parse the quoted message first so it is kept with the stored message
+    replyTo, err := event.ParseReplyContext()
+    if err != nil {
+        s.metrics.IncCounter("webhook_parse_failed")
+        return fmt.Errorf("failed to parse reply context: %w", err)
+    }
+    if replyTo != "" {
+        s.metrics.IncCounter("reply_received")
+    }
+
+    // Any inbound message opens or extends the sender's customer service window
+    if event.From != "" {
+        receivedAt := event.Timestamp
+        if receivedAt.IsZero() {
+            receivedAt = time.Now()
+        }
+        if err := s.repository.RecordInbound(ctx, event.From, receivedAt); err != nil {
+            s.metrics.IncCounter("inbound_record_failed")
+            return fmt.Errorf("failed to record inbound message: %w", err)
+        }
+
+        if event.MessageID != "" {
+            payload := event.Payload
+            if len(payload) == 0 {
+                // Keep the parsed event when the raw body was not attached
+                if encoded, err := json.Marshal(event); err == nil {
+                    payload = encoded
+                }
+            }
+            if err := s.repository.StoreInbound(ctx, event.MessageID, event.From, payload, receivedAt); err != nil {
+                s.metrics.IncCounter("inbound_store_failed")
+                return fmt.Errorf("failed to store inbound message: %w", err)
+            }
+            s.metrics.IncCounter("inbound_stored")
+        }
+    }
+
+    referral, err := event.ParseReferral()
+    if err != nil {
+        s.metrics.IncCounter("webhook_parse_failed")
+        return fmt.Errorf("failed to parse referral: %w", err)
+    }
+
+    if referral != nil {
+        if err := s.repository.StoreReferral(ctx, event.MessageID, event.From, referral, event.Timestamp); err != nil {
+            s.metrics.IncCounter("referral_store_failed")
+            return fmt.Errorf("failed to store referral: %w", err)
+        }
+        s.metrics.IncCounter("referral_received")
+    }
+
+    order, err := event.ParseOrder()
+    if err != nil {
+        s.metrics.IncCounter("webhook_parse_failed")
+        return fmt.Errorf("failed to parse order: %w", err)
+    }
+
+    if order != nil {
+        if err := s.repository.StoreOrder(ctx, order); err != nil {
+            s.metrics.IncCounter("order_store_failed")
+            return fmt.Errorf("failed to store order: %w", err)
+        }
+        s.metrics.IncCounter("order_received")
+    }
+
+    address, err := event.ParseAddress()
+    if err != nil {
+        s.metrics.IncCounter("webhook_parse_failed")
+        return fmt.Errorf("failed to parse address response: %w", err)
+    }
+    if address != nil {
+        s.metrics.IncCounter("address_received")
+    }
+
+    reply, err := event.ParseInteractiveReply()
+    if err != nil {
+        s.metrics.IncCounter("webhook_parse_failed")
+        return fmt.Errorf("failed to parse interactive reply: %w", err)
+    }
+    if reply != nil {
+        s.metrics.IncCounter("interactive_reply_received")
+    }
+
+    return nil
+}
+
+// webhookStatuses maps the statuses WhatsApp reports in webhooks to message statuses
+var webhookStatuses = map[types.MessageStatus]string{
+    types.MessageStatusSent:      models.MessageStatusSent,
+    types.MessageStatusDelivered: models.MessageStatusDelivered,
+    types.MessageStatusRead:      models.MessageStatusRead,
+    types.MessageStatusFailed:    models.MessageStatusFailed,
+}
+
+func (s *WhatsAppService) processStatusEvent(ctx context.Context, event *types.WebhookEvent) error {
+    // Correlate by the caller's external reference echoed back in biz_opaque_callback_data
+    if event.MessageID == "" && event.BizOpaqueCallbackData != "" {
+        messageID, err := s.repository.GetMessageIDByExternalRef(ctx, event.BizOpaqueCallbackData)
+        if err != nil {
+            s.metrics.IncCounter("external_ref_unmatched")
+            return fmt.Errorf("failed to match external reference: %w", err)
+        }
+        event.MessageID = messageID
+    }
+
+    if event.MessageID == "" || event.Status == "" {
+        return ErrInvalidWebhookEvent
+    }
+
+    conversation, pricing, err := event.ParseConversation()
+    if err != nil {
+        s.metrics.IncCounter("webhook_parse_failed")
+        return fmt.Errorf("failed to parse conversation: %w", err)
+    }
+
+    status, ok := webhookStatuses[event.Status]
+    if !ok {
+        s.metrics.IncCounter("webhook_status_unknown")
+        return fmt.Errorf("%w: unknown status %q", ErrInvalidWebhookEvent, event.Status)
+    }
+
+    updated, err := s.repository.UpdateStatusWithReason(ctx, event.MessageID, status, "webhook")
+    if err != nil {
+        s.metrics.IncCounter("status_update_failed")
+        return fmt.Errorf("failed to update message status: %w", err)
+    }
+    if !updated {
+        // A status older than the stored one arrived late; the message did not change
+        s.metrics.IncCounter("status_update_stale")
+    }
+
+    // Conversation and pricing are needed for cost reconciliation against the WhatsApp invoice
+    if conversation != nil || pricing != nil {
+        if err := s.repository.StoreConversation(ctx, event.MessageID, conversation, pricing); err != nil {
+            s.metrics.IncCounter("conversation_store_failed")
+            return fmt.Errorf("failed to store conversation: %w", err)
+        }
+        s.metrics.IncCounter("conversation_recorded")
+    }
+
+    if updated {
+        s.notifyCallback(ctx, event)
+    }
+    return nil
+}
+
+// notifyCallback posts the status change to the message's own callback URL, if it has one
+func (s *WhatsAppService) notifyCallback(ctx context.Context, event *types.WebhookEvent) {
+    s.mu.Lock()
+    dispatcher := s.callbacks
+    s.mu.Unlock()
+    if dispatcher == nil {
+        return
+    }
+
+    callbackURL, externalRef, err := s.repository.GetCallbackTarget(ctx, event.MessageID)
+    if err != nil {
+        s.metrics.IncCounter("callback_lookup_failed")
+        return
+    }
+    if callbackURL == "" {
+        return
+    }
+
+    notification := CallbackNotification{
+        MessageID:   event.MessageID,
+        ExternalRef: externalRef,
+        Status:      string(event.Status),
+        Timestamp:   event.Timestamp,
+    }
+
+    // Delivery outlives the webhook request, so it must not inherit its cancellation
+    dispatcher.DispatchAsync(context.WithoutCancel(ctx), callbackURL, notification, func(err error) {
+        s.metrics.IncCounter("callback_delivery_failed")
+    })
+    s.metrics.IncCounter("callback_dispatched")
+}
+
 func (s *WhatsAppService) processWithRetry(ctx context.Context, message *types.Message) error {
     timer := s.metrics.StartTimer("message_processing")
     defer timer.Stop()
@@ -199,6 +483,9 @@ func (s *WhatsAppService) processWithRetry(ctx context.Context, message *types.M
 
 func (s *WhatsAppService) processSingleMessage(ctx context.Context, message *types.Message) error {
     resp, err := s.client.SendMessage(ctx, message)
+    if err != nil && types.IsTemplateUnavailable(err) {
+        resp, err = s.sendPlainTextFallback(ctx, message, err)
+    }
     if err != nil {
         s.metrics.IncCounter("send_failed")
         return fmt.Errorf("failed to send message: %w", err)
@@ -220,6 +507,39 @@ func (s *WhatsAppService) processSingleMessage(ctx context.Context, message *typ
     return nil
 }
 
+// sendPlainTextFallback sends the template's plain-text fallback in place of an unavailable
+// template. Free-form text is only deliverable inside the customer service window, so
+// outside it, or without a fallback, the original template error is returned.
+func (s *WhatsAppService) sendPlainTextFallback(ctx context.Context, message *types.Message, templateErr error) (*types.APIResponse, error) {
+    if message.Template == nil || message.Template.PlainTextFallback == "" {
+        return nil, templateErr
+    }
+
+    lastInbound, err := s.repository.GetLastInboundAt(ctx, message.To)
+    if err != nil {
+        s.metrics.IncCounter("template_fallback_failed")
+        return nil, templateErr
+    }
+    if lastInbound == nil || time.Since(*lastInbound) > types.CustomerServiceWindow {
+        s.metrics.IncCounter("template_fallback_outside_window")
+        return nil, templateErr
+    }
+
+    fallback := *message
+    fallback.Type = types.MessageTypeText
+    fallback.Template = nil
+    fallback.Content = types.MessageContent{Text: message.Template.PlainTextFallback}
+
+    resp, err := s.client.SendMessage(ctx, &fallback)
+    if err != nil {
+        s.metrics.IncCounter("template_fallback_failed")
+        return nil, err
+    }
+
+    s.metrics.IncCounter("template_fallback_sent")
+    return resp, nil
+}
+
 func (s *WhatsAppService) validateMessage(message *types.Message) error {
     if message == nil {
         return ErrInvalidMessage
@@ -234,16 +554,14 @@ func (s *WhatsAppService) validateMessage(message *types.Message) error {
 }
 
 func (s *WhatsAppService) calculateBackoff(attempt int) time.Duration {
-    backoff := defaultRetryDelay * time.Duration(1<<uint(attempt))
-    if backoff > 30*time.Second {
-        backoff = 30 * time.Second
-    }
-    return backoff
+    return client.Backoff(defaultRetryDelay, maxRetryDelay, attempt)
 }
 
 func (s *WhatsAppService) processMessages(ctx context.Context) {
     ticker := time.NewTicker(5 * time.Second)
     defer ticker.Stop()
+    reaper := time.NewTicker(staleReapInterval)
+    defer reaper.Stop()
 
     for {
         select {
@@ -253,6 +571,11 @@ func (s *WhatsAppService) processMessages(ctx context.Context) {
             if err := s.ProcessPendingMessages(ctx); err != nil {
                 s.metrics.IncCounter("batch_processing_failed")
             }
+        case <-reaper.C:
+            // Release messages claimed by workers that died mid-send
+            if _, err := s.repository.ResetStaleProcessing(ctx, staleProcessingTimeout); err != nil {
+                s.metrics.IncCounter("stale_reset_failed")
+            }
         }
     }
 }
\ No newline at end of file
@@ -0,0 +1,38 @@
+// Package telemetry links Prometheus metrics to distributed traces through exemplars
+// Version: go1.21
+package telemetry
+
+import (
+    "context"
+
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+    "go.opentelemetry.io/otel/trace"                 // v1.19.0
+)
+
+// traceIDLabel is the exemplar label carrying the trace ID, as expected by Grafana and Tempo
+const traceIDLabel = "trace_id"
+
+// TraceID returns the ID of the sampled trace active in ctx, or "" when there is none.
+// OpenTracing spans started through the OpenTelemetry bridge are found here as well.
+func TraceID(ctx context.Context) string {
+    if ctx == nil {
+        return ""
+    }
+    spanContext := trace.SpanContextFromContext(ctx)
+    if !spanContext.IsValid() || !spanContext.IsSampled() {
+        return ""
+    }
+    return spanContext.TraceID().String()
+}
+
+// ObserveWithTraceExemplar records value on observer, attaching the active trace ID as an
+// exemplar when ctx carries a sampled span and the observer supports exemplars
+func ObserveWithTraceExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+    if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+        if traceID := TraceID(ctx); traceID != "" {
+            exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{traceIDLabel: traceID})
+            return
+        }
+    }
+    observer.Observe(value)
+}
@@ -0,0 +1,296 @@
+// Package utils provides localization of validation errors for the WhatsApp message service
+// Version: go1.21
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when no requested locale has a registered catalog
+const DefaultLocale = "en"
+
+// Stable validation error codes
+const (
+	CodeMessageRequired          = "message_required"
+	CodeContentRequired          = "content_required"
+	CodePhoneRequired            = "phone_required"
+	CodePhoneInvalidFormat       = "phone_invalid_format"
+	CodePhoneRegionUnknown       = "phone_region_unknown"
+	CodeTemplateRequired         = "template_required"
+	CodeTemplateNameRequired     = "template_name_required"
+	CodeTemplateLanguageRequired = "template_language_required"
+	CodeTemplateNoComponents     = "template_components_required"
+	CodeComponentTypeRequired    = "component_type_required"
+	CodeComponentTypeUnsupported = "component_type_unsupported"
+	CodeComponentDuplicate       = "component_duplicate"
+	CodeTemplateBodyRequired     = "template_body_required"
+	CodeTemplateTooManyParts     = "template_too_many_components"
+	CodeParameterTypeRequired    = "parameter_type_required"
+	CodeParameterTooLong         = "parameter_too_long"
+	CodeParameterTooShort        = "parameter_too_short"
+	CodeParameterPatternMismatch = "parameter_pattern_mismatch"
+	CodeParameterFormatUnknown   = "parameter_format_unknown"
+	CodeParameterCurrency        = "parameter_currency_invalid"
+	CodeParameterDateTime        = "parameter_date_time_invalid"
+	CodePlaceholderGap           = "template_placeholder_gap"
+	CodePlaceholderDuplicate     = "template_placeholder_duplicate"
+	CodeParameterCount           = "template_parameter_count"
+	CodeParameterMissing         = "template_parameter_missing"
+	CodeTextTooLong              = "text_too_long"
+	CodeMediaURLRequired         = "media_url_required"
+	CodeMediaTypeRequired        = "media_type_required"
+	CodeMediaTypeUnsupported     = "media_type_unsupported"
+	CodeMediaTooLarge            = "media_too_large"
+	CodeMediaHashRequired        = "media_hash_required"
+	CodeMediaRemoteType          = "media_remote_type_mismatch"
+	CodeMediaRemoteSize          = "media_remote_size_mismatch"
+	CodeMediaRemoteLength        = "media_remote_length_unknown"
+	CodeBoldRangeInvalid         = "bold_range_invalid"
+	CodeItalicRangeInvalid       = "italic_range_invalid"
+	CodeStrikeRangeInvalid       = "strikethrough_range_invalid"
+	CodeLinkRangeInvalid         = "link_range_invalid"
+	CodeLinkURLInvalid           = "link_url_invalid"
+	CodeScheduleInPast           = "schedule_in_past"
+	CodeScheduleTooFar           = "schedule_too_far"
+	CodePayloadTooLarge          = "payload_too_large"
+	CodeAddressCountry           = "address_country_unsupported"
+	CodeAddressBodyRequired      = "address_body_required"
+	CodeAddressSavedIDRequired   = "address_saved_id_required"
+	CodeAddressFieldRequired     = "address_field_required"
+	CodeInteractiveType          = "interactive_type_unsupported"
+	CodeInteractiveBodyRequired  = "interactive_body_required"
+	CodeInteractiveButtonCount   = "interactive_button_count"
+	CodeInteractiveListRowCount  = "interactive_list_row_count"
+	CodeInteractiveListButton    = "interactive_list_button_required"
+	CodeInteractiveOptionID      = "interactive_option_id_required"
+	CodeInteractiveOptionTitle   = "interactive_option_title_invalid"
+	CodeInteractiveDuplicateID   = "interactive_duplicate_id"
+	CodeReactionMessageID        = "reaction_message_id_required"
+	CodeReactionEmoji            = "reaction_emoji_invalid"
+	CodeLocationLatitude         = "location_latitude_out_of_range"
+	CodeLocationLongitude        = "location_longitude_out_of_range"
+	CodeLocationZero             = "location_zero_coordinates"
+	CodeContactsCount            = "contacts_count"
+	CodeContactNameRequired      = "contact_name_required"
+	CodeContactPhoneRequired     = "contact_phone_required"
+	CodeReplyToInvalid           = "reply_to_invalid"
+)
+
+// ValidationError is a validation failure identified by a stable code that can be rendered in any registered locale
+type ValidationError struct {
+	Code string
+	Args []interface{}
+	Err  error
+}
+
+// Error renders the validation error in the default locale
+func (e *ValidationError) Error() string {
+	return e.Localize(DefaultLocale)
+}
+
+// Unwrap returns the validation category, such as ErrInvalidTemplate
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Localize renders the validation error in the given locale, falling back to the default locale
+func (e *ValidationError) Localize(locale string) string {
+	format, ok := lookupMessage(locale, e.Code)
+	if !ok {
+		return e.Code
+	}
+	if len(e.Args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, e.Args...)
+}
+
+// newValidationError creates a coded validation error within the given category
+func newValidationError(code string, category error, args ...interface{}) *ValidationError {
+	return &ValidationError{Code: code, Args: args, Err: category}
+}
+
+var (
+	catalogMu sync.RWMutex
+
+	// messageCatalogs maps locale to error code to a fmt format string
+	messageCatalogs = map[string]map[string]string{
+		DefaultLocale: {
+			CodeMessageRequired:          "message cannot be nil",
+			CodeContentRequired:          "message must contain either content or template",
+			CodePhoneRequired:            "phone number cannot be empty",
+			CodePhoneInvalidFormat:       "phone number must match E.164 format",
+			CodePhoneRegionUnknown:       "phone number has no country code and default region %q is unknown",
+			CodeTemplateRequired:         "template cannot be nil",
+			CodeTemplateNameRequired:     "template name is required",
+			CodeTemplateLanguageRequired: "template language is required",
+			CodeTemplateNoComponents:     "template must have at least one component",
+			CodeComponentTypeRequired:    "component type is required",
+			CodeComponentTypeUnsupported: "unsupported component type %q",
+			CodeComponentDuplicate:       "template may contain at most one %s component",
+			CodeTemplateBodyRequired:     "template must contain exactly one BODY component",
+			CodeTemplateTooManyParts:     "template has %d components, maximum is %d",
+			CodeParameterTypeRequired:    "parameter type is required",
+			CodeParameterTooLong:         "parameter value exceeds maximum length",
+			CodeParameterTooShort:        "parameter value below minimum length",
+			CodeParameterPatternMismatch: "parameter value does not match required pattern",
+			CodeParameterFormatUnknown:   "unsupported parameter format %q",
+			CodeParameterCurrency:        "currency parameter %q must be an amount and a 3-letter currency code, e.g. \"12.50 USD\"",
+			CodeParameterDateTime:        "date_time parameter %q must be an RFC 3339 timestamp, a YYYY-MM-DD date or Unix seconds",
+			CodePlaceholderGap:           "%s placeholders must be numbered from {{1}} without gaps, {{%d}} is missing",
+			CodePlaceholderDuplicate:     "%s placeholder {{%d}} appears more than once",
+			CodeParameterCount:           "template expects %d parameters, got %d",
+			CodeParameterMissing:         "no value supplied for template parameter {{%d}}",
+			CodeTextTooLong:              "message text exceeds maximum length",
+			CodeMediaURLRequired:         "media URL is required",
+			CodeMediaTypeRequired:        "media type is required",
+			CodeMediaTypeUnsupported:     "unsupported media type",
+			CodeMediaTooLarge:            "%s media exceeds the %d byte size limit",
+			CodeMediaHashRequired:        "media hash is required for verification",
+			CodeMediaRemoteType:          "media URL serves %q, not the declared %q",
+			CodeMediaRemoteSize:          "media URL serves %d bytes, not the declared %d",
+			CodeMediaRemoteLength:        "media URL did not report its size",
+			CodeBoldRangeInvalid:         "invalid bold text range",
+			CodeItalicRangeInvalid:       "invalid italic text range",
+			CodeStrikeRangeInvalid:       "invalid strikethrough text range",
+			CodeLinkRangeInvalid:         "invalid link text range",
+			CodeLinkURLInvalid:           "invalid link URL format",
+			CodeScheduleInPast:           "cannot schedule message in the past",
+			CodeScheduleTooFar:           "schedule time exceeds maximum allowed range",
+			CodePayloadTooLarge:          "message payload is %d bytes, maximum is %d bytes",
+			CodeAddressCountry:           "address messages are not supported for country %q",
+			CodeAddressBodyRequired:      "address message requires body text",
+			CodeAddressSavedIDRequired:   "saved address ID is required",
+			CodeAddressFieldRequired:     "saved address %q is missing required field %q",
+			CodeInteractiveType:          "unsupported interactive message type %q",
+			CodeInteractiveBodyRequired:  "interactive message requires body text",
+			CodeInteractiveButtonCount:   "button messages need 1 to %d reply buttons, got %d",
+			CodeInteractiveListRowCount:  "list messages need 1 to %d rows, got %d",
+			CodeInteractiveListButton:    "list messages require a list button label",
+			CodeInteractiveOptionID:      "every button and list row requires an ID",
+			CodeInteractiveOptionTitle:   "button or list row %q title must be 1 to %d characters",
+			CodeInteractiveDuplicateID:   "button and list row IDs must be unique, %q is repeated",
+			CodeReactionMessageID:        "reaction requires the ID of the message reacted to",
+			CodeReactionEmoji:            "reaction must be a single emoji, got %q",
+			CodeLocationLatitude:         "latitude must be between -90 and 90, got %g",
+			CodeLocationLongitude:        "longitude must be between -180 and 180, got %g",
+			CodeLocationZero:             "location coordinates 0,0 are not allowed",
+			CodeContactsCount:            "contacts messages need 1 to %d contacts, got %d",
+			CodeContactNameRequired:      "contact %d requires a formatted name",
+			CodeContactPhoneRequired:     "contact %q requires at least one phone number",
+			CodeReplyToInvalid:           "reply_to %q is not a WhatsApp message ID",
+		},
+	}
+)
+
+// RegisterMessageCatalog adds or extends the validation messages for a locale.
+// Messages are fmt format strings keyed by error code; missing codes fall back to the default locale.
+func RegisterMessageCatalog(locale string, messages map[string]string) {
+	locale = normalizeLocale(locale)
+	if locale == "" {
+		return
+	}
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	catalog, ok := messageCatalogs[locale]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		messageCatalogs[locale] = catalog
+	}
+	for code, message := range messages {
+		catalog[code] = message
+	}
+}
+
+// LocalizeError renders err in the given locale if it is a validation error, otherwise returns err.Error()
+func LocalizeError(err error, locale string) string {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Localize(locale)
+	}
+	return err.Error()
+}
+
+// ResolveLocale picks the best registered locale for an Accept-Language header value
+func ResolveLocale(acceptLanguage string) string {
+	type candidate struct {
+		locale string
+		weight float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := normalizeLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					weight = q
+				}
+			}
+		}
+		if weight > 0 {
+			candidates = append(candidates, candidate{locale: locale, weight: weight})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].weight > candidates[j].weight
+	})
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	for _, c := range candidates {
+		if _, ok := messageCatalogs[c.locale]; ok {
+			return c.locale
+		}
+		if base := baseLanguage(c.locale); base != c.locale {
+			if _, ok := messageCatalogs[base]; ok {
+				return base
+			}
+		}
+	}
+
+	return DefaultLocale
+}
+
+// lookupMessage finds the message format for a code, trying the locale, its base language and the default locale
+func lookupMessage(locale, code string) (string, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	locale = normalizeLocale(locale)
+	for _, l := range []string{locale, baseLanguage(locale), DefaultLocale} {
+		if message, ok := messageCatalogs[l][code]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// normalizeLocale lowercases a locale tag and uses '-' as the region separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// baseLanguage returns the language part of a locale tag, e.g. "pt" for "pt-br"
+func baseLanguage(locale string) string {
+	if i := strings.Index(locale, "-"); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
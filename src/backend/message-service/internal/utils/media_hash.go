@@ -0,0 +1,161 @@
+// Package utils provides media integrity verification for the WhatsApp message service
+// Version: go1.21
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourdomain/message-service/pkg/whatsapp/types" // go1.21
+)
+
+var (
+	// ErrMediaHashMismatch is returned when fetched media does not match its declared hash
+	ErrMediaHashMismatch = errors.New("media hash mismatch")
+
+	// MediaHashClient fetches media for hash verification; replace it to customize transport or timeouts
+	MediaHashClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// sha256HashPrefix is an optional algorithm prefix accepted on MediaHash values
+const sha256HashPrefix = "sha256:"
+
+var (
+	// remoteMedia selects what ValidateMessageContext checks against the media itself
+	remoteMedia   remoteMediaSettings
+	remoteMediaMu sync.RWMutex
+)
+
+type remoteMediaSettings struct {
+	enabled    bool
+	verifyHash bool
+}
+
+// SetRemoteMediaValidation makes ValidateMessageContext check media against the file its
+// URL serves, and verify the file's hash too when verifyHash is set. It is off by default,
+// leaving only the local checks of ValidateMediaContent.
+func SetRemoteMediaValidation(enabled, verifyHash bool) {
+	remoteMediaMu.Lock()
+	defer remoteMediaMu.Unlock()
+	remoteMedia = remoteMediaSettings{enabled: enabled, verifyHash: verifyHash}
+}
+
+func getRemoteMediaValidation() remoteMediaSettings {
+	remoteMediaMu.RLock()
+	defer remoteMediaMu.RUnlock()
+	return remoteMedia
+}
+
+// ValidateMessageContext validates msg like ValidateMessage and, when remote media
+// validation is enabled, checks its media against the file its URL serves
+func ValidateMessageContext(ctx context.Context, msg *types.Message) error {
+	if err := ValidateMessage(msg); err != nil {
+		return err
+	}
+	if msg.Content.MediaURL == "" || !getRemoteMediaValidation().enabled {
+		return nil
+	}
+	return ValidateMediaRemote(ctx, &msg.Content)
+}
+
+// ValidateMediaRemote checks media against the file its URL serves: a HEAD request must
+// report a supported Content-Type matching MediaType and a Content-Length within the size
+// limit and equal to MediaSize when that is set. With hash verification enabled the file
+// is also downloaded and checked against MediaHash.
+func ValidateMediaRemote(ctx context.Context, content *types.MessageContent) error {
+	if err := ValidateMediaContent(content); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, content.MediaURL, nil)
+	if err != nil {
+		return fmt.Errorf("create media request: %w", err)
+	}
+
+	resp, err := MediaHashClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("inspect media: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("inspect media: unexpected status %d", resp.StatusCode)
+	}
+
+	served, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !validMediaTypes[served] {
+		return newValidationError(CodeMediaTypeUnsupported, ErrInvalidMedia)
+	}
+	if served != content.MediaType {
+		return newValidationError(CodeMediaRemoteType, ErrInvalidMedia, served, content.MediaType)
+	}
+
+	if resp.ContentLength < 0 {
+		return newValidationError(CodeMediaRemoteLength, ErrInvalidMedia)
+	}
+	if limit := mediaSizeLimit(served); resp.ContentLength > limit {
+		return newValidationError(CodeMediaTooLarge, ErrInvalidMedia, served, limit)
+	}
+	if content.MediaSize > 0 && resp.ContentLength != content.MediaSize {
+		return newValidationError(CodeMediaRemoteSize, ErrInvalidMedia, resp.ContentLength, content.MediaSize)
+	}
+
+	if getRemoteMediaValidation().verifyHash {
+		return VerifyMediaHash(ctx, content)
+	}
+	return nil
+}
+
+// VerifyMediaHash fetches the media referenced by content and checks its sha256 digest
+// against content.MediaHash. The download is bounded by the size limit of the media type.
+// Verification costs a full download, so callers opt in explicitly; ValidateMediaContent
+// only checks that a hash is present.
+func VerifyMediaHash(ctx context.Context, content *types.MessageContent) error {
+	if err := ValidateMediaContent(content); err != nil {
+		return err
+	}
+
+	expected := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(content.MediaHash), sha256HashPrefix))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, content.MediaURL, nil)
+	if err != nil {
+		return fmt.Errorf("create media request: %w", err)
+	}
+
+	resp, err := MediaHashClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch media: unexpected status %d", resp.StatusCode)
+	}
+
+	// Read one byte past the limit so oversized media is detected rather than silently truncated
+	limit := mediaSizeLimit(content.MediaType)
+	hasher := sha256.New()
+	n, err := io.Copy(hasher, io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return fmt.Errorf("read media: %w", err)
+	}
+	if n > limit {
+		return newValidationError(CodeMediaTooLarge, ErrInvalidMedia, content.MediaType, limit)
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if actual != expected {
+		return fmt.Errorf("%w: expected %s, got %s", ErrMediaHashMismatch, expected, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+// Package utils provides phone number normalization for the WhatsApp message service
+// Version: go1.21
+package utils
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	// regionCallingCodes maps ISO 3166 region codes to their country calling codes
+	regionCallingCodes = map[string]string{
+		"US": "1", "CA": "1", "GB": "44", "IE": "353", "DE": "49", "FR": "33",
+		"ES": "34", "IT": "39", "NL": "31", "BE": "32", "PT": "351", "CH": "41",
+		"AT": "43", "SE": "46", "NO": "47", "DK": "45", "FI": "358", "PL": "48",
+		"IN": "91", "SG": "65", "MY": "60", "ID": "62", "PH": "63", "TH": "66",
+		"AU": "61", "NZ": "64", "JP": "81", "KR": "82", "CN": "86", "HK": "852",
+		"BR": "55", "MX": "52", "AR": "54", "CO": "57", "CL": "56", "PE": "51",
+		"ZA": "27", "NG": "234", "KE": "254", "EG": "20", "AE": "971", "SA": "966",
+		"IL": "972", "TR": "90", "PK": "92", "BD": "880",
+	}
+
+	// phoneSeparators are the formatting characters stripped from raw numbers
+	phoneSeparators = " \t-.()/"
+
+	// defaultPhoneRegion is applied by ValidateMessage to numbers without a country code
+	defaultPhoneRegion   string
+	defaultPhoneRegionMu sync.RWMutex
+)
+
+// SetDefaultPhoneRegion sets the region ValidateMessage assumes for numbers written without
+// a country code. The region is an ISO 3166 code such as "US" or a calling code such as "44".
+func SetDefaultPhoneRegion(region string) {
+	defaultPhoneRegionMu.Lock()
+	defer defaultPhoneRegionMu.Unlock()
+	defaultPhoneRegion = region
+}
+
+func getDefaultPhoneRegion() string {
+	defaultPhoneRegionMu.RLock()
+	defer defaultPhoneRegionMu.RUnlock()
+	return defaultPhoneRegion
+}
+
+// NormalizePhoneNumber converts a loosely formatted phone number to E.164. Separators such
+// as spaces, dashes, dots and parentheses are removed and a 00 international prefix becomes
+// +. Numbers without either get the calling code of defaultRegion, an ISO 3166 code such as
+// "US" or a calling code such as "44", after dropping a national trunk prefix. The result
+// is checked with ValidatePhoneNumber.
+func NormalizePhoneNumber(raw, defaultRegion string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", newValidationError(CodePhoneRequired, ErrInvalidPhoneNumber)
+	}
+
+	international := strings.HasPrefix(raw, "+")
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(phoneSeparators, r), r == '+' && i == 0:
+		default:
+			return "", newValidationError(CodePhoneInvalidFormat, ErrInvalidPhoneNumber)
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	default:
+		callingCode, ok := callingCode(defaultRegion)
+		if !ok {
+			return "", newValidationError(CodePhoneRegionUnknown, ErrInvalidPhoneNumber, defaultRegion)
+		}
+		number = callingCode + nationalNumber(number, callingCode)
+	}
+
+	normalized := "+" + number
+	if _, err := ValidatePhoneNumber(normalized); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// callingCode resolves a region code or a calling code to a calling code
+func callingCode(region string) (string, bool) {
+	region = strings.TrimPrefix(strings.TrimSpace(region), "+")
+	if region == "" {
+		return "", false
+	}
+	if code, ok := regionCallingCodes[strings.ToUpper(region)]; ok {
+		return code, true
+	}
+	for _, r := range region {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return region, true
+}
+
+// nationalNumber drops the trunk prefix from a nationally formatted number: a leading 0 in
+// most regions, or the leading 1 of an 11-digit North American number
+func nationalNumber(number, callingCode string) string {
+	if callingCode == "1" {
+		if len(number) == 11 && strings.HasPrefix(number, "1") {
+			return number[1:]
+		}
+		return number
+	}
+	return strings.TrimPrefix(number, "0")
+}
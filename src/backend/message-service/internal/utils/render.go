@@ -0,0 +1,46 @@
+// Package utils provides template rendering previews for the WhatsApp message service
+// Version: go1.21
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yourdomain/message-service/pkg/whatsapp/types" // go1.21
+)
+
+// RenderTemplate returns the body text of tmpl with each {{n}} placeholder replaced by the
+// value of params["n"], as the recipient would see it. Placeholders are checked as in
+// template validation, and every placeholder must have a value; unused values are ignored.
+func RenderTemplate(tmpl *types.Template, params map[string]string) (string, error) {
+	if tmpl == nil {
+		return "", newValidationError(CodeTemplateRequired, ErrInvalidTemplate)
+	}
+
+	var body *types.TemplateComponent
+	for i := range tmpl.Components {
+		if strings.ToUpper(tmpl.Components[i].Type) == types.ComponentTypeBody {
+			body = &tmpl.Components[i]
+			break
+		}
+	}
+	if body == nil {
+		return "", newValidationError(CodeTemplateBodyRequired, ErrInvalidTemplate)
+	}
+
+	highest, err := countPlaceholders(body.Type, body.Text)
+	if err != nil {
+		return "", err
+	}
+	for n := 1; n <= highest; n++ {
+		if _, ok := params[strconv.Itoa(n)]; !ok {
+			return "", newValidationError(CodeParameterMissing, ErrInvalidTemplate, n)
+		}
+	}
+
+	return placeholderRegex.ReplaceAllStringFunc(body.Text, func(placeholder string) string {
+		// countPlaceholders has already checked every number parses
+		n, _ := strconv.Atoi(placeholderRegex.FindStringSubmatch(placeholder)[1])
+		return params[strconv.Itoa(n)]
+	}), nil
+}
@@ -0,0 +1,94 @@
+// Package utils provides splitting of over-long message content for the WhatsApp message service
+// Version: go1.21
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yourdomain/message-service/pkg/whatsapp/types" // go1.21
+)
+
+// maxCaptionLength is the longest media caption the API accepts
+const maxCaptionLength = 1024
+
+// sentenceEnds are the boundaries preferred when splitting text, best first
+var sentenceEnds = []string{"\n\n", "\n", ". ", "! ", "? "}
+
+// SplitLongMessage splits content whose text or caption is too long into parts that each fit
+// the API limits, to be sent in order. Text is split at sentence boundaries where possible,
+// then at spaces. A media caption is split separately: the media keeps the first part of
+// its caption, the rest follows as text, and then the message text. Formatting ranges do
+// not survive splitting and are dropped. Content that fits, and interactive, address,
+// reaction, location and contacts content, is returned as the only part.
+func SplitLongMessage(content types.MessageContent) []types.MessageContent {
+	if content.Interactive != nil || content.Address != nil || content.Reaction != nil || content.Location != nil || len(content.Contacts) > 0 {
+		return []types.MessageContent{content}
+	}
+
+	hasMedia := content.MediaURL != "" || content.MediaID != ""
+	if len(content.Text) <= maxMessageLength && (!hasMedia || len(content.Caption) <= maxCaptionLength) {
+		return []types.MessageContent{content}
+	}
+
+	var parts []types.MessageContent
+	textPart := func(text string) types.MessageContent {
+		return types.MessageContent{Text: text, RichText: content.RichText, PreviewURL: content.PreviewURL}
+	}
+
+	if hasMedia {
+		captions := splitText(content.Caption, maxCaptionLength)
+		media := content
+		media.Text = ""
+		media.Formatting = nil
+		media.Caption = ""
+		if len(captions) > 0 {
+			media.Caption = captions[0]
+			captions = captions[1:]
+		}
+		parts = append(parts, media)
+		for _, caption := range captions {
+			parts = append(parts, textPart(caption))
+		}
+	}
+
+	for _, text := range splitText(content.Text, maxMessageLength) {
+		parts = append(parts, textPart(text))
+	}
+	return parts
+}
+
+// splitText breaks text into chunks of at most limit bytes, cutting at the last sentence
+// boundary in the second half of a chunk, else the last space, else mid-word
+func splitText(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	var chunks []string
+	for len(text) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		window := text[:cut]
+
+		boundary := -1
+		for _, end := range sentenceEnds {
+			if i := strings.LastIndex(window, end); i >= limit/2 {
+				boundary = i + len(strings.TrimRight(end, " \n"))
+				break
+			}
+		}
+		if boundary <= 0 {
+			boundary = strings.LastIndexAny(window, " \n\t")
+		}
+		if boundary <= 0 {
+			boundary = cut
+		}
+
+		chunks = append(chunks, strings.TrimSpace(text[:boundary]))
+		text = strings.TrimSpace(text[boundary:])
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
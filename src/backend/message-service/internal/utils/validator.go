@@ -3,8 +3,13 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,20 +25,88 @@ var (
 	ErrInvalidMedia       = errors.New("invalid media content")
 	ErrInvalidSchedule    = errors.New("invalid schedule time")
 	ErrInvalidTemplate    = errors.New("invalid template configuration")
+	ErrPayloadTooLarge    = errors.New("message payload too large")
+	ErrInvalidAddress     = errors.New("invalid address message")
+	ErrInvalidInteractive = errors.New("invalid interactive message")
+	ErrInvalidReaction    = errors.New("invalid reaction")
+	ErrInvalidLocation    = errors.New("invalid location")
+	ErrInvalidContacts    = errors.New("invalid contacts message")
 
 	// Global constants for validation rules
 	phoneNumberRegex    = `^\+[1-9]\d{1,14}$`
 	maxMessageLength    = 4096
-	maxMediaSize       = 16 * 1024 * 1024 // 16MB
 	validMediaTypes    = map[string]bool{
 		"image/jpeg":     true,
 		"image/png":      true,
+		"video/mp4":      true,
 		"application/pdf": true,
+		"application/msword": true,
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
 		"audio/mpeg":     true,
 		"audio/ogg":      true,
 	}
+
+	// Maximum media size in bytes the WhatsApp Business API accepts per media type
+	maxMediaSizes = map[string]int64{
+		"image/jpeg":      5 * 1024 * 1024,
+		"image/png":       5 * 1024 * 1024,
+		"video/mp4":       16 * 1024 * 1024,
+		"application/pdf": 100 * 1024 * 1024,
+		"application/msword": 100 * 1024 * 1024,
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document": 100 * 1024 * 1024,
+		"audio/mpeg":      16 * 1024 * 1024,
+		"audio/ogg":       16 * 1024 * 1024,
+	}
+
+	// mediaSizeLimits tightens maxMediaSizes for individual media types; see SetMediaSizeLimits
+	mediaSizeLimits   map[string]int64
+	mediaSizeLimitsMu sync.RWMutex
 	maxScheduleTimeRange = 30 * 24 * time.Hour // 30 days
 
+	// Template composition rules: at most one of each component type and exactly one body
+	maxTemplateComponents     = 4
+	allowedTemplateComponents = map[string]bool{
+		types.ComponentTypeHeader:  true,
+		types.ComponentTypeBody:    true,
+		types.ComponentTypeFooter:  true,
+		types.ComponentTypeButtons: true,
+	}
+
+	// Accepted parameter formats: currency is an amount with an ISO 4217 code in either
+	// order, date_time is any of the listed layouts or Unix seconds
+	currencyAmountRegex = regexp.MustCompile(`^\d+(\.\d{1,3})?$`)
+	currencyCodeRegex   = regexp.MustCompile(`^[A-Z]{3}$`)
+	dateTimeLayouts     = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+	// WhatsApp message IDs quoted by replies: "wamid." followed by a base64 identifier
+	replyToRegex = regexp.MustCompile(`^wamid\.[A-Za-z0-9+/=_-]{8,256}$`)
+
+	// Numbered {{n}} placeholders in template component text
+	placeholderRegex = regexp.MustCompile(`\{\{\s*(\d+)\s*\}\}`)
+
+	// Maximum serialized message size in bytes per message type, with a default for other types
+	defaultMaxPayloadSize = 64 * 1024
+	maxPayloadSizes       = map[string]int{
+		types.MessageTypeText:     8 * 1024,
+		types.MessageTypeMedia:    8 * 1024,
+		types.MessageTypeTemplate: 32 * 1024,
+	}
+
+	// Countries supporting address messages and the address fields each requires
+	addressRequiredFields = map[string][]string{
+		"IN": {"name", "phone_number", "in_pin_code", "address", "city", "state"},
+		"SG": {"name", "phone_number", "sg_post_code", "address"},
+	}
+
+	// Interactive message limits imposed by the WhatsApp Business API
+	maxReplyButtons       = 3
+	maxListRows           = 10
+	maxButtonTitleLength  = 20
+	maxListRowTitleLength = 24
+
+	// Contact cards the WhatsApp Business API accepts in one contacts message
+	maxContactCards = 257
+
 	// Thread-safe regex cache
 	compiledRegexCache sync.Map
 )
@@ -56,17 +129,19 @@ func getCompiledRegex(pattern string) (*regexp.Regexp, error) {
 // ValidateMessage performs comprehensive validation of a WhatsApp message
 func ValidateMessage(msg *types.Message) error {
 	if msg == nil {
-		return errors.New("message cannot be nil")
+		return newValidationError(CodeMessageRequired, ErrInvalidMessage)
 	}
 
-	// Validate phone number
-	if valid, err := ValidatePhoneNumber(msg.To); !valid {
+	// Normalize the recipient to E.164 before validating it
+	normalized, err := NormalizePhoneNumber(msg.To, getDefaultPhoneRegion())
+	if err != nil {
 		return errors.Join(ErrInvalidPhoneNumber, err)
 	}
+	msg.To = normalized
 
 	// Validate message content or template
-	if msg.Template == nil && msg.Content.Text == "" && msg.Content.MediaURL == "" {
-		return errors.New("message must contain either content or template")
+	if msg.Template == nil && msg.Content.Text == "" && msg.Content.MediaURL == "" && msg.Content.Address == nil && msg.Content.Interactive == nil && msg.Content.Reaction == nil && msg.Content.Location == nil && len(msg.Content.Contacts) == 0 {
+		return newValidationError(CodeContentRequired, ErrInvalidContent)
 	}
 
 	// Validate content if present
@@ -88,13 +163,49 @@ func ValidateMessage(msg *types.Message) error {
 		}
 	}
 
+	return ValidatePayloadSize(msg)
+}
+
+// ValidatePayloadSize checks the serialized message against the API size limit for its type
+func ValidatePayloadSize(msg *types.Message) error {
+	if msg == nil {
+		return newValidationError(CodeMessageRequired, ErrInvalidMessage)
+	}
+
+	size, err := serializedSize(msg)
+	if err != nil {
+		return errors.Join(ErrInvalidMessage, err)
+	}
+
+	limit, ok := maxPayloadSizes[msg.Type]
+	if !ok {
+		limit = defaultMaxPayloadSize
+	}
+	if size > limit {
+		return newValidationError(CodePayloadTooLarge, ErrPayloadTooLarge, size, limit)
+	}
+
 	return nil
 }
 
-// ValidatePhoneNumber validates a phone number format
+// serializedSize returns the length of msg encoded as JSON. HTML escaping is disabled so
+// text containing <, > or & is not inflated by six-byte \u003c escapes the API decodes anyway.
+func serializedSize(msg *types.Message) (int, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(msg); err != nil {
+		return 0, err
+	}
+	// Encode terminates the value with a newline that is not part of the payload
+	return len(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// ValidatePhoneNumber strictly validates a phone number as E.164 without normalizing it;
+// use NormalizePhoneNumber first to accept formatted numbers
 func ValidatePhoneNumber(phoneNumber string) (bool, error) {
 	if phoneNumber == "" {
-		return false, errors.New("phone number cannot be empty")
+		return false, newValidationError(CodePhoneRequired, ErrInvalidPhoneNumber)
 	}
 
 	regex, err := getCompiledRegex(phoneNumberRegex)
@@ -103,7 +214,7 @@ func ValidatePhoneNumber(phoneNumber string) (bool, error) {
 	}
 
 	if !regex.MatchString(phoneNumber) {
-		return false, errors.New("phone number must match E.164 format")
+		return false, newValidationError(CodePhoneInvalidFormat, ErrInvalidPhoneNumber)
 	}
 
 	return true, nil
@@ -112,19 +223,23 @@ func ValidatePhoneNumber(phoneNumber string) (bool, error) {
 // ValidateTemplate validates a message template and its parameters
 func ValidateTemplate(tmpl *types.Template) error {
 	if tmpl == nil {
-		return errors.New("template cannot be nil")
+		return newValidationError(CodeTemplateRequired, ErrInvalidTemplate)
 	}
 
 	if tmpl.Name == "" {
-		return errors.New("template name is required")
+		return newValidationError(CodeTemplateNameRequired, ErrInvalidTemplate)
 	}
 
 	if tmpl.Language == "" {
-		return errors.New("template language is required")
+		return newValidationError(CodeTemplateLanguageRequired, ErrInvalidTemplate)
 	}
 
 	if len(tmpl.Components) == 0 {
-		return errors.New("template must have at least one component")
+		return newValidationError(CodeTemplateNoComponents, ErrInvalidTemplate)
+	}
+
+	if len(tmpl.Components) > maxTemplateComponents {
+		return newValidationError(CodeTemplateTooManyParts, ErrInvalidTemplate, len(tmpl.Components), maxTemplateComponents)
 	}
 
 	for i, comp := range tmpl.Components {
@@ -133,13 +248,34 @@ func ValidateTemplate(tmpl *types.Template) error {
 		}
 	}
 
+	return validateTemplateComposition(tmpl.Components)
+}
+
+// validateTemplateComposition enforces 0-1 header, exactly 1 body, 0-1 footer and 0-1 buttons
+func validateTemplateComposition(components []types.TemplateComponent) error {
+	counts := make(map[string]int, len(components))
+	for _, comp := range components {
+		compType := strings.ToUpper(comp.Type)
+		if !allowedTemplateComponents[compType] {
+			return newValidationError(CodeComponentTypeUnsupported, ErrInvalidTemplate, comp.Type)
+		}
+		counts[compType]++
+		if counts[compType] > 1 {
+			return newValidationError(CodeComponentDuplicate, ErrInvalidTemplate, compType)
+		}
+	}
+
+	if counts[types.ComponentTypeBody] != 1 {
+		return newValidationError(CodeTemplateBodyRequired, ErrInvalidTemplate)
+	}
+
 	return nil
 }
 
 // validateTemplateComponent validates a template component and its parameters
 func validateTemplateComponent(comp *types.TemplateComponent, index int) error {
 	if comp.Type == "" {
-		return errors.New("component type is required")
+		return newValidationError(CodeComponentTypeRequired, ErrInvalidTemplate)
 	}
 
 	for _, param := range comp.Parameters {
@@ -148,22 +284,75 @@ func validateTemplateComponent(comp *types.TemplateComponent, index int) error {
 		}
 	}
 
+	if comp.Text != "" {
+		if err := validatePlaceholders(comp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validatePlaceholders checks that the {{n}} placeholders in a component's text are numbered
+// 1 to n without gaps or repeats and that one parameter is supplied for each
+func validatePlaceholders(comp *types.TemplateComponent) error {
+	highest, err := countPlaceholders(comp.Type, comp.Text)
+	if err != nil {
+		return err
+	}
+
+	if len(comp.Parameters) != highest {
+		return newValidationError(CodeParameterCount, ErrInvalidTemplate, highest, len(comp.Parameters))
+	}
 	return nil
 }
 
+// countPlaceholders returns the number of distinct {{n}} placeholders in text, checking they
+// are numbered 1 to n without gaps or repeats
+func countPlaceholders(compType, text string) (int, error) {
+	matches := placeholderRegex.FindAllStringSubmatch(text, -1)
+
+	seen := make(map[int]bool, len(matches))
+	highest := 0
+	for _, match := range matches {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 {
+			return 0, newValidationError(CodePlaceholderGap, ErrInvalidTemplate, compType, 1)
+		}
+		if seen[n] {
+			return 0, newValidationError(CodePlaceholderDuplicate, ErrInvalidTemplate, compType, n)
+		}
+		seen[n] = true
+		if n > highest {
+			highest = n
+		}
+	}
+
+	for n := 1; n <= highest; n++ {
+		if !seen[n] {
+			return 0, newValidationError(CodePlaceholderGap, ErrInvalidTemplate, compType, n)
+		}
+	}
+	return highest, nil
+}
+
 // validateTemplateParameter validates a template parameter
 func validateTemplateParameter(param *types.Parameter) error {
 	if param.Type == "" {
-		return errors.New("parameter type is required")
+		return newValidationError(CodeParameterTypeRequired, ErrInvalidTemplate)
+	}
+
+	if err := validateParameterFormat(param); err != nil {
+		return err
 	}
 
 	if param.Validation != nil {
 		if param.Validation.MaxLength > 0 && len(param.Value) > param.Validation.MaxLength {
-			return errors.New("parameter value exceeds maximum length")
+			return newValidationError(CodeParameterTooLong, ErrInvalidTemplate)
 		}
 
 		if param.Validation.MinLength > 0 && len(param.Value) < param.Validation.MinLength {
-			return errors.New("parameter value below minimum length")
+			return newValidationError(CodeParameterTooShort, ErrInvalidTemplate)
 		}
 
 		if param.Validation.Pattern != "" {
@@ -172,7 +361,7 @@ func validateTemplateParameter(param *types.Parameter) error {
 				return err
 			}
 			if !regex.MatchString(param.Value) {
-				return errors.New("parameter value does not match required pattern")
+				return newValidationError(CodeParameterPatternMismatch, ErrInvalidTemplate)
 			}
 		}
 	}
@@ -180,16 +369,62 @@ func validateTemplateParameter(param *types.Parameter) error {
 	return nil
 }
 
+// validateParameterFormat checks the parameter value against its declared WhatsApp format
+func validateParameterFormat(param *types.Parameter) error {
+	switch param.Format {
+	case "":
+		return nil
+	case types.ParameterFormatCurrency:
+		if !isValidCurrency(param.Value) {
+			return newValidationError(CodeParameterCurrency, ErrInvalidTemplate, param.Value)
+		}
+	case types.ParameterFormatDateTime:
+		if !isValidDateTime(param.Value) {
+			return newValidationError(CodeParameterDateTime, ErrInvalidTemplate, param.Value)
+		}
+	default:
+		return newValidationError(CodeParameterFormatUnknown, ErrInvalidTemplate, param.Format)
+	}
+	return nil
+}
+
+// isValidCurrency reports whether value is a non-negative amount and currency code, e.g. "12.50 USD"
+func isValidCurrency(value string) bool {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return false
+	}
+
+	amount, code := fields[0], fields[1]
+	if currencyCodeRegex.MatchString(amount) {
+		amount, code = code, amount
+	}
+	return currencyAmountRegex.MatchString(amount) && currencyCodeRegex.MatchString(code)
+}
+
+// isValidDateTime reports whether value parses as a supported timestamp
+func isValidDateTime(value string) bool {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateTimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && seconds > 0
+}
+
 // validateMessageContent validates the message content structure
 func validateMessageContent(content *types.MessageContent) error {
 	if content == nil {
-		return errors.New("content cannot be nil")
+		return newValidationError(CodeContentRequired, ErrInvalidContent)
 	}
 
 	// Validate text content
 	if content.Text != "" {
 		if len(content.Text) > maxMessageLength {
-			return errors.New("message text exceeds maximum length")
+			return newValidationError(CodeTextTooLong, ErrInvalidContent)
 		}
 
 		if content.RichText && content.Formatting != nil {
@@ -206,34 +441,345 @@ func validateMessageContent(content *types.MessageContent) error {
 		}
 	}
 
+	// Validate address request if present
+	if content.Address != nil {
+		if err := ValidateAddressContent(content); err != nil {
+			return err
+		}
+	}
+
+	// Validate interactive buttons or list if present
+	if content.Interactive != nil {
+		if err := ValidateInteractiveContent(content.Interactive); err != nil {
+			return err
+		}
+	}
+
+	// Validate reaction if present
+	if content.Reaction != nil {
+		if err := ValidateReactionContent(content.Reaction); err != nil {
+			return err
+		}
+	}
+
+	// Validate location if present
+	if content.Location != nil {
+		if err := ValidateLocationContent(content.Location); err != nil {
+			return err
+		}
+	}
+
+	// Validate the quoted message ID if present
+	if content.ReplyTo != "" && !replyToRegex.MatchString(content.ReplyTo) {
+		return newValidationError(CodeReplyToInvalid, ErrInvalidContent, content.ReplyTo)
+	}
+
+	// Validate contact cards if present
+	if len(content.Contacts) > 0 {
+		if err := ValidateContacts(content.Contacts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateInteractiveContent validates a reply-button or list message against the API
+// limits: at most 3 buttons or 10 list rows, titles within length, and unique option IDs
+func ValidateInteractiveContent(interactive *types.InteractiveContent) error {
+	if interactive == nil {
+		return newValidationError(CodeContentRequired, ErrInvalidContent)
+	}
+	if strings.TrimSpace(interactive.Body) == "" {
+		return newValidationError(CodeInteractiveBodyRequired, ErrInvalidInteractive)
+	}
+
+	seen := make(map[string]bool)
+	checkOption := func(id, title string, maxTitle int) error {
+		if id == "" {
+			return newValidationError(CodeInteractiveOptionID, ErrInvalidInteractive)
+		}
+		if title == "" || len([]rune(title)) > maxTitle {
+			return newValidationError(CodeInteractiveOptionTitle, ErrInvalidInteractive, id, maxTitle)
+		}
+		if seen[id] {
+			return newValidationError(CodeInteractiveDuplicateID, ErrInvalidInteractive, id)
+		}
+		seen[id] = true
+		return nil
+	}
+
+	switch interactive.Type {
+	case types.InteractiveTypeButton:
+		if len(interactive.Buttons) == 0 || len(interactive.Buttons) > maxReplyButtons {
+			return newValidationError(CodeInteractiveButtonCount, ErrInvalidInteractive, maxReplyButtons, len(interactive.Buttons))
+		}
+		for _, button := range interactive.Buttons {
+			if err := checkOption(button.ID, button.Title, maxButtonTitleLength); err != nil {
+				return err
+			}
+		}
+	case types.InteractiveTypeList:
+		if interactive.ListButton == "" {
+			return newValidationError(CodeInteractiveListButton, ErrInvalidInteractive)
+		}
+		rows := 0
+		for _, section := range interactive.Sections {
+			rows += len(section.Rows)
+		}
+		if rows == 0 || rows > maxListRows {
+			return newValidationError(CodeInteractiveListRowCount, ErrInvalidInteractive, maxListRows, rows)
+		}
+		for _, section := range interactive.Sections {
+			for _, row := range section.Rows {
+				if err := checkOption(row.ID, row.Title, maxListRowTitleLength); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return newValidationError(CodeInteractiveType, ErrInvalidInteractive, interactive.Type)
+	}
+
+	return nil
+}
+
+// ValidateReactionContent validates a reaction: it must name the message reacted to and
+// carry a single emoji, or no emoji to remove an earlier reaction
+func ValidateReactionContent(reaction *types.ReactionContent) error {
+	if reaction == nil {
+		return newValidationError(CodeContentRequired, ErrInvalidContent)
+	}
+	if reaction.MessageID == "" {
+		return newValidationError(CodeReactionMessageID, ErrInvalidReaction)
+	}
+	if reaction.Emoji != "" && !isSingleEmoji(reaction.Emoji) {
+		return newValidationError(CodeReactionEmoji, ErrInvalidReaction, reaction.Emoji)
+	}
+	return nil
+}
+
+// isSingleEmoji reports whether s is exactly one emoji grapheme: a pictograph with optional
+// presentation selector and skin tone, a ZWJ sequence of those, a keycap, a pair of
+// regional indicators forming a flag, or a tagged subdivision flag
+func isSingleEmoji(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return false
+	}
+
+	// Flags are exactly two regional indicators
+	if isRegionalIndicator(runes[0]) {
+		return len(runes) == 2 && isRegionalIndicator(runes[1])
+	}
+
+	// Keycaps are a digit, # or * with an optional selector and the enclosing keycap
+	if strings.ContainsRune("0123456789#*", runes[0]) {
+		rest := runes[1:]
+		if len(rest) > 0 && rest[0] == 0xFE0F {
+			rest = rest[1:]
+		}
+		return len(rest) == 1 && rest[0] == 0x20E3
+	}
+
+	// Subdivision flags are a black flag followed by tag characters and a cancel tag
+	if runes[0] == 0x1F3F4 && len(runes) > 2 && runes[len(runes)-1] == 0xE007F {
+		for _, r := range runes[1 : len(runes)-1] {
+			if r < 0xE0020 || r > 0xE007E {
+				return false
+			}
+		}
+		return true
+	}
+
+	// Otherwise one or more pictographs joined by ZWJ, each optionally followed by a
+	// presentation selector and a skin tone modifier
+	i := 0
+	for {
+		if i >= len(runes) || !isPictograph(runes[i]) {
+			return false
+		}
+		i++
+		if i < len(runes) && (runes[i] == 0xFE0F || runes[i] == 0xFE0E) {
+			i++
+		}
+		if i < len(runes) && runes[i] >= 0x1F3FB && runes[i] <= 0x1F3FF {
+			i++
+		}
+		if i == len(runes) {
+			return true
+		}
+		if runes[i] != 0x200D {
+			return false
+		}
+		i++
+	}
+}
+
+// isRegionalIndicator reports whether r is one of the letters used to build flag emoji
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isPictograph reports whether r falls in the blocks that hold emoji pictographs
+func isPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return !isRegionalIndicator(r) && (r < 0x1F3FB || r > 0x1F3FF)
+	case r >= 0x2600 && r <= 0x27BF,
+		r >= 0x2300 && r <= 0x23FF,
+		r >= 0x2B00 && r <= 0x2BFF,
+		r >= 0x2190 && r <= 0x21FF,
+		r >= 0x2934 && r <= 0x2935:
+		return true
+	}
+	switch r {
+	case 0x00A9, 0x00AE, 0x203C, 0x2049, 0x2122, 0x2139, 0x24C2, 0x25AA, 0x25AB, 0x25B6, 0x25C0, 0x3030, 0x303D, 0x3297, 0x3299:
+		return true
+	}
+	return false
+}
+
+// ValidateLocationContent validates a location: latitude must be within [-90, 90] and
+// longitude within [-180, 180], and 0,0 is rejected unless the location allows it
+func ValidateLocationContent(location *types.LocationContent) error {
+	if location == nil {
+		return newValidationError(CodeContentRequired, ErrInvalidContent)
+	}
+	if math.IsNaN(location.Latitude) || location.Latitude < -90 || location.Latitude > 90 {
+		return newValidationError(CodeLocationLatitude, ErrInvalidLocation, location.Latitude)
+	}
+	if math.IsNaN(location.Longitude) || location.Longitude < -180 || location.Longitude > 180 {
+		return newValidationError(CodeLocationLongitude, ErrInvalidLocation, location.Longitude)
+	}
+	if location.Latitude == 0 && location.Longitude == 0 && !location.AllowZero {
+		return newValidationError(CodeLocationZero, ErrInvalidLocation)
+	}
+	return nil
+}
+
+// ValidateContacts validates the cards of a contacts message: there must be at most
+// maxContactCards, and each needs a formatted name and at least one phone number
+func ValidateContacts(contacts []types.ContactCard) error {
+	if len(contacts) == 0 || len(contacts) > maxContactCards {
+		return newValidationError(CodeContactsCount, ErrInvalidContacts, maxContactCards, len(contacts))
+	}
+
+	for i, contact := range contacts {
+		name := strings.TrimSpace(contact.Name.FormattedName)
+		if name == "" {
+			return newValidationError(CodeContactNameRequired, ErrInvalidContacts, i+1)
+		}
+
+		hasPhone := false
+		for _, phone := range contact.Phones {
+			if strings.TrimSpace(phone.Phone) != "" {
+				hasPhone = true
+				break
+			}
+		}
+		if !hasPhone {
+			return newValidationError(CodeContactPhoneRequired, ErrInvalidContacts, name)
+		}
+	}
+
+	return nil
+}
+
+// ValidateAddressContent validates an address message: the country must support address
+// messages, the body text is required and every saved address must carry the fields that
+// country requires
+func ValidateAddressContent(content *types.MessageContent) error {
+	address := content.Address
+	if address == nil {
+		return newValidationError(CodeContentRequired, ErrInvalidContent)
+	}
+
+	country := strings.ToUpper(address.Country)
+	required, ok := addressRequiredFields[country]
+	if !ok {
+		return newValidationError(CodeAddressCountry, ErrInvalidAddress, address.Country)
+	}
+
+	if strings.TrimSpace(content.Text) == "" {
+		return newValidationError(CodeAddressBodyRequired, ErrInvalidAddress)
+	}
+
+	for _, saved := range address.SavedAddresses {
+		if saved.ID == "" {
+			return newValidationError(CodeAddressSavedIDRequired, ErrInvalidAddress)
+		}
+		for _, field := range required {
+			if strings.TrimSpace(saved.Value[field]) == "" {
+				return newValidationError(CodeAddressFieldRequired, ErrInvalidAddress, saved.ID, field)
+			}
+		}
+	}
+
 	return nil
 }
 
 // ValidateMediaContent validates media attachments
 func ValidateMediaContent(content *types.MessageContent) error {
 	if content.MediaURL == "" {
-		return errors.New("media URL is required")
+		return newValidationError(CodeMediaURLRequired, ErrInvalidMedia)
 	}
 
 	if content.MediaType == "" {
-		return errors.New("media type is required")
+		return newValidationError(CodeMediaTypeRequired, ErrInvalidMedia)
 	}
 
 	if !validMediaTypes[content.MediaType] {
-		return errors.New("unsupported media type")
+		return newValidationError(CodeMediaTypeUnsupported, ErrInvalidMedia)
 	}
 
-	if content.MediaSize > maxMediaSize {
-		return errors.New("media size exceeds maximum allowed size")
+	if limit := mediaSizeLimit(content.MediaType); content.MediaSize > limit {
+		return newValidationError(CodeMediaTooLarge, ErrInvalidMedia, content.MediaType, limit)
 	}
 
 	if content.MediaHash == "" {
-		return errors.New("media hash is required for verification")
+		return newValidationError(CodeMediaHashRequired, ErrInvalidMedia)
 	}
 
 	return nil
 }
 
+// SetMediaSizeLimits lowers the size limit of the given media types below the API maximum,
+// for example to control cost. Types not listed keep the API maximum. It rejects unknown
+// types and limits that are not positive or exceed the API maximum, leaving the current
+// limits unchanged.
+func SetMediaSizeLimits(limits map[string]int64) error {
+	for mediaType, limit := range limits {
+		max, ok := maxMediaSizes[mediaType]
+		if !ok {
+			return fmt.Errorf("unsupported media type %q", mediaType)
+		}
+		if limit <= 0 || limit > max {
+			return fmt.Errorf("size limit for %s must be between 1 and %d bytes, got %d", mediaType, max, limit)
+		}
+	}
+
+	overrides := make(map[string]int64, len(limits))
+	for mediaType, limit := range limits {
+		overrides[mediaType] = limit
+	}
+
+	mediaSizeLimitsMu.Lock()
+	defer mediaSizeLimitsMu.Unlock()
+	mediaSizeLimits = overrides
+	return nil
+}
+
+// mediaSizeLimit returns the size limit in bytes for a media type, or zero for unsupported types
+func mediaSizeLimit(mediaType string) int64 {
+	mediaSizeLimitsMu.RLock()
+	defer mediaSizeLimitsMu.RUnlock()
+	if limit, ok := mediaSizeLimits[mediaType]; ok {
+		return limit
+	}
+	return maxMediaSizes[mediaType]
+}
+
 // validateFormatting validates rich text formatting
 func validateFormatting(text string, formatting *types.MessageFormatting) error {
 	textLength := len(text)
@@ -241,31 +787,31 @@ func validateFormatting(text string, formatting *types.MessageFormatting) error
 	// Validate bold ranges
 	for _, bold := range formatting.Bold {
 		if !isValidTextRange(bold, textLength) {
-			return errors.New("invalid bold text range")
+			return newValidationError(CodeBoldRangeInvalid, ErrInvalidContent)
 		}
 	}
 
 	// Validate italic ranges
 	for _, italic := range formatting.Italic {
 		if !isValidTextRange(italic, textLength) {
-			return errors.New("invalid italic text range")
+			return newValidationError(CodeItalicRangeInvalid, ErrInvalidContent)
 		}
 	}
 
 	// Validate strikethrough ranges
 	for _, strike := range formatting.Strikethrough {
 		if !isValidTextRange(strike, textLength) {
-			return errors.New("invalid strikethrough text range")
+			return newValidationError(CodeStrikeRangeInvalid, ErrInvalidContent)
 		}
 	}
 
 	// Validate links
 	for _, link := range formatting.Links {
 		if !isValidTextRange(TextRange{Start: link.Start, Length: link.Length}, textLength) {
-			return errors.New("invalid link text range")
+			return newValidationError(CodeLinkRangeInvalid, ErrInvalidContent)
 		}
 		if !strings.HasPrefix(link.URL, "https://") && !strings.HasPrefix(link.URL, "http://") {
-			return errors.New("invalid link URL format")
+			return newValidationError(CodeLinkURLInvalid, ErrInvalidContent)
 		}
 	}
 
@@ -283,12 +829,12 @@ func ValidateScheduledTime(scheduleTime time.Time) error {
 
 	// Cannot schedule in the past
 	if scheduleTime.Before(now) {
-		return errors.New("cannot schedule message in the past")
+		return newValidationError(CodeScheduleInPast, ErrInvalidSchedule)
 	}
 
 	// Cannot schedule too far in the future
 	if scheduleTime.Sub(now) > maxScheduleTimeRange {
-		return errors.New("schedule time exceeds maximum allowed range")
+		return newValidationError(CodeScheduleTooFar, ErrInvalidSchedule)
 	}
 
 	return nil
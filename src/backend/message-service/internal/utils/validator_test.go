@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yourdomain/message-service/pkg/whatsapp/types"
+)
+
+// templateWith returns a template made of components of the given types
+func templateWith(componentTypes ...string) *types.Template {
+	components := make([]types.TemplateComponent, len(componentTypes))
+	for i, compType := range componentTypes {
+		components[i] = types.TemplateComponent{Type: compType, Index: i}
+	}
+	return &types.Template{Name: "order_update", Language: "en_US", Components: components}
+}
+
+func TestValidateTemplateComposition(t *testing.T) {
+	const (
+		header  = types.ComponentTypeHeader
+		body    = types.ComponentTypeBody
+		footer  = types.ComponentTypeFooter
+		buttons = types.ComponentTypeButtons
+	)
+
+	tests := []struct {
+		name     string
+		template *types.Template
+		code     string
+	}{
+		{"body only", templateWith(body), ""},
+		{"header and body", templateWith(header, body), ""},
+		{"body and footer", templateWith(body, footer), ""},
+		{"body and buttons", templateWith(body, buttons), ""},
+		{"every component once", templateWith(header, body, footer, buttons), ""},
+		{"lowercase types", templateWith("header", "body"), ""},
+		{"no components", templateWith(), CodeTemplateNoComponents},
+		{"missing body", templateWith(header, footer), CodeTemplateBodyRequired},
+		{"two bodies", templateWith(body, body), CodeComponentDuplicate},
+		{"two headers", templateWith(header, header, body), CodeComponentDuplicate},
+		{"two footers", templateWith(body, footer, footer), CodeComponentDuplicate},
+		{"two button sets", templateWith(body, buttons, buttons), CodeComponentDuplicate},
+		{"duplicate in another case", templateWith("body", body), CodeComponentDuplicate},
+		{"unsupported type", templateWith(body, "CAROUSEL"), CodeComponentTypeUnsupported},
+		{"over the component cap", templateWith(header, body, footer, buttons, footer), CodeTemplateTooManyParts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplate(tt.template)
+			if tt.code == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrInvalidTemplate)
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, tt.code, validationErr.Code)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Package whatsapp provides the interactive address message payload for the WhatsApp Business API
+// Version: go1.21
+package whatsapp
+
+// Interactive message identifiers used by address messages
+const (
+    interactiveTypeAddress  = "address_message"
+    interactiveTypeNfmReply = "nfm_reply"
+)
+
+// newAddressPayload renders an address message in the shape the API expects
+func newAddressPayload(message *Message) *interactiveMessagePayload {
+    return newInteractiveMessage(message, interactiveContent{
+        Type: interactiveTypeAddress,
+        Body: interactiveText{Text: message.Content.Text},
+        Action: interactiveAction{
+            Name:       interactiveTypeAddress,
+            Parameters: message.Content.Address,
+        },
+    })
+}
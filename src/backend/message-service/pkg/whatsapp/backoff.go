@@ -0,0 +1,100 @@
+// Package whatsapp provides jittered exponential backoff for retrying WhatsApp Business API calls
+// Version: go1.21
+package whatsapp
+
+import (
+    "errors"    // go1.21
+    "math/rand" // go1.21
+    "net/http"  // go1.21
+    "strconv"   // go1.21
+    "strings"   // go1.21
+    "time"      // go1.21
+)
+
+// defaultMaxRetryDelay caps the backoff between retries
+const defaultMaxRetryDelay = 30 * time.Second
+
+// Backoff returns how long to wait before retry attempt, counting from zero. The window
+// grows as base * 2^(attempt+1) up to max and the delay is drawn at random between base and
+// the top of that window, so clients that failed together do not retry together and none
+// retries sooner than base. A max of zero or less uses the default cap; a base at or above
+// the cap always waits the cap.
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+    return backoff(rand.Int63n, base, max, attempt)
+}
+
+// backoff implements Backoff with int63n supplying the randomness, so tests can seed it
+func backoff(int63n func(n int64) int64, base, max time.Duration, attempt int) time.Duration {
+    if max <= 0 {
+        max = defaultMaxRetryDelay
+    }
+    if base <= 0 {
+        return 0
+    }
+    if base >= max {
+        return max
+    }
+
+    bound := base * 2
+    for i := 0; i < attempt && bound < max; i++ {
+        bound *= 2
+    }
+    if bound > max || bound <= 0 {
+        bound = max
+    }
+    return base + time.Duration(int63n(int64(bound-base)+1))
+}
+
+// retryAfter returns the delay the API asked for before retrying err, capped at max. It
+// reports false when err is not an APIError or HTTPError carrying a RetryAfter.
+func retryAfter(err error, max time.Duration) (time.Duration, bool) {
+    var (
+        apiErr  *APIError
+        httpErr *HTTPError
+        after   *time.Duration
+    )
+    switch {
+    case errors.As(err, &apiErr):
+        after = apiErr.RetryAfter
+    case errors.As(err, &httpErr):
+        after = httpErr.RetryAfter
+    }
+    if after == nil {
+        return 0, false
+    }
+
+    delay := *after
+    if delay < 0 {
+        delay = 0
+    }
+    if max > 0 && delay > max {
+        delay = max
+    }
+    return delay, true
+}
+
+// parseRetryAfter parses a Retry-After header given either as delay seconds or as an
+// HTTP date, returning nil when the header is missing or malformed. A date in the past
+// means retry now.
+func parseRetryAfter(value string, now time.Time) *time.Duration {
+    value = strings.TrimSpace(value)
+    if value == "" {
+        return nil
+    }
+
+    var delay time.Duration
+    if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+        if seconds < 0 {
+            return nil
+        }
+        delay = time.Duration(seconds) * time.Second
+    } else if at, err := http.ParseTime(value); err == nil {
+        delay = at.Sub(now)
+        if delay < 0 {
+            delay = 0
+        }
+    } else {
+        return nil
+    }
+    return &delay
+}
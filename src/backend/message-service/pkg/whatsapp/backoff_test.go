@@ -0,0 +1,119 @@
+package whatsapp
+
+import (
+    "errors"
+    "math/rand"
+    "net/http"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestBackoffStaysWithinBounds(t *testing.T) {
+    const (
+        base = 100 * time.Millisecond
+        max  = 5 * time.Second
+    )
+    rng := rand.New(rand.NewSource(42))
+
+    for attempt := 0; attempt < 12; attempt++ {
+        top := base << (attempt + 1)
+        if top > max {
+            top = max
+        }
+        for i := 0; i < 1000; i++ {
+            delay := backoff(rng.Int63n, base, max, attempt)
+            if delay < base || delay > top {
+                t.Fatalf("attempt %d: delay %v outside [%v, %v]", attempt, delay, base, top)
+            }
+        }
+    }
+}
+
+func TestBackoffSpreadsDelays(t *testing.T) {
+    rng := rand.New(rand.NewSource(7))
+
+    seen := make(map[time.Duration]bool)
+    lowest, highest := time.Duration(1<<62), time.Duration(0)
+    for i := 0; i < 100; i++ {
+        delay := backoff(rng.Int63n, time.Second, time.Minute, 3)
+        seen[delay] = true
+        if delay < lowest {
+            lowest = delay
+        }
+        if delay > highest {
+            highest = delay
+        }
+    }
+
+    assert.Greater(t, len(seen), 90, "clients retrying together should not wait the same time")
+    // The window for attempt 3 is [1s, 16s]; a hundred draws should cover most of it
+    assert.Less(t, lowest, 4*time.Second)
+    assert.Greater(t, highest, 13*time.Second)
+}
+
+func TestBackoffEdgeCases(t *testing.T) {
+    rng := rand.New(rand.NewSource(1))
+
+    assert.Zero(t, backoff(rng.Int63n, 0, time.Second, 3), "no base means no wait")
+    assert.Equal(t, time.Second, backoff(rng.Int63n, 2*time.Second, time.Second, 0), "a base above the cap waits the cap")
+    assert.LessOrEqual(t, backoff(rng.Int63n, time.Second, 0, 50), defaultMaxRetryDelay, "no cap uses the default")
+    assert.LessOrEqual(t, backoff(rng.Int63n, time.Second, time.Minute, 1000), time.Minute, "a huge attempt does not overflow")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+    now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+    seconds := func(n int) *time.Duration {
+        d := time.Duration(n) * time.Second
+        return &d
+    }
+
+    tests := []struct {
+        name  string
+        value string
+        want  *time.Duration
+    }{
+        {"delta seconds", "120", seconds(120)},
+        {"delta seconds with spaces", " 5 ", seconds(5)},
+        {"zero seconds", "0", seconds(0)},
+        {"HTTP date", now.Add(90 * time.Second).Format(http.TimeFormat), seconds(90)},
+        {"RFC 850 date", now.Add(time.Minute).Format("Monday, 02-Jan-06 15:04:05 GMT"), seconds(60)},
+        {"date in the past", now.Add(-time.Hour).Format(http.TimeFormat), seconds(0)},
+        {"missing", "", nil},
+        {"negative seconds", "-30", nil},
+        {"fractional seconds", "1.5", nil},
+        {"malformed", "soon", nil},
+        {"malformed date", "Fri, 31 Feb 2024 25:00:00 GMT", nil},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got := parseRetryAfter(tt.value, now)
+            if tt.want == nil {
+                assert.Nil(t, got)
+                return
+            }
+            require.NotNil(t, got)
+            assert.Equal(t, *tt.want, *got)
+        })
+    }
+}
+
+func TestRetryAfterCapsRequestedDelay(t *testing.T) {
+    long, short := time.Hour, 2*time.Second
+
+    delay, ok := retryAfter(&HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: &long}, time.Minute)
+    assert.True(t, ok)
+    assert.Equal(t, time.Minute, delay, "a delay beyond the cap is clamped")
+
+    delay, ok = retryAfter(&APIError{RetryAfter: &short}, time.Minute)
+    assert.True(t, ok)
+    assert.Equal(t, short, delay)
+
+    _, ok = retryAfter(&HTTPError{StatusCode: http.StatusServiceUnavailable}, time.Minute)
+    assert.False(t, ok, "no header means fall back to backoff")
+    _, ok = retryAfter(errors.New("connection reset"), time.Minute)
+    assert.False(t, ok)
+}
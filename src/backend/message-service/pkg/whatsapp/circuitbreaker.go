@@ -0,0 +1,218 @@
+// Package whatsapp provides the circuit breaker guarding WhatsApp Business API sends
+// Version: go1.21
+package whatsapp
+
+import (
+    "sync" // go1.21
+    "time" // go1.21
+
+    "github.com/prometheus/client_golang/prometheus"          // v1.17.0
+    "github.com/prometheus/client_golang/prometheus/promauto" // v1.17.0
+)
+
+// Circuit breaker defaults
+const (
+    defaultFailureThreshold = 5
+    defaultOpenTimeout      = 30 * time.Second
+    defaultHalfOpenProbes   = 1
+    defaultCircuitName      = "default"
+)
+
+var (
+    circuitTransitions = promauto.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "whatsapp_circuit_breaker_transitions_total",
+            Help: "Total number of circuit breaker state changes by breaker and the state entered",
+        },
+        []string{"name", "state"},
+    )
+
+    circuitStateGauge = promauto.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "whatsapp_circuit_breaker_state",
+            Help: "Current circuit breaker state by breaker: 0 closed, 1 half-open, 2 open",
+        },
+        []string{"name"},
+    )
+)
+
+// circuitStateValues are the gauge values reported for each state
+var circuitStateValues = map[string]float64{
+    CircuitStateClosed:   0,
+    CircuitStateHalfOpen: 1,
+    CircuitStateOpen:     2,
+}
+
+// CircuitBreakerConfig tunes the circuit breaker. FailureThreshold consecutive failures open
+// it; after OpenTimeout it lets HalfOpenProbes requests through at a time, and closes once
+// that many succeed in a row. Zero values use the defaults.
+// Name labels the breaker's metrics; clients in one process should each use their own,
+// typically the sending phone number ID. Clock defaults to the system clock.
+type CircuitBreakerConfig struct {
+    FailureThreshold int
+    OpenTimeout      time.Duration
+    HalfOpenProbes   int
+    Name             string
+    Clock            Clock
+}
+
+// CircuitBreaker stops sends while the API is failing so requests fail fast instead of
+// piling up behind timeouts. Callers ask Allow before a request and report its outcome with
+// RecordSuccess or RecordFailure, or Release when the request was never made.
+type CircuitBreaker struct {
+    name             string
+    failureThreshold int
+    openTimeout      time.Duration
+    halfOpenProbes   int
+    clock            Clock
+    stateGauge       prometheus.Gauge
+
+    mu        sync.Mutex
+    state     string
+    failures  int       // consecutive failures while closed
+    openedAt  time.Time
+    inFlight  int       // probes allowed while half-open and not yet reported
+    successes int       // consecutive successful probes while half-open
+}
+
+// newCircuitBreaker creates a closed circuit breaker
+func newCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
+    if config == nil {
+        config = &CircuitBreakerConfig{}
+    }
+
+    cb := &CircuitBreaker{
+        name:             config.Name,
+        failureThreshold: config.FailureThreshold,
+        openTimeout:      config.OpenTimeout,
+        halfOpenProbes:   config.HalfOpenProbes,
+        clock:            config.Clock,
+        state:            CircuitStateClosed,
+    }
+    if cb.name == "" {
+        cb.name = defaultCircuitName
+    }
+    if cb.clock == nil {
+        cb.clock = systemClock{}
+    }
+    if cb.failureThreshold <= 0 {
+        cb.failureThreshold = defaultFailureThreshold
+    }
+    if cb.openTimeout <= 0 {
+        cb.openTimeout = defaultOpenTimeout
+    }
+    if cb.halfOpenProbes <= 0 {
+        cb.halfOpenProbes = defaultHalfOpenProbes
+    }
+    cb.stateGauge = circuitStateGauge.WithLabelValues(cb.name)
+    cb.stateGauge.Set(circuitStateValues[CircuitStateClosed])
+    return cb
+}
+
+// Allow reports whether a request may be made, returning ErrCircuitOpen while the breaker
+// is open or all half-open probes are in flight
+func (cb *CircuitBreaker) Allow() error {
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+
+    cb.checkTimeout()
+    switch cb.state {
+    case CircuitStateOpen:
+        return ErrCircuitOpen
+    case CircuitStateHalfOpen:
+        if cb.inFlight >= cb.halfOpenProbes {
+            return ErrCircuitOpen
+        }
+        cb.inFlight++
+    }
+    return nil
+}
+
+// RecordSuccess reports that an allowed request reached a healthy API
+func (cb *CircuitBreaker) RecordSuccess() {
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+
+    switch cb.state {
+    case CircuitStateClosed:
+        cb.failures = 0
+    case CircuitStateHalfOpen:
+        cb.releaseProbe()
+        cb.successes++
+        if cb.successes >= cb.halfOpenProbes {
+            cb.transition(CircuitStateClosed)
+        }
+    }
+}
+
+// RecordFailure reports that an allowed request failed because of the API
+func (cb *CircuitBreaker) RecordFailure() {
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+
+    switch cb.state {
+    case CircuitStateClosed:
+        cb.failures++
+        if cb.failures >= cb.failureThreshold {
+            cb.transition(CircuitStateOpen)
+        }
+    case CircuitStateHalfOpen:
+        cb.transition(CircuitStateOpen)
+    }
+}
+
+// Release returns the probe slot of an allowed request that was never made
+func (cb *CircuitBreaker) Release() {
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+
+    if cb.state == CircuitStateHalfOpen {
+        cb.releaseProbe()
+    }
+}
+
+// State returns the current state, moving an open breaker whose timeout has passed to half-open
+func (cb *CircuitBreaker) State() string {
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+
+    cb.checkTimeout()
+    return cb.state
+}
+
+// HalfOpen moves the breaker to half-open so the next requests probe the API
+func (cb *CircuitBreaker) HalfOpen() {
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+
+    if cb.state != CircuitStateHalfOpen {
+        cb.transition(CircuitStateHalfOpen)
+    }
+}
+
+// checkTimeout moves an open breaker to half-open once its timeout has passed
+func (cb *CircuitBreaker) checkTimeout() {
+    if cb.state == CircuitStateOpen && cb.clock.Now().Sub(cb.openedAt) >= cb.openTimeout {
+        cb.transition(CircuitStateHalfOpen)
+    }
+}
+
+func (cb *CircuitBreaker) releaseProbe() {
+    if cb.inFlight > 0 {
+        cb.inFlight--
+    }
+}
+
+// transition enters state, resetting the counters of the state left
+func (cb *CircuitBreaker) transition(state string) {
+    cb.state = state
+    cb.failures = 0
+    cb.inFlight = 0
+    cb.successes = 0
+    if state == CircuitStateOpen {
+        cb.openedAt = cb.clock.Now()
+    }
+
+    circuitTransitions.WithLabelValues(cb.name, state).Inc()
+    cb.stateGauge.Set(circuitStateValues[state])
+}
@@ -0,0 +1,126 @@
+package whatsapp
+
+import (
+    "testing"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus/testutil"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func newTestBreaker(t *testing.T, probes int) (*CircuitBreaker, *fakeClock) {
+    t.Helper()
+    clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+    cb := newCircuitBreaker(&CircuitBreakerConfig{
+        FailureThreshold: 3,
+        OpenTimeout:      time.Minute,
+        HalfOpenProbes:   probes,
+        Name:             t.Name(),
+        Clock:            clock,
+    })
+    return cb, clock
+}
+
+// trip opens the breaker with threshold failures
+func trip(t *testing.T, cb *CircuitBreaker) {
+    t.Helper()
+    for i := 0; i < cb.failureThreshold; i++ {
+        require.NoError(t, cb.Allow())
+        cb.RecordFailure()
+    }
+    require.Equal(t, CircuitStateOpen, cb.State())
+}
+
+func TestCircuitBreakerOpensAtThreshold(t *testing.T) {
+    cb, _ := newTestBreaker(t, 1)
+
+    for i := 0; i < 2; i++ {
+        require.NoError(t, cb.Allow())
+        cb.RecordFailure()
+    }
+    assert.Equal(t, CircuitStateClosed, cb.State(), "below the threshold the breaker stays closed")
+
+    // A success resets the run of failures
+    require.NoError(t, cb.Allow())
+    cb.RecordSuccess()
+    for i := 0; i < 2; i++ {
+        require.NoError(t, cb.Allow())
+        cb.RecordFailure()
+    }
+    assert.Equal(t, CircuitStateClosed, cb.State())
+
+    require.NoError(t, cb.Allow())
+    cb.RecordFailure()
+    assert.Equal(t, CircuitStateOpen, cb.State(), "the third failure in a row opens the breaker")
+    assert.ErrorIs(t, cb.Allow(), ErrCircuitOpen)
+}
+
+func TestCircuitBreakerHalfOpensAfterTimeout(t *testing.T) {
+    cb, clock := newTestBreaker(t, 1)
+    trip(t, cb)
+
+    clock.now = clock.now.Add(time.Minute - time.Nanosecond)
+    assert.Equal(t, CircuitStateOpen, cb.State())
+    assert.ErrorIs(t, cb.Allow(), ErrCircuitOpen)
+
+    clock.now = clock.now.Add(time.Nanosecond)
+    assert.Equal(t, CircuitStateHalfOpen, cb.State())
+    assert.NoError(t, cb.Allow())
+}
+
+func TestCircuitBreakerLimitsHalfOpenProbes(t *testing.T) {
+    cb, clock := newTestBreaker(t, 2)
+    trip(t, cb)
+    clock.now = clock.now.Add(time.Minute)
+
+    require.NoError(t, cb.Allow())
+    require.NoError(t, cb.Allow())
+    assert.ErrorIs(t, cb.Allow(), ErrCircuitOpen, "every probe slot is in flight")
+
+    cb.Release()
+    assert.NoError(t, cb.Allow(), "a released probe frees its slot")
+}
+
+func TestCircuitBreakerClosesAfterSuccessfulProbes(t *testing.T) {
+    cb, clock := newTestBreaker(t, 2)
+    trip(t, cb)
+    clock.now = clock.now.Add(time.Minute)
+
+    require.NoError(t, cb.Allow())
+    cb.RecordSuccess()
+    assert.Equal(t, CircuitStateHalfOpen, cb.State(), "one success of two is not enough")
+
+    require.NoError(t, cb.Allow())
+    cb.RecordSuccess()
+    assert.Equal(t, CircuitStateClosed, cb.State())
+    assert.NoError(t, cb.Allow())
+}
+
+func TestCircuitBreakerReopensOnFailedProbe(t *testing.T) {
+    cb, clock := newTestBreaker(t, 1)
+    trip(t, cb)
+    clock.now = clock.now.Add(time.Minute)
+
+    require.NoError(t, cb.Allow())
+    cb.RecordFailure()
+    assert.Equal(t, CircuitStateOpen, cb.State())
+
+    // The open timeout starts again from the failed probe
+    clock.now = clock.now.Add(time.Minute - time.Nanosecond)
+    assert.Equal(t, CircuitStateOpen, cb.State())
+    clock.now = clock.now.Add(time.Nanosecond)
+    assert.Equal(t, CircuitStateHalfOpen, cb.State())
+}
+
+func TestCircuitBreakerStateGaugeIsPerBreaker(t *testing.T) {
+    tripped := newCircuitBreaker(&CircuitBreakerConfig{FailureThreshold: 1, Name: "phone-1"})
+    require.NoError(t, tripped.Allow())
+    tripped.RecordFailure()
+
+    // Building another client's breaker leaves the tripped one's series alone
+    newCircuitBreaker(&CircuitBreakerConfig{Name: "phone-2"})
+
+    assert.Equal(t, circuitStateValues[CircuitStateOpen], testutil.ToFloat64(circuitStateGauge.WithLabelValues("phone-1")))
+    assert.Equal(t, circuitStateValues[CircuitStateClosed], testutil.ToFloat64(circuitStateGauge.WithLabelValues("phone-2")))
+}
@@ -3,17 +3,24 @@
 package whatsapp
 
 import (
+    "bytes"             // go1.21
     "context"           // go1.21
     "crypto/hmac"      // go1.21
     "crypto/sha256"    // go1.21
+    "encoding/base64"  // go1.21
     "encoding/hex"     // go1.21
     "encoding/json"    // go1.21
     "errors"           // go1.21
     "fmt"             // go1.21
     "io"              // go1.21
+    "net"             // go1.21
     "net/http"        // go1.21
+    "strings"         // go1.21
     "sync"            // go1.21
     "time"            // go1.21
+
+    "github.com/go-redis/redis/v8" // v8.11.5
+    "github.com/rs/zerolog"        // v1.30.0
 )
 
 // Default configuration values
@@ -24,15 +31,30 @@ const (
     defaultMaxConcurrent = 1000
     defaultRateLimit     = 100
     maxRetryAttempts     = 5
+
+    defaultMaxIdleConns        = 100
+    defaultMaxIdleConnsPerHost = 100
+    defaultIdleConnTimeout     = 90 * time.Second
+)
+
+// Circuit breaker states reported by CircuitState
+const (
+    CircuitStateClosed   = "closed"
+    CircuitStateOpen     = "open"
+    CircuitStateHalfOpen = "half_open"
 )
 
 // Common errors
 var (
-    ErrInvalidAPIKey     = errors.New("invalid API key")
-    ErrInvalidEndpoint   = errors.New("invalid API endpoint")
-    ErrRateLimitExceeded = errors.New("rate limit exceeded")
-    ErrCircuitOpen       = errors.New("circuit breaker is open")
-    ErrInvalidSignature  = errors.New("invalid webhook signature")
+    ErrInvalidAPIKey        = errors.New("invalid API key")
+    ErrInvalidEndpoint      = errors.New("invalid API endpoint")
+    ErrRateLimitExceeded    = errors.New("rate limit exceeded")
+    ErrCircuitOpen          = errors.New("circuit breaker is open")
+    ErrInvalidSignature     = errors.New("invalid webhook signature")
+    ErrClientNotInitialized = errors.New("whatsapp client is not initialized")
+    ErrMessageNotFound      = errors.New("message not found")
+    ErrUnauthorized         = errors.New("unauthorized")
+    ErrUnexpectedStatus     = errors.New("unexpected HTTP status")
 )
 
 // Client represents a WhatsApp Business API client with comprehensive features
@@ -43,10 +65,14 @@ type Client struct {
     timeout         time.Duration
     retryAttempts   int
     retryDelay      time.Duration
-    rateLimiter     *RateLimiter
+    maxRetryDelay   time.Duration
+    rateLimiter     clientLimiter
     metrics         *MetricsCollector
     circuitBreaker  *CircuitBreaker
     webhookSecret   string
+    templates       templateCache
+    media           mediaCache
+    sent            sentCache
     mu              sync.RWMutex
 }
 
@@ -55,19 +81,30 @@ type ClientOptions struct {
     Timeout             time.Duration
     RetryAttempts       int
     RetryDelay          time.Duration
+    // MaxRetryDelay caps the jittered backoff between retries; zero uses 30 seconds
+    MaxRetryDelay       time.Duration
     MaxConcurrent       int
     RateLimitConfig     *RateLimitConfig
     CircuitBreakerConfig *CircuitBreakerConfig
     MetricsConfig       *MetricsConfig
+    TransportConfig     *TransportConfig
     WebhookSecret       string
+    // RedisClient backs the shared rate limiter when RateLimitConfig.Backend is "redis"
+    RedisClient         *redis.Client
+    // Logger logs API requests and responses at debug level with credentials and phone
+    // numbers redacted; nil disables request logging
+    Logger              *zerolog.Logger
 }
 
-// RateLimiter handles API rate limiting
-type RateLimiter struct {
-    limit     int
-    remaining int
-    reset     time.Time
-    mu        sync.RWMutex
+// TransportConfig tunes HTTP connection reuse. Deployments behind proxies that cut idle
+// connections aggressively should set IdleConnTimeout below the proxy's cutoff.
+// Zero connection and idle settings use the defaults; zero handshake timeouts mean no limit.
+type TransportConfig struct {
+    MaxIdleConns          int
+    MaxIdleConnsPerHost   int
+    IdleConnTimeout       time.Duration
+    TLSHandshakeTimeout   time.Duration
+    ExpectContinueTimeout time.Duration
 }
 
 // NewClient creates a new WhatsApp Business API client instance
@@ -92,15 +129,17 @@ func NewClient(apiKey, apiEndpoint string, opts *ClientOptions) (*Client, error)
     if opts.RetryDelay == 0 {
         opts.RetryDelay = defaultRetryDelay
     }
+    if opts.MaxRetryDelay == 0 {
+        opts.MaxRetryDelay = defaultMaxRetryDelay
+    }
     if opts.MaxConcurrent == 0 {
         opts.MaxConcurrent = defaultMaxConcurrent
     }
 
     // Initialize HTTP client with connection pooling
-    transport := &http.Transport{
-        MaxIdleConns:        100,
-        MaxIdleConnsPerHost: 100,
-        IdleConnTimeout:     90 * time.Second,
+    var transport http.RoundTripper = newTransport(opts.TransportConfig)
+    if opts.Logger != nil {
+        transport = newLoggingTransport(transport, *opts.Logger)
     }
 
     client := &Client{
@@ -113,22 +152,55 @@ func NewClient(apiKey, apiEndpoint string, opts *ClientOptions) (*Client, error)
         timeout:       opts.Timeout,
         retryAttempts: opts.RetryAttempts,
         retryDelay:    opts.RetryDelay,
-        rateLimiter:   newRateLimiter(opts.RateLimitConfig),
+        maxRetryDelay: opts.MaxRetryDelay,
+        rateLimiter:   newLimiter(opts.RateLimitConfig, opts.RedisClient),
         metrics:       newMetricsCollector(opts.MetricsConfig),
         circuitBreaker: newCircuitBreaker(opts.CircuitBreakerConfig),
         webhookSecret:  opts.WebhookSecret,
     }
 
+    // Guarantee the components used on every request are present even if a constructor
+    // returns nil for an unusual configuration
+    if client.rateLimiter == nil {
+        client.rateLimiter = NewRateLimiter(nil)
+    }
+    if client.metrics == nil {
+        client.metrics = newMetricsCollector(nil)
+    }
+    if client.circuitBreaker == nil {
+        client.circuitBreaker = newCircuitBreaker(nil)
+    }
+
     return client, nil
 }
 
-// SendMessage sends a message through WhatsApp Business API with retry and rate limiting
+// SendMessage sends a message through WhatsApp Business API with retry and rate limiting.
+//
+// Every attempt carries the same Idempotency-Key header, so when a request times out after
+// the API accepted it, the retry is answered with the original result instead of delivering
+// the message twice. The key is Message.IdempotencyKey when set and is otherwise derived
+// from Message.ID. A successful send is remembered by its key for a short while, and
+// sending the same key again returns the earlier response without calling the API.
 func (c *Client) SendMessage(ctx context.Context, message *Message) (*APIResponse, error) {
+    if err := c.checkInitialized(); err != nil {
+        return nil, err
+    }
+    if message == nil {
+        return nil, errors.New("message is required")
+    }
+
+    key := idempotencyKey(message)
+    if response, ok := c.sent.get(key); ok {
+        c.metrics.RecordSuccess("send_message_deduplicated")
+        return response, nil
+    }
+
     if err := c.circuitBreaker.Allow(); err != nil {
         return nil, fmt.Errorf("circuit breaker: %w", err)
     }
 
-    if err := c.rateLimiter.Allow(); err != nil {
+    if err := c.rateLimiter.acquire(ctx, 1); err != nil {
+        c.circuitBreaker.Release()
         return nil, fmt.Errorf("rate limit: %w", err)
     }
 
@@ -137,35 +209,82 @@ func (c *Client) SendMessage(ctx context.Context, message *Message) (*APIRespons
 
     // Implement retry with exponential backoff
     for attempt := 0; attempt <= c.retryAttempts; attempt++ {
-        response, lastErr = c.doSendMessage(ctx, message)
+        response, lastErr = c.doSendMessage(ctx, message, key)
         if lastErr == nil {
+            c.circuitBreaker.RecordSuccess()
+            c.sent.put(key, response)
             c.metrics.RecordSuccess("send_message")
             return response, nil
         }
 
         // Check if error is recoverable
-        if !isRecoverableError(lastErr) {
+        if !isRecoverableError(ctx, lastErr) {
+            var (
+                apiErr  *APIError
+                httpErr *HTTPError
+            )
+            switch {
+            case errors.As(lastErr, &apiErr), errors.As(lastErr, &httpErr):
+                // The API answered, so a rejected message says nothing about its health
+                c.circuitBreaker.RecordSuccess()
+            case ctx.Err() != nil:
+                // The caller gave up waiting, as when cancelled between retries
+                c.circuitBreaker.RecordFailure()
+            default:
+                // The message could not be encoded or the reply decoded, which says
+                // nothing about the API either
+                c.circuitBreaker.Release()
+            }
             c.metrics.RecordError("send_message", lastErr)
             return nil, lastErr
         }
 
         // Wait before retry with exponential backoff
         if attempt < c.retryAttempts {
-            backoffDuration := c.calculateBackoff(attempt)
+            // Honour the delay the API asked for, such as on a 429, over our own backoff
+            backoffDuration, ok := retryAfter(lastErr, c.maxRetryDelay)
+            if !ok {
+                backoffDuration = c.calculateBackoff(attempt)
+            }
             select {
             case <-ctx.Done():
+                c.circuitBreaker.RecordFailure()
                 return nil, ctx.Err()
             case <-time.After(backoffDuration):
             }
         }
     }
 
+    c.circuitBreaker.RecordFailure()
     c.metrics.RecordError("send_message", lastErr)
     return nil, fmt.Errorf("max retry attempts reached: %w", lastErr)
 }
 
+// MessageSender sends messages through the WhatsApp Business API. Client implements it;
+// consumers depend on the interface so a fake sender can be injected.
+type MessageSender interface {
+    SendMessage(ctx context.Context, message *Message) (*APIResponse, error)
+}
+
+// MessageBuilder produces a validated message ready to be sent
+type MessageBuilder interface {
+    Build() (*Message, error)
+}
+
+// SendBuiltMessage builds a message from the given builder and sends it
+func (c *Client) SendBuiltMessage(ctx context.Context, builder MessageBuilder) (*APIResponse, error) {
+    message, err := builder.Build()
+    if err != nil {
+        return nil, fmt.Errorf("build message: %w", err)
+    }
+    return c.SendMessage(ctx, message)
+}
+
 // GetMessageStatus retrieves the current status of a sent message
 func (c *Client) GetMessageStatus(ctx context.Context, messageID string) (*MessageStatus, error) {
+    if err := c.checkInitialized(); err != nil {
+        return nil, err
+    }
     if messageID == "" {
         return nil, errors.New("message ID is required")
     }
@@ -184,6 +303,10 @@ func (c *Client) GetMessageStatus(ctx context.Context, messageID string) (*Messa
     }
     defer resp.Body.Close()
 
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return nil, newHTTPError(resp)
+    }
+
     var apiResp APIResponse
     if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
         return nil, fmt.Errorf("decode response: %w", err)
@@ -193,8 +316,114 @@ func (c *Client) GetMessageStatus(ctx context.Context, messageID string) (*Messa
     return &status, nil
 }
 
+// markReadPayload is the API body marking a received message as read
+type markReadPayload struct {
+    MessagingProduct string `json:"messaging_product"`
+    Status           string `json:"status"`
+    MessageID        string `json:"message_id"`
+}
+
+// MarkAsRead marks a received message as read, showing the sender blue read receipts
+func (c *Client) MarkAsRead(ctx context.Context, messageID string) error {
+    if err := c.checkInitialized(); err != nil {
+        return err
+    }
+    if messageID == "" {
+        return errors.New("message ID is required")
+    }
+
+    if err := c.rateLimiter.acquire(ctx, 1); err != nil {
+        return fmt.Errorf("rate limit: %w", err)
+    }
+
+    payload, err := json.Marshal(markReadPayload{
+        MessagingProduct: "whatsapp",
+        Status:           MessageStatusRead,
+        MessageID:        messageID,
+    })
+    if err != nil {
+        return fmt.Errorf("marshal request: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.apiEndpoint+"/messages", bytes.NewReader(payload))
+    if err != nil {
+        return fmt.Errorf("create request: %w", err)
+    }
+
+    c.setRequestHeaders(req)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return newHTTPError(resp)
+    }
+    return nil
+}
+
+// maxErrorBodySize bounds how much of an error response body is kept for debugging
+const maxErrorBodySize = 4096
+
+// HTTPError is a non-2xx API response. It unwraps to ErrMessageNotFound, ErrUnauthorized or
+// ErrUnexpectedStatus and keeps the start of the body, which may be HTML from a proxy
+// rather than JSON. RetryAfter is the delay the response asked for, if any.
+type HTTPError struct {
+    StatusCode int
+    Body       string
+    Err        error
+    RetryAfter *time.Duration
+}
+
+// Error reports the status code and the response body
+func (e *HTTPError) Error() string {
+    if e.Body == "" {
+        return fmt.Sprintf("%v: HTTP %d", e.Err, e.StatusCode)
+    }
+    return fmt.Sprintf("%v: HTTP %d: %s", e.Err, e.StatusCode, e.Body)
+}
+
+// Unwrap returns the error category of the status code
+func (e *HTTPError) Unwrap() error {
+    return e.Err
+}
+
+// newHTTPError classifies a non-2xx response and captures the start of its body
+func newHTTPError(resp *http.Response) *HTTPError {
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+    return httpErrorFromBody(resp.StatusCode, body)
+}
+
+// httpErrorFromBody classifies a non-2xx status with the start of its response body
+func httpErrorFromBody(statusCode int, body []byte) *HTTPError {
+    category := ErrUnexpectedStatus
+    switch statusCode {
+    case http.StatusNotFound:
+        category = ErrMessageNotFound
+    case http.StatusUnauthorized, http.StatusForbidden:
+        category = ErrUnauthorized
+    }
+
+    return &HTTPError{
+        StatusCode: statusCode,
+        Body:       string(bytes.TrimSpace(body)),
+        Err:        category,
+    }
+}
+
+// isRetryableStatus reports whether a request answered with statusCode may succeed later:
+// the API is throttling it or the API or a gateway in front of it is failing
+func isRetryableStatus(statusCode int) bool {
+    return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
 // HandleWebhook processes incoming webhook events with signature validation
 func (c *Client) HandleWebhook(req *http.Request) (*WebhookEvent, error) {
+    if err := c.checkInitialized(); err != nil {
+        return nil, err
+    }
     if c.webhookSecret == "" {
         return nil, errors.New("webhook secret not configured")
     }
@@ -219,26 +448,90 @@ func (c *Client) HandleWebhook(req *http.Request) (*WebhookEvent, error) {
         return nil, fmt.Errorf("unmarshal event: %w", err)
     }
 
+    if _, err := event.ParseReferral(); err != nil {
+        return nil, fmt.Errorf("parse referral: %w", err)
+    }
+    if _, err := event.ParseOrder(); err != nil {
+        return nil, fmt.Errorf("parse order: %w", err)
+    }
+    if _, err := event.ParseReplyContext(); err != nil {
+        return nil, fmt.Errorf("parse reply context: %w", err)
+    }
+
     c.metrics.RecordWebhook(event.Type)
     return &event, nil
 }
 
 // Helper methods
 
-func (c *Client) doSendMessage(ctx context.Context, message *Message) (*APIResponse, error) {
-    payload, err := json.Marshal(message)
+// newTransport builds the pooled HTTP transport, filling unset fields with the defaults
+func newTransport(cfg *TransportConfig) *http.Transport {
+    if cfg == nil {
+        cfg = &TransportConfig{}
+    }
+
+    transport := &http.Transport{
+        MaxIdleConns:          cfg.MaxIdleConns,
+        MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+        IdleConnTimeout:       cfg.IdleConnTimeout,
+        TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+        ExpectContinueTimeout: cfg.ExpectContinueTimeout,
+    }
+    if transport.MaxIdleConns == 0 {
+        transport.MaxIdleConns = defaultMaxIdleConns
+    }
+    if transport.MaxIdleConnsPerHost == 0 {
+        transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+    }
+    if transport.IdleConnTimeout == 0 {
+        transport.IdleConnTimeout = defaultIdleConnTimeout
+    }
+    return transport
+}
+
+// checkInitialized reports which required component is missing on a Client that was not
+// built with NewClient, instead of letting a nil dereference panic
+func (c *Client) checkInitialized() error {
+    switch {
+    case c == nil:
+        return ErrClientNotInitialized
+    case c.httpClient == nil:
+        return fmt.Errorf("%w: missing HTTP client", ErrClientNotInitialized)
+    case c.circuitBreaker == nil:
+        return fmt.Errorf("%w: missing circuit breaker", ErrClientNotInitialized)
+    case c.rateLimiter == nil:
+        return fmt.Errorf("%w: missing rate limiter", ErrClientNotInitialized)
+    case c.metrics == nil:
+        return fmt.Errorf("%w: missing metrics collector", ErrClientNotInitialized)
+    }
+    return nil
+}
+
+func (c *Client) doSendMessage(ctx context.Context, message *Message, idempotencyKey string) (*APIResponse, error) {
+    message, err := c.prepareMedia(ctx, message)
+    if err != nil {
+        return nil, err
+    }
+
+    payload, err := json.Marshal(requestPayload(message))
     if err != nil {
-        return nil, fmt.Errorf("marshal message: %w", err)
+        return nil, &encodingError{op: "marshal message", err: err}
     }
 
-    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiEndpoint+"/messages", nil)
+    // A bytes.Reader body also sets the request's Content-Length
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiEndpoint+"/messages", bytes.NewReader(payload))
     if err != nil {
         return nil, fmt.Errorf("create request: %w", err)
     }
 
     c.setRequestHeaders(req)
+    if idempotencyKey != "" {
+        req.Header.Set(idempotencyKeyHeader, idempotencyKey)
+    }
 
+    start := time.Now()
     resp, err := c.httpClient.Do(req)
+    c.metrics.ObserveLatency("send_message", time.Since(start))
     if err != nil {
         return nil, fmt.Errorf("do request: %w", err)
     }
@@ -247,98 +540,228 @@ func (c *Client) doSendMessage(ctx context.Context, message *Message) (*APIRespo
     // Update rate limit information
     c.updateRateLimits(resp)
 
+    // A replay means an earlier attempt was delivered and this one was not sent again
+    if isReplayedResponse(resp) {
+        c.metrics.RecordSuccess("send_message_replayed")
+    }
+
+    // Check the status before decoding: a gateway in front of the API answers outages with
+    // HTML, which must be retried as the outage it is rather than failing to decode
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return c.sendError(resp)
+    }
+
     var apiResp APIResponse
     if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-        return nil, fmt.Errorf("decode response: %w", err)
+        return nil, &encodingError{op: "decode response", err: err}
     }
 
+    // WhatsApp can report errors in the body of a 200 response; classify them by the
+    // embedded error rather than the HTTP status so retries honour Recoverable
     if apiResp.Error != nil {
-        return &apiResp, fmt.Errorf("API error: %s", apiResp.Error.Message)
+        c.metrics.RecordError("api_error", apiResp.Error)
+        return &apiResp, fmt.Errorf("API error: %w", apiResp.Error)
     }
 
     return &apiResp, nil
 }
 
+// sendError turns a non-2xx response to a send into an error. A JSON error body is returned
+// as the APIError it describes; any other body, such as a proxy's HTML error page, becomes
+// an HTTPError. Either is recoverable for a 429 or a 5xx and carries the Retry-After delay.
+func (c *Client) sendError(resp *http.Response) (*APIResponse, error) {
+    body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+    retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+
+    var apiResp APIResponse
+    if json.Unmarshal(body, &apiResp) == nil && apiResp.Error != nil {
+        apiResp.Error.Recoverable = apiResp.Error.Recoverable || isRetryableStatus(resp.StatusCode)
+        if apiResp.Error.RetryAfter == nil {
+            apiResp.Error.RetryAfter = retryAfter
+        }
+        c.metrics.RecordError("api_error", apiResp.Error)
+        return &apiResp, fmt.Errorf("API error: %w", apiResp.Error)
+    }
+
+    httpErr := httpErrorFromBody(resp.StatusCode, body)
+    httpErr.RetryAfter = retryAfter
+    c.metrics.RecordError("api_error", httpErr)
+    return nil, httpErr
+}
+
+// encodingError is a request that could not be marshalled or a 2xx response that could not
+// be decoded. Neither changes on retry.
+type encodingError struct {
+    op  string
+    err error
+}
+
+func (e *encodingError) Error() string {
+    return e.op + ": " + e.err.Error()
+}
+
+func (e *encodingError) Unwrap() error {
+    return e.err
+}
+
+// requestPayload returns the API body for a message. Interactive messages are rendered in
+// the API's interactive shape; other messages are sent as they are, with the context of the
+// message they reply to.
+func requestPayload(message *Message) interface{} {
+    switch {
+    case message.Content.Reaction != nil:
+        return newReactionPayload(message)
+    case message.Content.Address != nil:
+        return newAddressPayload(message)
+    case message.Content.Location != nil:
+        return newLocationPayload(message)
+    case len(message.Content.Contacts) > 0:
+        return newContactsPayload(message)
+    case message.Content.Interactive != nil:
+        return newInteractivePayload(message)
+    }
+    if quoted := replyContext(message); quoted != nil {
+        return &quotedMessagePayload{Message: message, Context: quoted}
+    }
+    return message
+}
+
 func (c *Client) setRequestHeaders(req *http.Request) {
     req.Header.Set("Authorization", "Bearer "+c.apiKey)
     req.Header.Set("Content-Type", "application/json")
     req.Header.Set("Accept", "application/json")
 }
 
+// VerifySignature reports whether signature is a valid HMAC-SHA256 of body under the
+// webhook secret
+func (c *Client) VerifySignature(body []byte, signature string) bool {
+    if c.webhookSecret == "" {
+        return false
+    }
+    return c.validateWebhookSignature(body, signature)
+}
+
 func (c *Client) validateWebhookSignature(body []byte, signature string) bool {
+    provided, ok := decodeSignature(signature)
+    if !ok {
+        return false
+    }
+
     mac := hmac.New(sha256.New, []byte(c.webhookSecret))
     mac.Write(body)
-    expectedMAC := hex.EncodeToString(mac.Sum(nil))
-    return hmac.Equal([]byte(signature), []byte(expectedMAC))
+    return hmac.Equal(provided, mac.Sum(nil))
 }
 
-func (c *Client) calculateBackoff(attempt int) time.Duration {
-    backoff := c.retryDelay * time.Duration(1<<uint(attempt))
-    if backoff > 30*time.Second {
-        backoff = 30 * time.Second
+// decodeSignature decodes a signature header value to the raw digest. Meta sends the digest
+// hex encoded after a sha256= prefix, and some products send it base64 encoded instead.
+func decodeSignature(signature string) ([]byte, bool) {
+    signature = strings.TrimSpace(signature)
+    if len(signature) > len("sha256=") && strings.EqualFold(signature[:len("sha256=")], "sha256=") {
+        signature = signature[len("sha256="):]
+    }
+
+    if len(signature) == hex.EncodedLen(sha256.Size) {
+        if digest, err := hex.DecodeString(signature); err == nil {
+            return digest, true
+        }
     }
-    return backoff
+    for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+        if digest, err := encoding.DecodeString(signature); err == nil && len(digest) == sha256.Size {
+            return digest, true
+        }
+    }
+    return nil, false
+}
+
+func (c *Client) calculateBackoff(attempt int) time.Duration {
+    return Backoff(c.retryDelay, c.maxRetryDelay, attempt)
 }
 
 func (c *Client) updateRateLimits(resp *http.Response) {
-    c.rateLimiter.mu.Lock()
-    defer c.rateLimiter.mu.Unlock()
+    limit, remaining := -1, -1
+    var reset time.Time
 
-    if limit := resp.Header.Get("X-RateLimit-Limit"); limit != "" {
-        fmt.Sscanf(limit, "%d", &c.rateLimiter.limit)
+    if value := resp.Header.Get("X-RateLimit-Limit"); value != "" {
+        fmt.Sscanf(value, "%d", &limit)
     }
-    if remaining := resp.Header.Get("X-RateLimit-Remaining"); remaining != "" {
-        fmt.Sscanf(remaining, "%d", &c.rateLimiter.remaining)
+    if value := resp.Header.Get("X-RateLimit-Remaining"); value != "" {
+        fmt.Sscanf(value, "%d", &remaining)
     }
-    if reset := resp.Header.Get("X-RateLimit-Reset"); reset != "" {
-        c.rateLimiter.reset, _ = time.Parse(time.RFC3339, reset)
+    if value := resp.Header.Get("X-RateLimit-Reset"); value != "" {
+        reset, _ = time.Parse(time.RFC3339, value)
     }
+
+    c.rateLimiter.applyServerLimits(limit, remaining, reset)
 }
 
-func isRecoverableError(err error) bool {
-    if err == nil {
+// isRecoverableError reports whether retrying the request that failed with err may succeed.
+// The API says so for its own errors, and a 429 or 5xx status is retried whatever its body.
+// Nothing is retried once the caller's ctx is done, and a cancellation, a request that cannot
+// be encoded, a 2xx reply that cannot be decoded, or a bad media file fail the same way every
+// time; timeouts of a single request and other network errors are retried.
+func isRecoverableError(ctx context.Context, err error) bool {
+    if err == nil || ctx.Err() != nil {
         return false
     }
     
-    // Consider network errors, rate limits, and 5xx errors as recoverable
+    // A missing or oversized local media file will not succeed on retry
+    if errors.Is(err, ErrInvalidMediaSource) || errors.Is(err, ErrMediaTooLarge) {
+        return false
+    }
+
+    // Rate limits and 5xx errors are flagged recoverable by the API
     var apiErr *APIError
     if errors.As(err, &apiErr) {
         return apiErr.Recoverable
     }
+
+    // A throttled request or a failing API or gateway may succeed later; other statuses,
+    // such as a rejected token, will not
+    var httpErr *HTTPError
+    if errors.As(err, &httpErr) {
+        return isRetryableStatus(httpErr.StatusCode)
+    }
+
+    if errors.Is(err, context.Canceled) {
+        return false
+    }
+
+    // With the caller's ctx still live, a deadline or timeout is the HTTP client's limit on
+    // this one request and is worth another attempt
+    var netErr net.Error
+    if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+        return true
+    }
+
+    if isJSONError(err) {
+        return false
+    }
     
     return true
 }
 
-// newRateLimiter creates a new rate limiter instance
-func newRateLimiter(config *RateLimitConfig) *RateLimiter {
-    if config == nil {
-        return &RateLimiter{
-            limit:     defaultRateLimit,
-            remaining: defaultRateLimit,
-            reset:     time.Now().Add(time.Hour),
-        }
-    }
-    return &RateLimiter{
-        limit:     config.Limit,
-        remaining: config.Limit,
-        reset:     time.Now().Add(time.Hour),
-    }
+// isJSONError reports whether err comes from marshalling a request or decoding a 2xx
+// response. A body that failed to decode on any other status was an HTTPError already.
+func isJSONError(err error) bool {
+    var encErr *encodingError
+    return errors.As(err, &encErr)
 }
 
-// Allow checks if the request can be made under current rate limits
-func (r *RateLimiter) Allow() error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
+// RateLimiter returns the client's rate limiter so callers can pace requests against the same budget
+func (c *Client) RateLimiter() Limiter {
+    return c.rateLimiter
+}
 
-    if time.Now().After(r.reset) {
-        r.remaining = r.limit
-        r.reset = time.Now().Add(time.Hour)
-    }
+// CircuitState reports the current state of the client's circuit breaker
+func (c *Client) CircuitState() string {
+    return c.circuitBreaker.State()
+}
 
-    if r.remaining <= 0 {
-        return ErrRateLimitExceeded
+// ResetCircuitBreaker lets operators recover a breaker left open after a resolved incident
+// without a restart. The breaker moves to half-open rather than closed, so the next request
+// probes the API and a failed probe reopens it. Closed and half-open breakers are unchanged.
+func (c *Client) ResetCircuitBreaker() {
+    if c.circuitBreaker.State() == CircuitStateOpen {
+        c.circuitBreaker.HalfOpen()
     }
-
-    r.remaining--
-    return nil
-}
\ No newline at end of file
+}
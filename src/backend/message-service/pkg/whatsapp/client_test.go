@@ -0,0 +1,398 @@
+package whatsapp
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client for server that retries quickly
+func newTestClient(t *testing.T, server *httptest.Server) *Client {
+    t.Helper()
+    client, err := NewClient("test-key", server.URL, &ClientOptions{
+        RetryAttempts: 2,
+        RetryDelay:    time.Millisecond,
+        MaxRetryDelay: time.Millisecond,
+        MetricsConfig: &MetricsConfig{Registry: prometheus.NewRegistry()},
+    })
+    require.NoError(t, err)
+    return client
+}
+
+func TestSendMessageSendsMarshaledBody(t *testing.T) {
+    message := &Message{
+        ID:       "msg-body",
+        To:       "+14155550100",
+        Type:     "text",
+        Content:  MessageContent{Text: "hello <world> & co"},
+        Metadata: map[string]interface{}{"campaign": "spring"},
+    }
+    want, err := json.Marshal(requestPayload(message))
+    require.NoError(t, err)
+
+    var (
+        body          []byte
+        contentLength int64
+    )
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ = io.ReadAll(r.Body)
+        contentLength = r.ContentLength
+        fmt.Fprint(w, `{"messaging_product":"whatsapp"}`)
+    }))
+    defer server.Close()
+
+    client := newTestClient(t, server)
+    _, err = client.SendMessage(context.Background(), message)
+
+    require.NoError(t, err)
+    assert.JSONEq(t, string(want), string(body))
+    assert.EqualValues(t, len(want), contentLength)
+}
+
+func TestSendMessageRetriesGatewayHTML(t *testing.T) {
+    var hits int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if atomic.AddInt32(&hits, 1) <= 2 {
+            w.Header().Set("Content-Type", "text/html")
+            w.WriteHeader(http.StatusServiceUnavailable)
+            fmt.Fprint(w, "<html><body>503 Service Temporarily Unavailable</body></html>")
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"messaging_product":"whatsapp"}`)
+    }))
+    defer server.Close()
+
+    client := newTestClient(t, server)
+    _, err := client.SendMessage(context.Background(), &Message{
+        ID:      "msg-1",
+        To:      "+14155550100",
+        Type:    "text",
+        Content: MessageContent{Text: "hello"},
+    })
+
+    require.NoError(t, err)
+    assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+}
+
+func TestSendMessageGatewayHTMLExhaustsRetries(t *testing.T) {
+    var hits int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&hits, 1)
+        w.Header().Set("Content-Type", "text/html")
+        w.WriteHeader(http.StatusBadGateway)
+        fmt.Fprint(w, "<html>502 Bad Gateway</html>")
+    }))
+    defer server.Close()
+
+    client := newTestClient(t, server)
+    _, err := client.SendMessage(context.Background(), &Message{
+        ID:      "msg-2",
+        To:      "+14155550100",
+        Type:    "text",
+        Content: MessageContent{Text: "hello"},
+    })
+
+    var httpErr *HTTPError
+    require.True(t, errors.As(err, &httpErr))
+    assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+    assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+}
+
+func TestSendMessageDoesNotRetryMarshalError(t *testing.T) {
+    var hits int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&hits, 1)
+    }))
+    defer server.Close()
+
+    client := newTestClient(t, server)
+    _, err := client.SendMessage(context.Background(), &Message{
+        ID:       "msg-3",
+        To:       "+14155550100",
+        Type:     "text",
+        Content:  MessageContent{Text: "hello"},
+        Metadata: map[string]interface{}{"unencodable": make(chan int)},
+    })
+
+    require.Error(t, err)
+    assert.True(t, isJSONError(err))
+    assert.EqualValues(t, 0, atomic.LoadInt32(&hits))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRecoverableError(t *testing.T) {
+    cancelled, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    tests := []struct {
+        name string
+        ctx  context.Context
+        err  error
+        want bool
+    }{
+        {"nil error", context.Background(), nil, false},
+        {"caller context done", cancelled, errors.New("do request: connection reset"), false},
+        {"invalid media source", context.Background(), fmt.Errorf("media: %w", ErrInvalidMediaSource), false},
+        {"media too large", context.Background(), fmt.Errorf("media: %w", ErrMediaTooLarge), false},
+        {"recoverable API error", context.Background(), fmt.Errorf("API error: %w", &APIError{Code: 130429, Recoverable: true}), true},
+        {"rejected API error", context.Background(), fmt.Errorf("API error: %w", &APIError{Code: 131026}), false},
+        {"gateway 503", context.Background(), httpErrorFromBody(http.StatusServiceUnavailable, []byte("<html></html>")), true},
+        {"throttled 429", context.Background(), httpErrorFromBody(http.StatusTooManyRequests, nil), true},
+        {"unauthorized 401", context.Background(), httpErrorFromBody(http.StatusUnauthorized, nil), false},
+        {"bad request 400", context.Background(), httpErrorFromBody(http.StatusBadRequest, nil), false},
+        {"cancelled request", context.Background(), fmt.Errorf("do request: %w", context.Canceled), false},
+        {"request deadline", context.Background(), fmt.Errorf("do request: %w", context.DeadlineExceeded), true},
+        {"network timeout", context.Background(), fmt.Errorf("do request: %w", timeoutError{}), true},
+        {"marshal error", context.Background(), &encodingError{op: "marshal message", err: errors.New("json: unsupported type")}, false},
+        {"2xx decode error", context.Background(), &encodingError{op: "decode response", err: errors.New("unexpected EOF")}, false},
+        {"network error", context.Background(), errors.New("do request: connection refused"), true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            assert.Equal(t, tt.want, isRecoverableError(tt.ctx, tt.err))
+        })
+    }
+}
+
+func TestResetCircuitBreakerProbesBackend(t *testing.T) {
+    var hits int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&hits, 1)
+        fmt.Fprint(w, `{"messaging_product":"whatsapp"}`)
+    }))
+    defer server.Close()
+
+    client := newTestClient(t, server)
+    for i := 0; i < client.circuitBreaker.failureThreshold; i++ {
+        client.circuitBreaker.RecordFailure()
+    }
+    require.Equal(t, CircuitStateOpen, client.CircuitState())
+
+    message := &Message{ID: "msg-probe", To: "+14155550100", Type: "text", Content: MessageContent{Text: "hello"}}
+    _, err := client.SendMessage(context.Background(), message)
+    assert.ErrorIs(t, err, ErrCircuitOpen)
+    assert.EqualValues(t, 0, atomic.LoadInt32(&hits), "an open breaker fails fast")
+
+    client.ResetCircuitBreaker()
+    assert.Equal(t, CircuitStateHalfOpen, client.CircuitState())
+
+    _, err = client.SendMessage(context.Background(), message)
+    require.NoError(t, err)
+    assert.EqualValues(t, 1, atomic.LoadInt32(&hits), "the next send probes the API")
+    assert.Equal(t, CircuitStateClosed, client.CircuitState(), "a successful probe closes the breaker")
+}
+
+func TestSendMessageReturnsPromptlyWhenCancelledDuringBackoff(t *testing.T) {
+    var hits atomic.Int32
+    attempted := make(chan struct{}, 1)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        hits.Add(1)
+        w.Header().Set("Retry-After", "60")
+        w.WriteHeader(http.StatusServiceUnavailable)
+        attempted <- struct{}{}
+    }))
+    defer server.Close()
+
+    client, err := NewClient("test-key", server.URL, &ClientOptions{
+        RetryAttempts: 2,
+        RetryDelay:    time.Minute,
+        MaxRetryDelay: time.Minute,
+        MetricsConfig: &MetricsConfig{Registry: prometheus.NewRegistry()},
+    })
+    require.NoError(t, err)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    result := make(chan error, 1)
+    go func() {
+        _, err := client.SendMessage(ctx, &Message{ID: "msg-cancel", To: "+14155550100", Type: "text", Content: MessageContent{Text: "hello"}})
+        result <- err
+    }()
+
+    // Once the client has read the retryable status it backs off for the minute the server
+    // asked for; cancel well inside that window
+    <-attempted
+    time.Sleep(50 * time.Millisecond)
+    start := time.Now()
+    cancel()
+
+    select {
+    case err := <-result:
+        assert.Equal(t, ctx.Err(), err)
+        assert.Less(t, time.Since(start), time.Second)
+    case <-time.After(5 * time.Second):
+        require.FailNow(t, "SendMessage kept backing off after cancellation")
+    }
+    assert.EqualValues(t, 1, hits.Load(), "no retry is made after cancellation")
+}
+
+func TestSendMessageRetriesTimeoutWithoutDuplicating(t *testing.T) {
+    var (
+        mu        sync.Mutex
+        keys      []string
+        delivered = make(map[string]string)
+        release   = make(chan struct{})
+    )
+    // The fake API delivers the first attempt but stalls past the client's timeout, then
+    // answers the retry with the same key from what it already delivered
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        key := r.Header.Get(idempotencyKeyHeader)
+        mu.Lock()
+        keys = append(keys, key)
+        messageID, seen := delivered[key]
+        if !seen {
+            messageID = fmt.Sprintf("wamid.%d", len(delivered)+1)
+            delivered[key] = messageID
+        }
+        mu.Unlock()
+
+        if !seen {
+            <-release
+            return
+        }
+        w.Header().Set(idempotentReplayHeader, "true")
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprintf(w, `{"message_id":%q,"status":"sent"}`, messageID)
+    }))
+    defer server.Close()
+    defer close(release)
+
+    client, err := NewClient("test-key", server.URL, &ClientOptions{
+        Timeout:         100 * time.Millisecond,
+        RetryAttempts:   2,
+        RetryDelay:      time.Millisecond,
+        MaxRetryDelay:   time.Millisecond,
+        RateLimitConfig: &RateLimitConfig{Limit: 60, Burst: 2},
+        MetricsConfig:   &MetricsConfig{Registry: prometheus.NewRegistry()},
+    })
+    require.NoError(t, err)
+    message := &Message{ID: "msg-timeout", To: "+14155550100", Type: "text", Content: MessageContent{Text: "hello"}}
+
+    response, err := client.SendMessage(context.Background(), message)
+    require.NoError(t, err)
+    assert.Equal(t, "wamid.1", response.MessageID)
+
+    mu.Lock()
+    assert.Len(t, keys, 2, "the timed out attempt is retried once")
+    assert.Equal(t, keys[0], keys[1], "both attempts carry the same key")
+    assert.Len(t, delivered, 1, "only one message reaches the recipient")
+    mu.Unlock()
+
+    // Sending the message again is answered from the earlier result
+    again, err := client.SendMessage(context.Background(), message)
+    require.NoError(t, err)
+    assert.Equal(t, response.MessageID, again.MessageID)
+    mu.Lock()
+    assert.Len(t, keys, 2)
+    mu.Unlock()
+
+    // The retry and the repeat are one logical send, so one of the two tokens is left
+    require.NoError(t, client.RateLimiter().Allow())
+    assert.ErrorIs(t, client.RateLimiter().Allow(), ErrRateLimitExceeded)
+}
+
+func TestSendErrorCarriesRetryAfter(t *testing.T) {
+    server := httptest.NewServer(http.NotFoundHandler())
+    defer server.Close()
+    client := newTestClient(t, server)
+    respond := func(retryAfter, body string) error {
+        header := http.Header{}
+        if retryAfter != "" {
+            header.Set("Retry-After", retryAfter)
+        }
+        _, err := client.sendError(&http.Response{
+            StatusCode: http.StatusTooManyRequests,
+            Header:     header,
+            Body:       io.NopCloser(strings.NewReader(body)),
+        })
+        return err
+    }
+
+    delay, ok := retryAfter(respond("30", "<html>slow down</html>"), time.Hour)
+    assert.True(t, ok)
+    assert.Equal(t, 30*time.Second, delay, "delta seconds on an HTML error")
+
+    // Parsed against the wall clock, so allow for the second the header is rounded to
+    delay, ok = retryAfter(respond(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), `{"error":{"code":4,"message":"too many calls"}}`), time.Hour)
+    assert.True(t, ok)
+    assert.InDelta(t, float64(time.Minute), float64(delay), float64(2*time.Second), "an HTTP date on an API error")
+
+    delay, ok = retryAfter(respond(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), "<html>slow down</html>"), time.Hour)
+    assert.True(t, ok)
+    assert.Zero(t, delay, "a date in the past means retry now")
+
+    _, ok = retryAfter(respond("whenever", "<html>slow down</html>"), time.Hour)
+    assert.False(t, ok, "a malformed header falls back to backoff")
+}
+
+func TestValidateWebhookSignature(t *testing.T) {
+    const secret = "app-secret"
+    body := []byte(`{"object":"whatsapp_business_account","entry":[{"id":"102290129340398"}]}`)
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write(body)
+    digest := mac.Sum(nil)
+    hexDigest := hex.EncodeToString(digest)
+
+    // Flip the last hex digit to tamper with the signature
+    last := hexDigest[len(hexDigest)-1]
+    flipped := byte('0')
+    if last == '0' {
+        flipped = '1'
+    }
+    tampered := hexDigest[:len(hexDigest)-1] + string(flipped)
+
+    tests := []struct {
+        name      string
+        body      []byte
+        signature string
+        valid     bool
+    }{
+        {"hex with prefix", body, "sha256=" + hexDigest, true},
+        {"uppercase prefix and digest", body, "SHA256=" + strings.ToUpper(hexDigest), true},
+        {"hex without prefix", body, hexDigest, true},
+        {"base64 with prefix", body, "sha256=" + base64.StdEncoding.EncodeToString(digest), true},
+        {"base64 without prefix", body, base64.StdEncoding.EncodeToString(digest), true},
+        {"surrounding whitespace", body, " sha256=" + hexDigest + " ", true},
+        {"tampered signature", body, "sha256=" + tampered, false},
+        {"tampered body", append([]byte(`{"tampered":true,`), body[1:]...), "sha256=" + hexDigest, false},
+        {"bad hex", body, "sha256=" + strings.Repeat("zz", sha256.Size), false},
+        {"truncated digest", body, "sha256=" + hexDigest[:len(hexDigest)-2], false},
+        {"other algorithm prefix", body, "sha1=" + hexDigest, false},
+        {"prefix only", body, "sha256=", false},
+        {"missing signature", body, "", false},
+    }
+
+    client := &Client{webhookSecret: secret}
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            assert.Equal(t, tt.valid, client.validateWebhookSignature(tt.body, tt.signature))
+        })
+    }
+
+    other := &Client{webhookSecret: "another-secret"}
+    assert.False(t, other.validateWebhookSignature(body, "sha256="+hexDigest), "a signature made with another secret is rejected")
+}
@@ -0,0 +1,45 @@
+// Package whatsapp provides contacts message payloads for the WhatsApp Business API
+// Version: go1.21
+package whatsapp
+
+import (
+    "context" // go1.21
+    "errors"  // go1.21
+)
+
+// contactsMessagePayload is the API body of a contacts message
+type contactsMessagePayload struct {
+    MessagingProduct      string          `json:"messaging_product"`
+    RecipientType         string          `json:"recipient_type"`
+    To                    string          `json:"to"`
+    Type                  string          `json:"type"`
+    Contacts              []ContactCard   `json:"contacts"`
+    Context               *messageContext `json:"context,omitempty"`
+    BizOpaqueCallbackData string          `json:"biz_opaque_callback_data,omitempty"`
+}
+
+// SendContacts sends one or more contact cards to the recipient
+func (c *Client) SendContacts(ctx context.Context, to string, contacts []ContactCard) (*APIResponse, error) {
+    if len(contacts) == 0 {
+        return nil, errors.New("at least one contact is required")
+    }
+
+    return c.SendMessage(ctx, &Message{
+        To:      to,
+        Type:    MessageTypeContacts,
+        Content: MessageContent{Contacts: contacts},
+    })
+}
+
+// newContactsPayload renders a contacts message in the shape the API expects
+func newContactsPayload(message *Message) *contactsMessagePayload {
+    return &contactsMessagePayload{
+        MessagingProduct:      "whatsapp",
+        RecipientType:         "individual",
+        To:                    message.To,
+        Type:                  MessageTypeContacts,
+        Contacts:              message.Content.Contacts,
+        Context:               replyContext(message),
+        BizOpaqueCallbackData: message.BizOpaqueCallbackData,
+    }
+}
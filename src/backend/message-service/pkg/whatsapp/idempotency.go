@@ -0,0 +1,106 @@
+// Package whatsapp provides idempotent message sends for the WhatsApp Business API client
+// Version: go1.21
+package whatsapp
+
+import (
+    "crypto/rand"   // go1.21
+    "crypto/sha256" // go1.21
+    "encoding/hex"  // go1.21
+    "net/http"      // go1.21
+    "sync"          // go1.21
+    "time"          // go1.21
+)
+
+const (
+    // idempotencyKeyHeader carries the key the API uses to recognise repeated attempts
+    idempotencyKeyHeader = "Idempotency-Key"
+    // idempotentReplayHeader is set by the API when it answers with the result of an
+    // earlier attempt instead of sending the message again
+    idempotentReplayHeader = "Idempotent-Replayed"
+    // sentResponseTTL is how long a successful send is remembered for its key
+    sentResponseTTL = 15 * time.Minute
+)
+
+// idempotencyKey returns the key attached to every attempt of one logical send.
+// Message.IdempotencyKey is used verbatim; otherwise the key is derived from Message.ID
+// so a message re-sent from the queue maps to the same key. Messages with neither get a
+// random key that still covers the retries of a single SendMessage call.
+func idempotencyKey(message *Message) string {
+    if message.IdempotencyKey != "" {
+        return message.IdempotencyKey
+    }
+    if message.ID != "" {
+        sum := sha256.Sum256([]byte("whatsapp-send:" + message.ID))
+        return hex.EncodeToString(sum[:16])
+    }
+
+    var random [16]byte
+    if _, err := rand.Read(random[:]); err != nil {
+        return ""
+    }
+    return hex.EncodeToString(random[:])
+}
+
+// isReplayedResponse reports whether the API answered from an earlier attempt with the same key
+func isReplayedResponse(resp *http.Response) bool {
+    return resp.Header.Get(idempotentReplayHeader) == "true"
+}
+
+// sentCache remembers successful responses by idempotency key so a send that already
+// succeeded is not repeated. The zero value is empty.
+type sentCache struct {
+    mu        sync.Mutex
+    responses map[string]cachedResponse
+    lastSweep time.Time
+}
+
+type cachedResponse struct {
+    response *APIResponse
+    sentAt   time.Time
+}
+
+// get returns the response of an earlier successful send with the same key, if it has not expired
+func (sc *sentCache) get(key string) (*APIResponse, bool) {
+    if key == "" {
+        return nil, false
+    }
+
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+
+    cached, ok := sc.responses[key]
+    if !ok {
+        return nil, false
+    }
+    if time.Since(cached.sentAt) > sentResponseTTL {
+        delete(sc.responses, key)
+        return nil, false
+    }
+    return cached.response, true
+}
+
+func (sc *sentCache) put(key string, response *APIResponse) {
+    if key == "" {
+        return
+    }
+
+    sc.mu.Lock()
+    defer sc.mu.Unlock()
+
+    if sc.responses == nil {
+        sc.responses = make(map[string]cachedResponse)
+    }
+
+    // Every send adds an entry, so expired ones are dropped periodically to bound memory
+    now := time.Now()
+    if now.Sub(sc.lastSweep) > sentResponseTTL {
+        for k, cached := range sc.responses {
+            if now.Sub(cached.sentAt) > sentResponseTTL {
+                delete(sc.responses, k)
+            }
+        }
+        sc.lastSweep = now
+    }
+
+    sc.responses[key] = cachedResponse{response: response, sentAt: now}
+}
@@ -0,0 +1,108 @@
+// Package whatsapp provides interactive message payloads for the WhatsApp Business API
+// Version: go1.21
+package whatsapp
+
+import (
+    "context" // go1.21
+    "errors"  // go1.21
+)
+
+// interactiveMessagePayload is the API body of an interactive message
+type interactiveMessagePayload struct {
+    MessagingProduct      string             `json:"messaging_product"`
+    RecipientType         string             `json:"recipient_type"`
+    To                    string             `json:"to"`
+    Type                  string             `json:"type"`
+    Interactive           interactiveContent `json:"interactive"`
+    Context               *messageContext    `json:"context,omitempty"`
+    BizOpaqueCallbackData string             `json:"biz_opaque_callback_data,omitempty"`
+}
+
+// interactiveContent is the interactive object of an interactive message
+type interactiveContent struct {
+    Type   string             `json:"type"`
+    Header *interactiveHeader `json:"header,omitempty"`
+    Body   interactiveText    `json:"body"`
+    Footer *interactiveText   `json:"footer,omitempty"`
+    Action interactiveAction  `json:"action"`
+}
+
+type interactiveHeader struct {
+    Type string `json:"type"`
+    Text string `json:"text"`
+}
+
+type interactiveText struct {
+    Text string `json:"text"`
+}
+
+// interactiveAction holds the buttons, list sections or named action of an interactive message
+type interactiveAction struct {
+    Name       string              `json:"name,omitempty"`
+    Parameters *AddressContent     `json:"parameters,omitempty"`
+    Button     string              `json:"button,omitempty"`
+    Buttons    []interactiveButton `json:"buttons,omitempty"`
+    Sections   []ListSection       `json:"sections,omitempty"`
+}
+
+type interactiveButton struct {
+    Type  string      `json:"type"`
+    Reply ReplyButton `json:"reply"`
+}
+
+// SendInteractiveMessage sends a reply-button or list message to the recipient
+func (c *Client) SendInteractiveMessage(ctx context.Context, to string, content *InteractiveContent) (*APIResponse, error) {
+    if content == nil {
+        return nil, errors.New("interactive content is required")
+    }
+    if content.Type != InteractiveTypeButton && content.Type != InteractiveTypeList {
+        return nil, errors.New("interactive type must be button or list")
+    }
+
+    return c.SendMessage(ctx, &Message{
+        To:      to,
+        Type:    MessageTypeInteractive,
+        Content: MessageContent{Interactive: content},
+    })
+}
+
+// newInteractivePayload renders a reply-button or list message in the shape the API expects
+func newInteractivePayload(message *Message) *interactiveMessagePayload {
+    content := message.Content.Interactive
+
+    interactive := interactiveContent{
+        Type: content.Type,
+        Body: interactiveText{Text: content.Body},
+    }
+    if content.Header != "" {
+        interactive.Header = &interactiveHeader{Type: "text", Text: content.Header}
+    }
+    if content.Footer != "" {
+        interactive.Footer = &interactiveText{Text: content.Footer}
+    }
+
+    switch content.Type {
+    case InteractiveTypeButton:
+        for _, button := range content.Buttons {
+            interactive.Action.Buttons = append(interactive.Action.Buttons, interactiveButton{Type: "reply", Reply: button})
+        }
+    case InteractiveTypeList:
+        interactive.Action.Button = content.ListButton
+        interactive.Action.Sections = content.Sections
+    }
+
+    return newInteractiveMessage(message, interactive)
+}
+
+// newInteractiveMessage wraps an interactive object in the message envelope
+func newInteractiveMessage(message *Message, interactive interactiveContent) *interactiveMessagePayload {
+    return &interactiveMessagePayload{
+        MessagingProduct:      "whatsapp",
+        RecipientType:         "individual",
+        To:                    message.To,
+        Type:                  "interactive",
+        Interactive:           interactive,
+        Context:               replyContext(message),
+        BizOpaqueCallbackData: message.BizOpaqueCallbackData,
+    }
+}
@@ -0,0 +1,59 @@
+// Package whatsapp provides location message payloads for the WhatsApp Business API
+// Version: go1.21
+package whatsapp
+
+import (
+    "context" // go1.21
+    "errors"  // go1.21
+)
+
+// locationMessagePayload is the API body of a location message
+type locationMessagePayload struct {
+    MessagingProduct      string          `json:"messaging_product"`
+    RecipientType         string          `json:"recipient_type"`
+    To                    string          `json:"to"`
+    Type                  string          `json:"type"`
+    Location              locationDetails `json:"location"`
+    Context               *messageContext `json:"context,omitempty"`
+    BizOpaqueCallbackData string          `json:"biz_opaque_callback_data,omitempty"`
+}
+
+// locationDetails is the location object of a location message
+type locationDetails struct {
+    Latitude  float64 `json:"latitude"`
+    Longitude float64 `json:"longitude"`
+    Name      string  `json:"name,omitempty"`
+    Address   string  `json:"address,omitempty"`
+}
+
+// SendLocation sends a map pin to the recipient
+func (c *Client) SendLocation(ctx context.Context, to string, location *LocationContent) (*APIResponse, error) {
+    if location == nil {
+        return nil, errors.New("location is required")
+    }
+
+    return c.SendMessage(ctx, &Message{
+        To:      to,
+        Type:    MessageTypeLocation,
+        Content: MessageContent{Location: location},
+    })
+}
+
+// newLocationPayload renders a location message in the shape the API expects
+func newLocationPayload(message *Message) *locationMessagePayload {
+    location := message.Content.Location
+    return &locationMessagePayload{
+        MessagingProduct: "whatsapp",
+        RecipientType:    "individual",
+        To:               message.To,
+        Type:             MessageTypeLocation,
+        Location: locationDetails{
+            Latitude:  location.Latitude,
+            Longitude: location.Longitude,
+            Name:      location.Name,
+            Address:   location.Address,
+        },
+        Context:               replyContext(message),
+        BizOpaqueCallbackData: message.BizOpaqueCallbackData,
+    }
+}
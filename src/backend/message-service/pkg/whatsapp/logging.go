@@ -0,0 +1,129 @@
+// Package whatsapp provides debug logging of WhatsApp Business API requests and responses
+// Version: go1.21
+package whatsapp
+
+import (
+    "bytes"    // go1.21
+    "io"       // go1.21
+    "net/http" // go1.21
+    "regexp"   // go1.21
+    "strings"  // go1.21
+    "time"     // go1.21
+
+    "github.com/rs/zerolog" // v1.30.0
+)
+
+const (
+    // maxLoggedBodyBytes caps how much of a body is logged
+    maxLoggedBodyBytes = 4096
+
+    redacted = "[REDACTED]"
+)
+
+var (
+    // phoneFieldPattern matches JSON fields that carry phone numbers, whatever their format
+    phoneFieldPattern = regexp.MustCompile(`"(to|from|wa_id|recipient_id|phone|phone_number|display_phone_number)"(\s*:\s*)"[^"]*"`)
+
+    // phoneNumberPattern matches E.164 numbers anywhere else in a body
+    phoneNumberPattern = regexp.MustCompile(`\+[1-9]\d{6,14}`)
+)
+
+// loggingTransport logs each request and response at debug level with the Authorization
+// header and phone numbers redacted
+type loggingTransport struct {
+    next   http.RoundTripper
+    logger zerolog.Logger
+}
+
+// newLoggingTransport wraps next so its traffic is logged to logger
+func newLoggingTransport(next http.RoundTripper, logger zerolog.Logger) http.RoundTripper {
+    return &loggingTransport{next: next, logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if !t.logger.Debug().Enabled() {
+        return t.next.RoundTrip(req)
+    }
+
+    event := t.logger.Debug().
+        Str("component", "whatsapp_client").
+        Str("method", req.Method).
+        Str("endpoint", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path).
+        Str("authorization", redactAuthorization(req.Header.Get("Authorization")))
+    if body, ok := requestBody(req); ok {
+        event = event.Str("request_body", body)
+    }
+
+    start := time.Now()
+    resp, err := t.next.RoundTrip(req)
+    event = event.Dur("latency", time.Since(start))
+    if err != nil {
+        event.Err(err).Msg("WhatsApp API request failed")
+        return nil, err
+    }
+
+    event = event.Int("status_code", resp.StatusCode)
+    if isLoggableBody(resp.Header.Get("Content-Type")) {
+        body, readErr := peekBody(resp)
+        if readErr != nil {
+            event = event.AnErr("body_error", readErr)
+        }
+        event = event.Str("response_body", redactBody(body))
+    }
+    event.Msg("WhatsApp API request")
+    return resp, nil
+}
+
+// requestBody returns a redacted copy of the request body without consuming it. Bodies that
+// cannot be replayed or are not text are left out.
+func requestBody(req *http.Request) (string, bool) {
+    if req.GetBody == nil || !isLoggableBody(req.Header.Get("Content-Type")) {
+        return "", false
+    }
+    body, err := req.GetBody()
+    if err != nil {
+        return "", false
+    }
+    defer body.Close()
+
+    data, err := io.ReadAll(io.LimitReader(body, maxLoggedBodyBytes))
+    if err != nil {
+        return "", false
+    }
+    return redactBody(data), true
+}
+
+// peekBody reads up to maxLoggedBodyBytes of the response body and puts them back so the
+// caller still reads the whole body
+func peekBody(resp *http.Response) ([]byte, error) {
+    data, err := io.ReadAll(io.LimitReader(resp.Body, maxLoggedBodyBytes))
+    resp.Body = struct {
+        io.Reader
+        io.Closer
+    }{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+    return data, err
+}
+
+// isLoggableBody reports whether a body of contentType is text worth logging
+func isLoggableBody(contentType string) bool {
+    return strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/")
+}
+
+// redactBody hides phone numbers in a logged body
+func redactBody(body []byte) string {
+    body = phoneFieldPattern.ReplaceAll(body, []byte(`"$1"$2"`+redacted+`"`))
+    body = phoneNumberPattern.ReplaceAll(body, []byte(redacted))
+    return string(body)
+}
+
+// redactAuthorization keeps the scheme of an Authorization header and hides the credentials
+func redactAuthorization(value string) string {
+    if value == "" {
+        return ""
+    }
+    if scheme, _, ok := strings.Cut(value, " "); ok {
+        return scheme + " " + redacted
+    }
+    return redacted
+}
@@ -0,0 +1,287 @@
+// Package whatsapp provides media upload support for the WhatsApp Business API client
+// Version: go1.21
+package whatsapp
+
+import (
+    "bytes"           // go1.21
+    "context"         // go1.21
+    "encoding/json"   // go1.21
+    "errors"          // go1.21
+    "fmt"             // go1.21
+    "io"              // go1.21
+    "mime/multipart"  // go1.21
+    "net/http"        // go1.21
+    "os"              // go1.21
+    "strings"         // go1.21
+    "sync"            // go1.21
+    "time"            // go1.21
+)
+
+// Media size limits
+const (
+    maxMediaUploadSize = 100 * 1024 * 1024 // 100MB accepted by the WhatsApp upload endpoint
+    maxMediaSize       = 16 * 1024 * 1024  // 16MB accepted for a message attachment
+)
+
+// mediaIDTTL is how long an uploaded media ID is reused; WhatsApp keeps uploads for 30 days
+const mediaIDTTL = 29 * 24 * time.Hour
+
+// mediaCache maps the hash of uploaded local files to their media IDs so re-sending the same
+// file does not upload it again. The zero value is empty.
+type mediaCache struct {
+    mu  sync.Mutex
+    ids map[string]cachedMediaID
+}
+
+type cachedMediaID struct {
+    id         string
+    uploadedAt time.Time
+}
+
+// Media upload errors
+var (
+    ErrMediaTooLarge      = errors.New("media exceeds maximum upload size")
+    ErrMediaLengthUnknown = errors.New("media source did not report a content length")
+    ErrInvalidMediaSource = errors.New("invalid media source URL")
+)
+
+// mediaUploadResponse is the WhatsApp response to a media upload
+type mediaUploadResponse struct {
+    ID    string    `json:"id"`
+    Error *APIError `json:"error,omitempty"`
+}
+
+// UploadMediaFromURL streams media from a (typically presigned) source URL to the
+// WhatsApp media endpoint and returns the uploaded media ID. The file is piped through
+// a multipart encoder without being buffered in memory; the source must report a
+// Content-Length within the upload size limit.
+func (c *Client) UploadMediaFromURL(ctx context.Context, sourceURL, mimeType string) (string, error) {
+    if err := c.checkInitialized(); err != nil {
+        return "", err
+    }
+    if sourceURL == "" {
+        return "", ErrInvalidMediaSource
+    }
+    if mimeType == "" {
+        return "", errors.New("mime type is required")
+    }
+
+    srcReq, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+    if err != nil {
+        return "", fmt.Errorf("%w: %v", ErrInvalidMediaSource, err)
+    }
+
+    // The source is fetched without API credentials; presigned URLs carry their own auth
+    srcResp, err := c.httpClient.Do(srcReq)
+    if err != nil {
+        return "", fmt.Errorf("fetch media source: %w", err)
+    }
+    defer srcResp.Body.Close()
+
+    if srcResp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("fetch media source: unexpected status %d", srcResp.StatusCode)
+    }
+    if srcResp.ContentLength < 0 {
+        return "", ErrMediaLengthUnknown
+    }
+    if srcResp.ContentLength > maxMediaUploadSize {
+        return "", fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, srcResp.ContentLength)
+    }
+
+    return c.uploadMedia(ctx, srcResp.Body, srcResp.ContentLength, mimeType)
+}
+
+// UploadMedia uploads media read from reader to the WhatsApp media endpoint and returns the
+// media ID. Files, byte and string readers are streamed; other readers are buffered up to
+// the upload size limit so their length can be checked before uploading.
+func (c *Client) UploadMedia(ctx context.Context, reader io.Reader, mediaType string) (string, error) {
+    if err := c.checkInitialized(); err != nil {
+        return "", err
+    }
+    if reader == nil {
+        return "", ErrInvalidMediaSource
+    }
+    if mediaType == "" {
+        return "", errors.New("mime type is required")
+    }
+
+    length, ok := mediaLength(reader)
+    if !ok {
+        data, err := io.ReadAll(io.LimitReader(reader, maxMediaUploadSize+1))
+        if err != nil {
+            return "", fmt.Errorf("read media: %w", err)
+        }
+        reader, length = bytes.NewReader(data), int64(len(data))
+    }
+    if length > maxMediaUploadSize {
+        return "", fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, length)
+    }
+
+    return c.uploadMedia(ctx, reader, length, mediaType)
+}
+
+// uploadMedia streams length bytes of src to the WhatsApp media endpoint as a multipart form
+func (c *Client) uploadMedia(ctx context.Context, src io.Reader, length int64, mimeType string) (string, error) {
+    pr, pw := io.Pipe()
+    defer pr.Close() // unblocks the encoder if the upload returns before consuming the body
+    form := multipart.NewWriter(pw)
+
+    // Encode the multipart body as the source is read; errors surface to the upload request via the pipe
+    go func() {
+        pw.CloseWithError(writeMediaForm(form, src, length, mimeType))
+    }()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiEndpoint+"/media", pr)
+    if err != nil {
+        return "", fmt.Errorf("create request: %w", err)
+    }
+
+    c.setRequestHeaders(req)
+    req.Header.Set("Content-Type", form.FormDataContentType())
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        c.metrics.RecordError("upload_media", err)
+        return "", fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    var uploadResp mediaUploadResponse
+    if err := json.NewDecoder(resp.Body).Decode(&uploadResp); err != nil {
+        return "", fmt.Errorf("decode response: %w", err)
+    }
+    if uploadResp.Error != nil {
+        err := fmt.Errorf("API error: %s", uploadResp.Error.Message)
+        c.metrics.RecordError("upload_media", err)
+        return "", err
+    }
+    if uploadResp.ID == "" {
+        return "", errors.New("upload response missing media ID")
+    }
+
+    c.metrics.RecordSuccess("upload_media")
+    return uploadResp.ID, nil
+}
+
+// writeMediaForm writes the upload form fields and copies the media into the file part.
+// The copy is bounded by the declared length so a source sending more data than it
+// reported cannot exceed the limit.
+func writeMediaForm(form *multipart.Writer, src io.Reader, length int64, mimeType string) error {
+    if err := form.WriteField("messaging_product", "whatsapp"); err != nil {
+        return err
+    }
+    if err := form.WriteField("type", mimeType); err != nil {
+        return err
+    }
+
+    part, err := form.CreateFormFile("file", "media")
+    if err != nil {
+        return err
+    }
+
+    n, err := io.Copy(part, io.LimitReader(src, length+1))
+    if err != nil {
+        return fmt.Errorf("stream media: %w", err)
+    }
+    if n > length {
+        return fmt.Errorf("%w: source sent more than its declared %d bytes", ErrMediaTooLarge, length)
+    }
+
+    return form.Close()
+}
+
+// mediaLength reports the remaining length of readers that know it without being read
+func mediaLength(reader io.Reader) (int64, bool) {
+    switch r := reader.(type) {
+    case interface{ Len() int }:
+        return int64(r.Len()), true
+    case *os.File:
+        info, err := r.Stat()
+        if err != nil || !info.Mode().IsRegular() {
+            return 0, false
+        }
+        offset, err := r.Seek(0, io.SeekCurrent)
+        if err != nil {
+            return 0, false
+        }
+        return info.Size() - offset, true
+    }
+    return 0, false
+}
+
+// isLocalMedia reports whether a media URL names a local file rather than a remote URL
+func isLocalMedia(mediaURL string) bool {
+    return mediaURL != "" && (strings.HasPrefix(mediaURL, "file://") || !strings.Contains(mediaURL, "://"))
+}
+
+// prepareMedia uploads a message's local media file and returns a copy of the message that
+// references the uploaded media ID. Messages without local media are returned unchanged.
+// Uploads are reused by media hash, so resending the same file does not upload it again.
+func (c *Client) prepareMedia(ctx context.Context, message *Message) (*Message, error) {
+    if message.Content.MediaID != "" || !isLocalMedia(message.Content.MediaURL) {
+        return message, nil
+    }
+
+    mediaID, ok := c.media.get(message.Content.MediaHash)
+    if !ok {
+        path := strings.TrimPrefix(message.Content.MediaURL, "file://")
+        file, err := os.Open(path)
+        if err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidMediaSource, err)
+        }
+        defer file.Close()
+
+        info, err := file.Stat()
+        if err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidMediaSource, err)
+        }
+        if info.Size() > maxMediaSize {
+            return nil, fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, info.Size())
+        }
+
+        mediaID, err = c.UploadMedia(ctx, file, message.Content.MediaType)
+        if err != nil {
+            return nil, fmt.Errorf("upload media: %w", err)
+        }
+        c.media.put(message.Content.MediaHash, mediaID)
+    }
+
+    prepared := *message
+    prepared.Content.MediaID = mediaID
+    prepared.Content.MediaURL = ""
+    return &prepared, nil
+}
+
+// get returns the cached media ID for a hash, if it has not expired
+func (mc *mediaCache) get(hash string) (string, bool) {
+    if hash == "" {
+        return "", false
+    }
+
+    mc.mu.Lock()
+    defer mc.mu.Unlock()
+
+    cached, ok := mc.ids[hash]
+    if !ok {
+        return "", false
+    }
+    if time.Since(cached.uploadedAt) > mediaIDTTL {
+        delete(mc.ids, hash)
+        return "", false
+    }
+    return cached.id, true
+}
+
+func (mc *mediaCache) put(hash, id string) {
+    if hash == "" {
+        return
+    }
+
+    mc.mu.Lock()
+    defer mc.mu.Unlock()
+
+    if mc.ids == nil {
+        mc.ids = make(map[string]cachedMediaID)
+    }
+    mc.ids[hash] = cachedMediaID{id: id, uploadedAt: time.Now()}
+}
@@ -0,0 +1,152 @@
+// Package whatsapp provides download of inbound media from the WhatsApp Business API
+// Version: go1.21
+package whatsapp
+
+import (
+    "context"       // go1.21
+    "encoding/json" // go1.21
+    "errors"        // go1.21
+    "fmt"           // go1.21
+    "io"            // go1.21
+    "net/http"      // go1.21
+    "net/url"       // go1.21
+)
+
+// MediaMeta describes downloaded media. Size is the length reported by the API, or -1 when
+// it is unknown.
+type MediaMeta struct {
+    ID          string
+    ContentType string
+    Size        int64
+    SHA256      string
+}
+
+// mediaInfoResponse is the WhatsApp response resolving a media ID to its download URL
+type mediaInfoResponse struct {
+    ID       string    `json:"id"`
+    URL      string    `json:"url"`
+    MimeType string    `json:"mime_type"`
+    SHA256   string    `json:"sha256"`
+    FileSize int64     `json:"file_size"`
+    Error    *APIError `json:"error,omitempty"`
+}
+
+// DownloadMedia streams the media a webhook referenced by mediaID. The short-lived download
+// URL is resolved through the media endpoint and fetched with the API credentials. The
+// caller must close the returned reader, which fails with ErrMediaTooLarge once more than
+// the attachment size limit has been read.
+func (c *Client) DownloadMedia(ctx context.Context, mediaID string) (io.ReadCloser, *MediaMeta, error) {
+    if err := c.checkInitialized(); err != nil {
+        return nil, nil, err
+    }
+    if mediaID == "" {
+        return nil, nil, errors.New("media ID is required")
+    }
+
+    info, err := c.resolveMedia(ctx, mediaID)
+    if err != nil {
+        c.metrics.RecordError("download_media", err)
+        return nil, nil, err
+    }
+    if info.FileSize > maxMediaSize {
+        err := fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, info.FileSize)
+        c.metrics.RecordError("download_media", err)
+        return nil, nil, err
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, info.URL, nil)
+    if err != nil {
+        return nil, nil, fmt.Errorf("create request: %w", err)
+    }
+    // Media URLs only serve requests carrying the API credentials
+    req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        c.metrics.RecordError("download_media", err)
+        return nil, nil, fmt.Errorf("do request: %w", err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        defer resp.Body.Close()
+        httpErr := newHTTPError(resp)
+        c.metrics.RecordError("download_media", httpErr)
+        return nil, nil, httpErr
+    }
+    if resp.ContentLength > maxMediaSize {
+        resp.Body.Close()
+        err := fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, resp.ContentLength)
+        c.metrics.RecordError("download_media", err)
+        return nil, nil, err
+    }
+
+    meta := &MediaMeta{
+        ID:          mediaID,
+        ContentType: info.MimeType,
+        Size:        info.FileSize,
+        SHA256:      info.SHA256,
+    }
+    if meta.ContentType == "" {
+        meta.ContentType = resp.Header.Get("Content-Type")
+    }
+    if meta.Size <= 0 {
+        meta.Size = resp.ContentLength
+    }
+
+    c.metrics.RecordSuccess("download_media")
+    return &limitedMediaReader{body: resp.Body, limit: maxMediaSize}, meta, nil
+}
+
+// resolveMedia looks up the download URL and details of a media ID
+func (c *Client) resolveMedia(ctx context.Context, mediaID string) (*mediaInfoResponse, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiEndpoint+"/"+url.PathEscape(mediaID), nil)
+    if err != nil {
+        return nil, fmt.Errorf("create request: %w", err)
+    }
+    c.setRequestHeaders(req)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    var info mediaInfoResponse
+    if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+        return nil, fmt.Errorf("decode response: %w", err)
+    }
+    if info.Error != nil {
+        return nil, fmt.Errorf("API error: %w", info.Error)
+    }
+    if info.URL == "" {
+        return nil, errors.New("media response missing download URL")
+    }
+    return &info, nil
+}
+
+// limitedMediaReader reads a media body, failing with ErrMediaTooLarge once it yields more
+// than limit bytes
+type limitedMediaReader struct {
+    body  io.ReadCloser
+    limit int64
+    read  int64
+}
+
+func (r *limitedMediaReader) Read(p []byte) (int, error) {
+    if r.read > r.limit {
+        return 0, fmt.Errorf("%w: more than %d bytes", ErrMediaTooLarge, r.limit)
+    }
+    // Read at most one byte past the limit so a body of exactly the limit ends cleanly
+    if max := r.limit - r.read + 1; int64(len(p)) > max {
+        p = p[:max]
+    }
+    n, err := r.body.Read(p)
+    r.read += int64(n)
+    if r.read > r.limit {
+        return n - int(r.read-r.limit), fmt.Errorf("%w: more than %d bytes", ErrMediaTooLarge, r.limit)
+    }
+    return n, err
+}
+
+func (r *limitedMediaReader) Close() error {
+    return r.body.Close()
+}
@@ -0,0 +1,66 @@
+package whatsapp
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "strings"
+    "testing"
+    "testing/iotest"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func readLimited(body string, limit int64, oneByte bool) ([]byte, error) {
+    var reader io.Reader = strings.NewReader(body)
+    if oneByte {
+        reader = iotest.OneByteReader(reader)
+    }
+    limited := &limitedMediaReader{body: io.NopCloser(reader), limit: limit}
+    defer limited.Close()
+    return io.ReadAll(limited)
+}
+
+func TestLimitedMediaReaderBoundaries(t *testing.T) {
+    tests := []struct {
+        name   string
+        size   int
+        limit  int64
+        tooBig bool
+    }{
+        {"empty", 0, 10, false},
+        {"under the limit", 9, 10, false},
+        {"exactly the limit", 10, 10, false},
+        {"one byte over", 11, 10, true},
+        {"far over", 1000, 10, true},
+        {"zero limit", 1, 0, true},
+    }
+
+    for _, tt := range tests {
+        for _, oneByte := range []bool{false, true} {
+            t.Run(tt.name, func(t *testing.T) {
+                body := strings.Repeat("x", tt.size)
+                data, err := readLimited(body, tt.limit, oneByte)
+
+                if tt.tooBig {
+                    assert.True(t, errors.Is(err, ErrMediaTooLarge))
+                    assert.LessOrEqual(t, int64(len(data)), tt.limit, "no byte past the limit is returned")
+                    return
+                }
+                assert.NoError(t, err)
+                assert.True(t, bytes.Equal([]byte(body), data))
+            })
+        }
+    }
+}
+
+func TestLimitedMediaReaderStaysFailed(t *testing.T) {
+    limited := &limitedMediaReader{body: io.NopCloser(strings.NewReader("abcdef")), limit: 3}
+
+    _, err := io.ReadAll(limited)
+    assert.True(t, errors.Is(err, ErrMediaTooLarge))
+
+    n, err := limited.Read(make([]byte, 8))
+    assert.Equal(t, 0, n)
+    assert.True(t, errors.Is(err, ErrMediaTooLarge))
+}
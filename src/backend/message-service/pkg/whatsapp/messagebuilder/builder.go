@@ -0,0 +1,165 @@
+// Package messagebuilder provides fluent builders for constructing validated WhatsApp messages
+// Version: go1.21
+package messagebuilder
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/yourdomain/message-service/internal/utils"
+    "github.com/yourdomain/message-service/pkg/whatsapp/types"
+)
+
+// Parameter type used for plain text template parameters
+const textParameterType = "text"
+
+// TextMessageBuilder builds plain text messages
+type TextMessageBuilder struct {
+    msg *types.Message
+}
+
+// NewTextMessage starts a text message to the given recipient
+func NewTextMessage(to, text string) *TextMessageBuilder {
+    return &TextMessageBuilder{
+        msg: &types.Message{
+            To:   to,
+            Type: types.MessageTypeText,
+            Content: types.MessageContent{
+                Text: text,
+            },
+        },
+    }
+}
+
+// WithID sets the message ID used for tracking
+func (b *TextMessageBuilder) WithID(id string) *TextMessageBuilder {
+    b.msg.ID = id
+    return b
+}
+
+// WithPreviewURL sets the URL rendered as a link preview
+func (b *TextMessageBuilder) WithPreviewURL(url string) *TextMessageBuilder {
+    b.msg.Content.PreviewURL = url
+    return b
+}
+
+// WithPreviewDisabled disables link previews for the message
+func (b *TextMessageBuilder) WithPreviewDisabled() *TextMessageBuilder {
+    b.msg.Content.PreviewURL = ""
+    return b
+}
+
+// WithFormatting applies rich text formatting to the message text
+func (b *TextMessageBuilder) WithFormatting(formatting *types.MessageFormatting) *TextMessageBuilder {
+    b.msg.Content.RichText = formatting != nil
+    b.msg.Content.Formatting = formatting
+    return b
+}
+
+// WithMetadata attaches a metadata key/value pair to the message
+func (b *TextMessageBuilder) WithMetadata(key string, value interface{}) *TextMessageBuilder {
+    if b.msg.Metadata == nil {
+        b.msg.Metadata = make(map[string]interface{})
+    }
+    b.msg.Metadata[key] = value
+    return b
+}
+
+// Build validates and returns the constructed message
+func (b *TextMessageBuilder) Build() (*types.Message, error) {
+    if b.msg.Content.Text == "" {
+        return nil, errors.New("text message requires text content")
+    }
+    return build(b.msg)
+}
+
+// TemplateMessageBuilder builds template messages
+type TemplateMessageBuilder struct {
+    msg    *types.Message
+    header []types.Parameter
+    body   []types.Parameter
+}
+
+// NewTemplateMessage starts a template message to the given recipient
+func NewTemplateMessage(to, name, language string) *TemplateMessageBuilder {
+    return &TemplateMessageBuilder{
+        msg: &types.Message{
+            To:   to,
+            Type: types.MessageTypeTemplate,
+            Template: &types.Template{
+                Name:     name,
+                Language: language,
+            },
+        },
+    }
+}
+
+// WithID sets the message ID used for tracking
+func (b *TemplateMessageBuilder) WithID(id string) *TemplateMessageBuilder {
+    b.msg.ID = id
+    return b
+}
+
+// WithCategory sets the template category
+func (b *TemplateMessageBuilder) WithCategory(category string) *TemplateMessageBuilder {
+    b.msg.Template.Category = category
+    return b
+}
+
+// WithHeaderParam appends a text parameter to the template header
+func (b *TemplateMessageBuilder) WithHeaderParam(value string) *TemplateMessageBuilder {
+    b.header = append(b.header, types.Parameter{Type: textParameterType, Value: value})
+    return b
+}
+
+// WithBodyParam appends a text parameter to the template body
+func (b *TemplateMessageBuilder) WithBodyParam(value string) *TemplateMessageBuilder {
+    b.body = append(b.body, types.Parameter{Type: textParameterType, Value: value})
+    return b
+}
+
+// WithBodyParameter appends a fully specified parameter to the template body
+func (b *TemplateMessageBuilder) WithBodyParameter(param types.Parameter) *TemplateMessageBuilder {
+    b.body = append(b.body, param)
+    return b
+}
+
+// WithMetadata attaches a metadata key/value pair to the message
+func (b *TemplateMessageBuilder) WithMetadata(key string, value interface{}) *TemplateMessageBuilder {
+    if b.msg.Metadata == nil {
+        b.msg.Metadata = make(map[string]interface{})
+    }
+    b.msg.Metadata[key] = value
+    return b
+}
+
+// Build assembles the template components, validates and returns the constructed message
+func (b *TemplateMessageBuilder) Build() (*types.Message, error) {
+    components := make([]types.TemplateComponent, 0, 2)
+    if len(b.header) > 0 {
+        components = append(components, types.TemplateComponent{
+            Type:       types.ComponentTypeHeader,
+            Parameters: b.header,
+            Index:      len(components),
+        })
+    }
+
+    // A body component is always present, even without parameters
+    components = append(components, types.TemplateComponent{
+        Type:       types.ComponentTypeBody,
+        Parameters: b.body,
+        Index:      len(components),
+        Required:   true,
+    })
+    b.msg.Template.Components = components
+
+    return build(b.msg)
+}
+
+// build runs the shared message validation on a constructed message
+func build(msg *types.Message) (*types.Message, error) {
+    if err := utils.ValidateMessage(msg); err != nil {
+        return nil, fmt.Errorf("build %s message: %w", msg.Type, err)
+    }
+    return msg, nil
+}
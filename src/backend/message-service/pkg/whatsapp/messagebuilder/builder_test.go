@@ -0,0 +1,214 @@
+package messagebuilder
+
+import (
+    "errors"
+    "strings"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+
+    "github.com/yourdomain/message-service/internal/utils"
+    "github.com/yourdomain/message-service/pkg/whatsapp/types"
+)
+
+const recipient = "+14155550100"
+
+func TestTextMessageBuilder(t *testing.T) {
+    formatting := &types.MessageFormatting{Bold: []types.TextRange{{Start: 0, Length: 5}}}
+
+    tests := []struct {
+        name    string
+        builder *TextMessageBuilder
+        want    *types.Message
+    }{
+        {
+            name:    "plain text",
+            builder: NewTextMessage(recipient, "hello there").WithID("msg-1"),
+            want: &types.Message{
+                ID:      "msg-1",
+                To:      recipient,
+                Type:    types.MessageTypeText,
+                Content: types.MessageContent{Text: "hello there"},
+            },
+        },
+        {
+            name:    "link preview",
+            builder: NewTextMessage(recipient, "see https://example.com").WithPreviewURL("https://example.com"),
+            want: &types.Message{
+                To:      recipient,
+                Type:    types.MessageTypeText,
+                Content: types.MessageContent{Text: "see https://example.com", PreviewURL: "https://example.com"},
+            },
+        },
+        {
+            name:    "preview disabled",
+            builder: NewTextMessage(recipient, "see https://example.com").WithPreviewURL("https://example.com").WithPreviewDisabled(),
+            want: &types.Message{
+                To:      recipient,
+                Type:    types.MessageTypeText,
+                Content: types.MessageContent{Text: "see https://example.com"},
+            },
+        },
+        {
+            name:    "formatting",
+            builder: NewTextMessage(recipient, "hello there").WithFormatting(formatting),
+            want: &types.Message{
+                To:      recipient,
+                Type:    types.MessageTypeText,
+                Content: types.MessageContent{Text: "hello there", RichText: true, Formatting: formatting},
+            },
+        },
+        {
+            name:    "metadata",
+            builder: NewTextMessage(recipient, "hello there").WithMetadata("campaign", "spring").WithMetadata("attempt", 2),
+            want: &types.Message{
+                To:       recipient,
+                Type:     types.MessageTypeText,
+                Content:  types.MessageContent{Text: "hello there"},
+                Metadata: map[string]interface{}{"campaign": "spring", "attempt": 2},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            msg, err := tt.builder.Build()
+            require.NoError(t, err)
+            assert.Equal(t, tt.want, msg)
+        })
+    }
+}
+
+func TestTemplateMessageBuilder(t *testing.T) {
+    currency := types.Parameter{Type: textParameterType, Value: "12.50 USD", Format: types.ParameterFormatCurrency}
+
+    tests := []struct {
+        name    string
+        builder *TemplateMessageBuilder
+        want    *types.Template
+    }{
+        {
+            name:    "body without parameters",
+            builder: NewTemplateMessage(recipient, "welcome", "en_US"),
+            want: &types.Template{
+                Name:     "welcome",
+                Language: "en_US",
+                Components: []types.TemplateComponent{
+                    {Type: types.ComponentTypeBody, Index: 0, Required: true},
+                },
+            },
+        },
+        {
+            name: "header and body parameters",
+            builder: NewTemplateMessage(recipient, "order_update", "en_US").
+                WithCategory("UTILITY").
+                WithHeaderParam("Order 42").
+                WithBodyParam("Ada").
+                WithBodyParameter(currency),
+            want: &types.Template{
+                Name:     "order_update",
+                Language: "en_US",
+                Category: "UTILITY",
+                Components: []types.TemplateComponent{
+                    {
+                        Type:       types.ComponentTypeHeader,
+                        Parameters: []types.Parameter{{Type: textParameterType, Value: "Order 42"}},
+                        Index:      0,
+                    },
+                    {
+                        Type:       types.ComponentTypeBody,
+                        Parameters: []types.Parameter{{Type: textParameterType, Value: "Ada"}, currency},
+                        Index:      1,
+                        Required:   true,
+                    },
+                },
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            msg, err := tt.builder.WithID("msg-1").WithMetadata("source", "test").Build()
+            require.NoError(t, err)
+            assert.Equal(t, "msg-1", msg.ID)
+            assert.Equal(t, recipient, msg.To)
+            assert.Equal(t, types.MessageTypeTemplate, msg.Type)
+            assert.Equal(t, map[string]interface{}{"source": "test"}, msg.Metadata)
+            assert.Equal(t, tt.want, msg.Template)
+        })
+    }
+}
+
+func TestBuildersSurfaceValidationErrors(t *testing.T) {
+    tests := []struct {
+        name  string
+        build func() (*types.Message, error)
+        want  error
+        code  string
+    }{
+        {
+            name:  "text without content",
+            build: NewTextMessage(recipient, "").Build,
+        },
+        {
+            name:  "text with an invalid recipient",
+            build: NewTextMessage("not-a-number", "hello").Build,
+            want:  utils.ErrInvalidPhoneNumber,
+            code:  utils.CodePhoneInvalidFormat,
+        },
+        {
+            name:  "text without a recipient",
+            build: NewTextMessage("", "hello").Build,
+            want:  utils.ErrInvalidPhoneNumber,
+            code:  utils.CodePhoneRequired,
+        },
+        {
+            name:  "text over the length limit",
+            build: NewTextMessage(recipient, strings.Repeat("a", 4097)).Build,
+            want:  utils.ErrInvalidContent,
+            code:  utils.CodeTextTooLong,
+        },
+        {
+            name:  "template without a name",
+            build: NewTemplateMessage(recipient, "", "en_US").Build,
+            want:  utils.ErrInvalidTemplate,
+            code:  utils.CodeTemplateNameRequired,
+        },
+        {
+            name:  "template without a language",
+            build: NewTemplateMessage(recipient, "welcome", "").Build,
+            want:  utils.ErrInvalidTemplate,
+            code:  utils.CodeTemplateLanguageRequired,
+        },
+        {
+            name: "template with a malformed parameter",
+            build: NewTemplateMessage(recipient, "receipt", "en_US").
+                WithBodyParameter(types.Parameter{Type: textParameterType, Value: "$10", Format: types.ParameterFormatCurrency}).
+                Build,
+            want: utils.ErrInvalidTemplate,
+            code: utils.CodeParameterCurrency,
+        },
+        {
+            name:  "template with an invalid recipient",
+            build: NewTemplateMessage("not-a-number", "welcome", "en_US").Build,
+            want:  utils.ErrInvalidPhoneNumber,
+            code:  utils.CodePhoneInvalidFormat,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            msg, err := tt.build()
+            require.Error(t, err)
+            assert.Nil(t, msg)
+            if tt.want == nil {
+                return
+            }
+            assert.ErrorIs(t, err, tt.want)
+            var validationErr *utils.ValidationError
+            require.True(t, errors.As(err, &validationErr))
+            assert.Equal(t, tt.code, validationErr.Code)
+        })
+    }
+}
@@ -0,0 +1,156 @@
+// Package whatsapp provides Prometheus metrics for WhatsApp Business API client operations
+// Version: go1.21
+package whatsapp
+
+import (
+    "errors"  // go1.21
+    "strconv" // go1.21
+    "time"    // go1.21
+
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+)
+
+const (
+    defaultMetricsNamespace = "whatsapp_client"
+
+    // errorCodeUnknown labels errors that carry no API error code
+    errorCodeUnknown = "unknown"
+)
+
+// MetricsConfig configures the client's metrics. Registry receives the collector and
+// defaults to the Prometheus default registerer; Namespace prefixes the metric names; nil
+// Buckets use the Prometheus default latency buckets.
+type MetricsConfig struct {
+    Registry  *prometheus.Registry
+    Namespace string
+    Buckets   []float64
+}
+
+// MetricsCollector records the outcome and latency of client operations and the webhook
+// events received. It implements prometheus.Collector.
+type MetricsCollector struct {
+    successes *prometheus.CounterVec
+    errors    *prometheus.CounterVec
+    latency   *prometheus.HistogramVec
+    webhooks  *prometheus.CounterVec
+}
+
+// newMetricsCollector creates a collector and registers it. Clients sharing a registry share
+// the collector registered first, so creating several clients does not fail registration.
+func newMetricsCollector(config *MetricsConfig) *MetricsCollector {
+    if config == nil {
+        config = &MetricsConfig{}
+    }
+    namespace := config.Namespace
+    if namespace == "" {
+        namespace = defaultMetricsNamespace
+    }
+    buckets := config.Buckets
+    if len(buckets) == 0 {
+        buckets = prometheus.DefBuckets
+    }
+
+    mc := &MetricsCollector{
+        successes: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Namespace: namespace,
+                Name:      "operations_success_total",
+                Help:      "Total number of successful WhatsApp API operations",
+            },
+            []string{"operation"},
+        ),
+        errors: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Namespace: namespace,
+                Name:      "operations_error_total",
+                Help:      "Total number of failed WhatsApp API operations by API error code",
+            },
+            []string{"operation", "code"},
+        ),
+        latency: prometheus.NewHistogramVec(
+            prometheus.HistogramOpts{
+                Namespace: namespace,
+                Name:      "request_duration_seconds",
+                Help:      "Latency of WhatsApp API requests",
+                Buckets:   buckets,
+            },
+            []string{"operation"},
+        ),
+        webhooks: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Namespace: namespace,
+                Name:      "webhooks_total",
+                Help:      "Total number of webhook events received by type",
+            },
+            []string{"type"},
+        ),
+    }
+
+    var registerer prometheus.Registerer = prometheus.DefaultRegisterer
+    if config.Registry != nil {
+        registerer = config.Registry
+    }
+    if err := registerer.Register(mc); err != nil {
+        var registered prometheus.AlreadyRegisteredError
+        if errors.As(err, &registered) {
+            if existing, ok := registered.ExistingCollector.(*MetricsCollector); ok {
+                return existing
+            }
+        }
+        // The collector still counts when it cannot be exported
+    }
+    return mc
+}
+
+// RecordSuccess counts a successful operation
+func (mc *MetricsCollector) RecordSuccess(operation string) {
+    mc.successes.WithLabelValues(operation).Inc()
+}
+
+// RecordError counts a failed operation, labelled with the API error code when err is or
+// wraps an APIError and with the status when it is an HTTPError
+func (mc *MetricsCollector) RecordError(operation string, err error) {
+    mc.errors.WithLabelValues(operation, errorCode(err)).Inc()
+}
+
+// ObserveLatency records how long a request for operation took
+func (mc *MetricsCollector) ObserveLatency(operation string, duration time.Duration) {
+    mc.latency.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// RecordWebhook counts a received webhook event
+func (mc *MetricsCollector) RecordWebhook(eventType string) {
+    mc.webhooks.WithLabelValues(eventType).Inc()
+}
+
+// Describe implements prometheus.Collector
+func (mc *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+    mc.successes.Describe(ch)
+    mc.errors.Describe(ch)
+    mc.latency.Describe(ch)
+    mc.webhooks.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (mc *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
+    mc.successes.Collect(ch)
+    mc.errors.Collect(ch)
+    mc.latency.Collect(ch)
+    mc.webhooks.Collect(ch)
+}
+
+// errorCode returns the API error code of err as a label value, or the HTTP status when the
+// response carried no API error
+func errorCode(err error) string {
+    var (
+        apiErr  *APIError
+        httpErr *HTTPError
+    )
+    if errors.As(err, &apiErr) && apiErr != nil {
+        return strconv.Itoa(apiErr.Code)
+    }
+    if errors.As(err, &httpErr) && httpErr != nil {
+        return strconv.Itoa(httpErr.StatusCode)
+    }
+    return errorCodeUnknown
+}
@@ -0,0 +1,231 @@
+// Package whatsapp provides token-bucket rate limiting for the WhatsApp Business API client
+// Version: go1.21
+package whatsapp
+
+import (
+    "context" // go1.21
+    "fmt"     // go1.21
+    "math"    // go1.21
+    "sync"    // go1.21
+    "time"    // go1.21
+
+    "github.com/go-redis/redis/v8" // v8.11.5
+)
+
+// Rate limiter backends
+const (
+    RateLimitBackendLocal = "local"
+    RateLimitBackendRedis = "redis"
+)
+
+// RateLimitConfig configures the client's rate limiter. Tokens refill continuously at Limit
+// per hour up to Burst, which defaults to Limit. With WaitMode set, Allow and AllowN block
+// until tokens are available instead of returning ErrRateLimitExceeded.
+// The redis backend shares Limit per hour across all replicas using RedisKey, typically
+// the sending phone number ID; the local backend limits each process on its own.
+// Clock defaults to the system clock.
+type RateLimitConfig struct {
+    Limit    int
+    Burst    int
+    WaitMode bool
+    Backend  string
+    RedisKey string
+    Clock    Clock
+}
+
+// Limiter paces API requests. RateLimiter limits a single process; RedisRateLimiter shares
+// the limit across replicas.
+type Limiter interface {
+    Allow() error
+    AllowN(n int) error
+    WaitAvailable(ctx context.Context) error
+}
+
+// Clock tells a rate limiter the time and waits out its delays, so tests can drive refills
+// without sleeping
+type Clock interface {
+    Now() time.Time
+    After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clockFrom returns the configured clock, or the system clock when none is set
+func clockFrom(config *RateLimitConfig) Clock {
+    if config == nil || config.Clock == nil {
+        return systemClock{}
+    }
+    return config.Clock
+}
+
+// clientLimiter is the Limiter a Client drives: it takes tokens for its own requests and
+// follows the limits the API reports
+type clientLimiter interface {
+    Limiter
+    acquire(ctx context.Context, n int) error
+    applyServerLimits(limit, remaining int, reset time.Time)
+}
+
+// newLimiter selects the configured rate limiter backend. The redis backend falls back to
+// local limiting when no Redis client is provided.
+func newLimiter(config *RateLimitConfig, client *redis.Client) clientLimiter {
+    if config != nil && config.Backend == RateLimitBackendRedis && client != nil {
+        return newRedisRateLimiter(config, client)
+    }
+    return NewRateLimiter(config)
+}
+
+// RateLimiter is a token bucket refilled at the configured hourly rate. Limits reported by
+// the API in response headers act as a ceiling: while the server says fewer requests remain
+// before its reset time, no more than that are allowed, however full the bucket is.
+type RateLimiter struct {
+    rate     float64 // tokens per second
+    burst    float64
+    tokens   float64
+    last     time.Time
+    waitMode bool
+    clock    Clock
+
+    // ceiling is the server-reported remaining budget, enforced until ceilingUntil
+    ceiling      int
+    ceilingUntil time.Time
+
+    mu sync.Mutex
+}
+
+// NewRateLimiter creates a local rate limiter with a full bucket
+func NewRateLimiter(config *RateLimitConfig) *RateLimiter {
+    if config == nil {
+        config = &RateLimitConfig{}
+    }
+
+    limit := config.Limit
+    if limit <= 0 {
+        limit = defaultRateLimit
+    }
+    burst := config.Burst
+    if burst <= 0 {
+        burst = limit
+    }
+
+    clock := clockFrom(config)
+    return &RateLimiter{
+        rate:     float64(limit) / time.Hour.Seconds(),
+        burst:    float64(burst),
+        tokens:   float64(burst),
+        last:     clock.Now(),
+        waitMode: config.WaitMode,
+        clock:    clock,
+    }
+}
+
+// Allow takes a token for one request. It blocks until one is available in wait mode and
+// otherwise returns ErrRateLimitExceeded when the bucket is empty.
+func (r *RateLimiter) Allow() error {
+    return r.AllowN(1)
+}
+
+// AllowN takes n tokens at once, reserving capacity for a batch of sends. Either all n are
+// taken or none are.
+func (r *RateLimiter) AllowN(n int) error {
+    return r.acquire(context.Background(), n)
+}
+
+// WaitAvailable blocks until the limiter has at least one token without consuming it.
+// Callers that share the limiter with a Client use it to pace work ahead of SendMessage,
+// which consumes the token itself.
+func (r *RateLimiter) WaitAvailable(ctx context.Context) error {
+    for {
+        r.mu.Lock()
+        wait := r.waitFor(r.clock.Now(), 1)
+        r.mu.Unlock()
+
+        if wait == 0 {
+            return nil
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-r.clock.After(wait):
+        }
+    }
+}
+
+// acquire takes n tokens, waiting for them in wait mode until ctx is done
+func (r *RateLimiter) acquire(ctx context.Context, n int) error {
+    if n <= 0 {
+        return fmt.Errorf("token count must be positive, got %d", n)
+    }
+    if float64(n) > r.burst {
+        return fmt.Errorf("%w: %d tokens exceed burst capacity %d", ErrRateLimitExceeded, n, int(r.burst))
+    }
+
+    for {
+        r.mu.Lock()
+        now := r.clock.Now()
+        wait := r.waitFor(now, n)
+        if wait == 0 {
+            r.tokens -= float64(n)
+            if now.Before(r.ceilingUntil) {
+                r.ceiling -= n
+            }
+            r.mu.Unlock()
+            return nil
+        }
+        r.mu.Unlock()
+
+        if !r.waitMode {
+            return ErrRateLimitExceeded
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-r.clock.After(wait):
+        }
+    }
+}
+
+// waitFor refills the bucket and returns how long until n tokens are available, or zero if
+// they are available now. The caller must hold the lock.
+func (r *RateLimiter) waitFor(now time.Time, n int) time.Duration {
+    if elapsed := now.Sub(r.last).Seconds(); elapsed > 0 {
+        r.tokens = math.Min(r.burst, r.tokens+elapsed*r.rate)
+        r.last = now
+    }
+
+    if now.Before(r.ceilingUntil) && r.ceiling < n {
+        return r.ceilingUntil.Sub(now)
+    }
+
+    deficit := float64(n) - r.tokens
+    if deficit <= 0 {
+        return 0
+    }
+    return time.Duration(math.Ceil(deficit / r.rate * float64(time.Second)))
+}
+
+// applyServerLimits applies the rate limit headers of an API response. A reported limit
+// becomes the hourly refill rate; a reported remaining count caps the available tokens and,
+// with a reset time, keeps capping them until that time. Negative values were not reported.
+func (r *RateLimiter) applyServerLimits(limit, remaining int, reset time.Time) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if limit > 0 {
+        r.rate = float64(limit) / time.Hour.Seconds()
+    }
+    if remaining < 0 {
+        return
+    }
+
+    r.tokens = math.Min(r.tokens, float64(remaining))
+    if reset.After(r.clock.Now()) {
+        r.ceiling = remaining
+        r.ceilingUntil = reset
+    }
+}
@@ -0,0 +1,210 @@
+// Package whatsapp provides Redis-backed rate limiting shared across client replicas
+// Version: go1.21
+package whatsapp
+
+import (
+    "context"       // go1.21
+    "crypto/rand"   // go1.21
+    "encoding/hex"  // go1.21
+    "fmt"           // go1.21
+    "sync/atomic"   // go1.21
+    "time"          // go1.21
+
+    "github.com/go-redis/redis/v8" // v8.11.5
+)
+
+// Redis rate limiter settings
+const (
+    redisRateLimitKeyPrefix  = "whatsapp:ratelimit:"
+    redisRateLimitWindow     = time.Hour
+    redisRateLimitTimeout    = 500 * time.Millisecond
+    defaultRedisRateLimitKey = "default"
+)
+
+// redisSlidingWindowScript admits ARGV[4] requests when they fit within ARGV[3] requests over
+// the last ARGV[2] milliseconds, recording each under a unique member built from ARGV[5].
+// Zero requests only checks for capacity. It returns {admitted, remaining, retry_after_ms}.
+var redisSlidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local wanted = math.max(n, 1)
+if count + wanted <= limit then
+    for i = 1, n do
+        redis.call('ZADD', KEYS[1], now, ARGV[5] .. ':' .. i)
+    end
+    if n > 0 then
+        redis.call('PEXPIRE', KEYS[1], window)
+    end
+    return {1, limit - count - n, 0}
+end
+local excess = count + wanted - limit
+local oldest = redis.call('ZRANGE', KEYS[1], excess - 1, excess - 1, 'WITHSCORES')
+local retry = 0
+if oldest[2] then
+    retry = tonumber(oldest[2]) + window - now
+end
+return {0, math.max(limit - count, 0), retry}
+`)
+
+// RedisRateLimiter enforces the hourly limit across all replicas with a sliding window kept
+// in Redis. When Redis cannot be reached it degrades to a local token bucket with the same
+// configuration, so each replica keeps limiting itself rather than failing sends.
+type RedisRateLimiter struct {
+    client   *redis.Client
+    key      string
+    limit    int
+    waitMode bool
+    local    *RateLimiter
+    clock    Clock
+    degraded atomic.Bool
+    seq      atomic.Uint64
+    instance string
+}
+
+// newRedisRateLimiter creates a limiter sharing config.Limit per hour under config.RedisKey
+func newRedisRateLimiter(config *RateLimitConfig, client *redis.Client) *RedisRateLimiter {
+    limit := config.Limit
+    if limit <= 0 {
+        limit = defaultRateLimit
+    }
+    key := config.RedisKey
+    if key == "" {
+        key = defaultRedisRateLimitKey
+    }
+
+    return &RedisRateLimiter{
+        client:   client,
+        key:      redisRateLimitKeyPrefix + key,
+        limit:    limit,
+        waitMode: config.WaitMode,
+        local:    NewRateLimiter(config),
+        clock:    clockFrom(config),
+        instance: newLimiterInstanceID(),
+    }
+}
+
+// Allow takes one request from the shared budget
+func (r *RedisRateLimiter) Allow() error {
+    return r.AllowN(1)
+}
+
+// AllowN takes n requests from the shared budget at once; either all are taken or none are
+func (r *RedisRateLimiter) AllowN(n int) error {
+    return r.acquire(context.Background(), n)
+}
+
+// WaitAvailable blocks until the shared budget has capacity without consuming it
+func (r *RedisRateLimiter) WaitAvailable(ctx context.Context) error {
+    for {
+        admitted, _, wait, err := r.run(ctx, 0)
+        if err != nil {
+            return r.local.WaitAvailable(ctx)
+        }
+        if admitted {
+            return nil
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-r.clock.After(wait):
+        }
+    }
+}
+
+// Remaining returns how many requests the shared budget has left in the current window
+func (r *RedisRateLimiter) Remaining(ctx context.Context) (int, error) {
+    _, remaining, _, err := r.run(ctx, 0)
+    if err != nil {
+        return 0, err
+    }
+    return remaining, nil
+}
+
+// Degraded reports whether the last Redis call failed and limiting fell back to local
+func (r *RedisRateLimiter) Degraded() bool {
+    return r.degraded.Load()
+}
+
+// acquire takes n requests from the shared budget, waiting for them in wait mode
+func (r *RedisRateLimiter) acquire(ctx context.Context, n int) error {
+    if n <= 0 {
+        return fmt.Errorf("token count must be positive, got %d", n)
+    }
+    if n > r.limit {
+        return fmt.Errorf("%w: %d requests exceed the limit of %d", ErrRateLimitExceeded, n, r.limit)
+    }
+
+    for {
+        admitted, _, wait, err := r.run(ctx, n)
+        if err != nil {
+            return r.local.acquire(ctx, n)
+        }
+        if admitted {
+            return nil
+        }
+        if !r.waitMode {
+            return ErrRateLimitExceeded
+        }
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-r.clock.After(wait):
+        }
+    }
+}
+
+// applyServerLimits keeps the local fallback in line with the limits the API reports
+func (r *RedisRateLimiter) applyServerLimits(limit, remaining int, reset time.Time) {
+    r.local.applyServerLimits(limit, remaining, reset)
+}
+
+// run executes the sliding window script for n requests, marking the limiter degraded
+// when Redis fails
+func (r *RedisRateLimiter) run(ctx context.Context, n int) (bool, int, time.Duration, error) {
+    ctx, cancel := context.WithTimeout(ctx, redisRateLimitTimeout)
+    defer cancel()
+
+    res, err := redisSlidingWindowScript.Run(ctx, r.client,
+        []string{r.key},
+        r.clock.Now().UnixMilli(),
+        redisRateLimitWindow.Milliseconds(),
+        r.limit,
+        n,
+        fmt.Sprintf("%s:%d", r.instance, r.seq.Add(1)),
+    ).Result()
+    if err != nil {
+        r.degraded.Store(true)
+        return false, 0, 0, fmt.Errorf("redis rate limit: %w", err)
+    }
+
+    values, ok := res.([]interface{})
+    if !ok || len(values) != 3 {
+        r.degraded.Store(true)
+        return false, 0, 0, fmt.Errorf("unexpected redis rate limit result %v", res)
+    }
+    r.degraded.Store(false)
+
+    admitted, _ := values[0].(int64)
+    remaining, _ := values[1].(int64)
+    retryAfter, _ := values[2].(int64)
+    wait := time.Duration(retryAfter) * time.Millisecond
+    if wait <= 0 {
+        wait = time.Millisecond
+    }
+    return admitted == 1, int(remaining), wait, nil
+}
+
+// newLimiterInstanceID distinguishes this process's entries in the shared window
+func newLimiterInstanceID() string {
+    b := make([]byte, 8)
+    if _, err := rand.Read(b); err != nil {
+        return fmt.Sprintf("%d", time.Now().UnixNano())
+    }
+    return hex.EncodeToString(b)
+}
@@ -0,0 +1,145 @@
+package whatsapp
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// fakeClock stands still until a limiter waits on it, then jumps ahead by the wait
+type fakeClock struct {
+    mu    sync.Mutex
+    now   time.Time
+    waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.now = c.now.Add(d)
+    c.waits = append(c.waits, d)
+    fired := make(chan time.Time, 1)
+    fired <- c.now
+    return fired
+}
+
+func TestRateLimiterDefaults(t *testing.T) {
+    r := NewRateLimiter(nil)
+
+    assert.Equal(t, float64(defaultRateLimit), r.burst)
+    assert.Equal(t, float64(defaultRateLimit), r.tokens)
+    assert.InDelta(t, float64(defaultRateLimit)/3600, r.rate, 1e-12)
+}
+
+func TestRateLimiterWaitForRefill(t *testing.T) {
+    // One token per second up to a burst of 10
+    r := NewRateLimiter(&RateLimitConfig{Limit: 3600, Burst: 10})
+    start := time.Now()
+    r.tokens, r.last = 0, start
+
+    assert.Equal(t, time.Second, r.waitFor(start, 1))
+    assert.Equal(t, 500*time.Millisecond, r.waitFor(start.Add(500*time.Millisecond), 1))
+    assert.Equal(t, time.Duration(0), r.waitFor(start.Add(time.Second), 1))
+    assert.Equal(t, 2*time.Second, r.waitFor(start.Add(time.Second), 3))
+
+    // A long idle period refills only up to the burst
+    r.waitFor(start.Add(time.Hour), 1)
+    assert.Equal(t, 10.0, r.tokens)
+}
+
+func TestRateLimiterAllowN(t *testing.T) {
+    r := NewRateLimiter(&RateLimitConfig{Limit: 1, Burst: 3})
+
+    require.NoError(t, r.AllowN(2))
+    assert.True(t, errors.Is(r.AllowN(2), ErrRateLimitExceeded), "only one token is left")
+    require.NoError(t, r.Allow())
+    assert.True(t, errors.Is(r.Allow(), ErrRateLimitExceeded))
+
+    assert.True(t, errors.Is(r.AllowN(4), ErrRateLimitExceeded), "more than the burst can never be taken")
+    assert.Error(t, r.AllowN(0))
+}
+
+func TestRateLimiterWaitModeHonoursContext(t *testing.T) {
+    r := NewRateLimiter(&RateLimitConfig{Limit: 1, Burst: 1, WaitMode: true})
+    require.NoError(t, r.Allow())
+
+    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+    defer cancel()
+    assert.ErrorIs(t, r.acquire(ctx, 1), context.DeadlineExceeded)
+}
+
+func TestRateLimiterWaitsOnInjectedClock(t *testing.T) {
+    start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+    clock := &fakeClock{now: start}
+    // One token per second with room for one
+    var limiter Limiter = NewRateLimiter(&RateLimitConfig{Limit: 3600, Burst: 1, WaitMode: true, Clock: clock})
+
+    require.NoError(t, limiter.Allow())
+    assert.Empty(t, clock.waits, "the first call is served from the full bucket")
+
+    require.NoError(t, limiter.Allow())
+    assert.Equal(t, []time.Duration{time.Second}, clock.waits, "the second call waits exactly one refill")
+    assert.Equal(t, start.Add(time.Second), clock.Now())
+}
+
+func TestRateLimiterServerLimits(t *testing.T) {
+    r := NewRateLimiter(&RateLimitConfig{Limit: 3600, Burst: 100})
+    now := time.Now()
+    reset := now.Add(time.Minute)
+
+    r.applyServerLimits(7200, 2, reset)
+
+    assert.InDelta(t, 2.0, r.rate, 1e-9, "the reported limit becomes the refill rate")
+    assert.Equal(t, 2.0, r.tokens, "the remaining count caps the bucket")
+    assert.Equal(t, time.Duration(0), r.waitFor(now, 2))
+
+    // The ceiling holds until the reset however much the bucket refills
+    r.tokens = 100
+    r.ceiling = 0
+    assert.InDelta(t, float64(time.Minute), float64(r.waitFor(now, 1)), float64(time.Second))
+    assert.Equal(t, time.Duration(0), r.waitFor(reset.Add(time.Millisecond), 1))
+}
+
+func TestRateLimiterIgnoresUnreportedServerLimits(t *testing.T) {
+    r := NewRateLimiter(&RateLimitConfig{Limit: 3600, Burst: 5})
+
+    r.applyServerLimits(0, -1, time.Time{})
+
+    assert.InDelta(t, 1.0, r.rate, 1e-9)
+    assert.Equal(t, 5.0, r.tokens)
+}
+
+func TestRedisRateLimiterFallsBackToLocal(t *testing.T) {
+    // Nothing listens on port 1, so every Redis call fails
+    client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+    defer client.Close()
+
+    r := newRedisRateLimiter(&RateLimitConfig{Limit: 2, Burst: 2, Backend: RateLimitBackendRedis}, client)
+
+    require.NoError(t, r.Allow())
+    assert.True(t, r.Degraded())
+    require.NoError(t, r.Allow())
+    assert.True(t, errors.Is(r.Allow(), ErrRateLimitExceeded), "the local bucket keeps limiting")
+    assert.True(t, errors.Is(r.AllowN(3), ErrRateLimitExceeded), "more than the limit is rejected before Redis")
+}
+
+func TestNewLimiterSelectsBackend(t *testing.T) {
+    client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+    defer client.Close()
+
+    assert.IsType(t, &RateLimiter{}, newLimiter(nil, client))
+    assert.IsType(t, &RateLimiter{}, newLimiter(&RateLimitConfig{Backend: RateLimitBackendRedis}, nil))
+    assert.IsType(t, &RedisRateLimiter{}, newLimiter(&RateLimitConfig{Backend: RateLimitBackendRedis}, client))
+}
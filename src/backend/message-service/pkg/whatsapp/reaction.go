@@ -0,0 +1,46 @@
+// Package whatsapp provides reaction message payloads for the WhatsApp Business API
+// Version: go1.21
+package whatsapp
+
+import (
+    "context" // go1.21
+    "errors"  // go1.21
+)
+
+// reactionMessagePayload is the API body of a reaction message
+type reactionMessagePayload struct {
+    MessagingProduct string          `json:"messaging_product"`
+    RecipientType    string          `json:"recipient_type"`
+    To               string          `json:"to"`
+    Type             string          `json:"type"`
+    Reaction         ReactionContent `json:"reaction"`
+}
+
+// SendReaction reacts to a received message with an emoji. An empty emoji removes an
+// earlier reaction.
+func (c *Client) SendReaction(ctx context.Context, to, messageID, emoji string) error {
+    if messageID == "" {
+        return errors.New("message ID is required")
+    }
+
+    _, err := c.SendMessage(ctx, &Message{
+        To:   to,
+        Type: MessageTypeReaction,
+        Content: MessageContent{Reaction: &ReactionContent{
+            MessageID: messageID,
+            Emoji:     emoji,
+        }},
+    })
+    return err
+}
+
+// newReactionPayload renders a reaction in the shape the API expects
+func newReactionPayload(message *Message) *reactionMessagePayload {
+    return &reactionMessagePayload{
+        MessagingProduct: "whatsapp",
+        RecipientType:    "individual",
+        To:               message.To,
+        Type:             MessageTypeReaction,
+        Reaction:         *message.Content.Reaction,
+    }
+}
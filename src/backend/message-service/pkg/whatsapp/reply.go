@@ -0,0 +1,22 @@
+// Package whatsapp provides the reply context quoting an earlier message in WhatsApp Business API payloads
+// Version: go1.21
+package whatsapp
+
+// messageContext is the context object quoting the message an outgoing message replies to
+type messageContext struct {
+    MessageID string `json:"message_id"`
+}
+
+// quotedMessagePayload sends a message as it is with the context of the message it quotes
+type quotedMessagePayload struct {
+    *Message
+    Context *messageContext `json:"context"`
+}
+
+// replyContext returns the context quoting Content.ReplyTo, or nil when the message quotes nothing
+func replyContext(message *Message) *messageContext {
+    if message.Content.ReplyTo == "" {
+        return nil
+    }
+    return &messageContext{MessageID: message.Content.ReplyTo}
+}
@@ -0,0 +1,139 @@
+// Package whatsapp provides bulk message status lookups for the WhatsApp Business API client
+// Version: go1.21
+package whatsapp
+
+import (
+    "context"       // go1.21
+    "encoding/json" // go1.21
+    "fmt"           // go1.21
+    "net/http"      // go1.21
+    "net/url"       // go1.21
+    "sort"          // go1.21
+    "strings"       // go1.21
+)
+
+// maxStatusBatchSize is the most message IDs the status endpoint accepts per request
+const maxStatusBatchSize = 50
+
+// BatchStatusError reports the message IDs whose status could not be retrieved
+// by GetMessageStatuses. Statuses for the remaining IDs are still returned.
+type BatchStatusError struct {
+    Errors map[string]error
+}
+
+// Error summarizes the failed lookups
+func (e *BatchStatusError) Error() string {
+    ids := make([]string, 0, len(e.Errors))
+    for id := range e.Errors {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    if len(ids) == 1 {
+        return fmt.Sprintf("status lookup failed for message %s: %v", ids[0], e.Errors[ids[0]])
+    }
+    return fmt.Sprintf("status lookup failed for %d messages, first %s: %v", len(ids), ids[0], e.Errors[ids[0]])
+}
+
+// GetMessageStatuses retrieves the status of many messages using as few API calls as
+// the endpoint allows. Rate limit tokens for every call are reserved before the first
+// request is sent. Lookups that fail are reported per ID in a *BatchStatusError
+// alongside the statuses that were retrieved.
+func (c *Client) GetMessageStatuses(ctx context.Context, ids []string) (map[string]*MessageStatus, error) {
+    if err := c.checkInitialized(); err != nil {
+        return nil, err
+    }
+
+    ids = uniqueIDs(ids)
+    statuses := make(map[string]*MessageStatus, len(ids))
+    if len(ids) == 0 {
+        return statuses, nil
+    }
+
+    calls := (len(ids) + maxStatusBatchSize - 1) / maxStatusBatchSize
+    if err := c.rateLimiter.acquire(ctx, calls); err != nil {
+        return nil, err
+    }
+
+    failed := make(map[string]error)
+    for start := 0; start < len(ids); start += maxStatusBatchSize {
+        end := start + maxStatusBatchSize
+        if end > len(ids) {
+            end = len(ids)
+        }
+        batch := ids[start:end]
+
+        results, err := c.fetchStatusBatch(ctx, batch)
+        if err != nil {
+            for _, id := range batch {
+                failed[id] = err
+            }
+            continue
+        }
+
+        for _, id := range batch {
+            resp, ok := results[id]
+            if !ok {
+                failed[id] = ErrMessageNotFound
+                continue
+            }
+            if resp.Error != nil {
+                failed[id] = resp.Error
+                continue
+            }
+            status := MessageStatus(resp.Status)
+            statuses[id] = &status
+        }
+    }
+
+    if len(failed) > 0 {
+        return statuses, &BatchStatusError{Errors: failed}
+    }
+    return statuses, nil
+}
+
+// fetchStatusBatch requests the status of up to maxStatusBatchSize messages in one call
+func (c *Client) fetchStatusBatch(ctx context.Context, ids []string) (map[string]APIResponse, error) {
+    query := url.Values{"ids": []string{strings.Join(ids, ",")}}
+    endpoint := fmt.Sprintf("%s/messages?%s", c.apiEndpoint, query.Encode())
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, fmt.Errorf("create request: %w", err)
+    }
+
+    c.setRequestHeaders(req)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return nil, newHTTPError(resp)
+    }
+
+    var results map[string]APIResponse
+    if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+        return nil, fmt.Errorf("decode response: %w", err)
+    }
+    return results, nil
+}
+
+// uniqueIDs drops empty and repeated message IDs while keeping their order
+func uniqueIDs(ids []string) []string {
+    seen := make(map[string]struct{}, len(ids))
+    unique := make([]string, 0, len(ids))
+    for _, id := range ids {
+        if id == "" {
+            continue
+        }
+        if _, ok := seen[id]; ok {
+            continue
+        }
+        seen[id] = struct{}{}
+        unique = append(unique, id)
+    }
+    return unique
+}
@@ -0,0 +1,384 @@
+// Package whatsapp provides message template submission and lookup for the WhatsApp Business API
+// Version: go1.21
+package whatsapp
+
+import (
+    "bytes"         // go1.21
+    "context"       // go1.21
+    "encoding/json" // go1.21
+    "errors"        // go1.21
+    "fmt"           // go1.21
+    "net/http"      // go1.21
+    "net/url"       // go1.21
+    "regexp"        // go1.21
+    "strings"       // go1.21
+    "sync"          // go1.21
+    "time"          // go1.21
+)
+
+// Template review statuses reported by the WhatsApp Business API
+const (
+    TemplateStatusApproved = "APPROVED"
+    TemplateStatusPending  = "PENDING"
+    TemplateStatusRejected = "REJECTED"
+    TemplateStatusPaused   = "PAUSED"
+    TemplateStatusDisabled = "DISABLED"
+)
+
+// Template errors
+var (
+    // ErrInvalidTemplate is returned when a template is rejected locally before submission
+    ErrInvalidTemplate = errors.New("invalid template")
+    // ErrTemplateNotFound is returned when no template with the name and language was submitted
+    ErrTemplateNotFound = errors.New("template not found")
+)
+
+// templateNamePattern matches the names WhatsApp accepts: lowercase letters, digits and underscores
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,512}$`)
+
+// maxTemplateComponents is the most components a template can have: one each of header,
+// body, footer and buttons
+const maxTemplateComponents = 4
+
+// templateCacheTTL bounds how stale a cached template status may be before the list is refetched
+const templateCacheTTL = 5 * time.Minute
+
+// templateCache holds submitted templates keyed by name and language. The zero value is empty.
+type templateCache struct {
+    mu        sync.RWMutex
+    templates map[string]Template
+    loadedAt  time.Time
+}
+
+// templateListResponse is a page of the body returned when listing message templates
+type templateListResponse struct {
+    Data   []Template     `json:"data"`
+    Paging templatePaging `json:"paging"`
+    Error  *APIError      `json:"error,omitempty"`
+}
+
+// templatePaging locates the next page of a template list. Next is empty on the last page.
+type templatePaging struct {
+    Cursors struct {
+        After string `json:"after"`
+    } `json:"cursors"`
+    Next string `json:"next"`
+}
+
+// templateCreateResponse is the body returned when submitting a message template
+type templateCreateResponse struct {
+    ID       string    `json:"id"`
+    Status   string    `json:"status"`
+    Category string    `json:"category"`
+    Error    *APIError `json:"error,omitempty"`
+}
+
+// ListTemplates fetches all submitted message templates, following the list page by page,
+// and refreshes the template cache
+func (c *Client) ListTemplates(ctx context.Context) ([]Template, error) {
+    if err := c.checkInitialized(); err != nil {
+        return nil, err
+    }
+
+    var templates []Template
+    seen := make(map[string]bool)
+    after := ""
+    for {
+        page, err := c.listTemplatesPage(ctx, after)
+        if err != nil {
+            return nil, err
+        }
+        templates = append(templates, page.Data...)
+
+        // The API omits the next link on the last page; a repeated cursor would loop forever
+        after = page.Paging.Cursors.After
+        if page.Paging.Next == "" || after == "" || seen[after] {
+            break
+        }
+        seen[after] = true
+    }
+
+    c.templates.replace(templates)
+    c.metrics.RecordSuccess("list_templates")
+    return templates, nil
+}
+
+// listTemplatesPage fetches the page of the template list that starts after the cursor, or
+// the first page when after is empty
+func (c *Client) listTemplatesPage(ctx context.Context, after string) (*templateListResponse, error) {
+    endpoint := c.apiEndpoint + "/message_templates"
+    if after != "" {
+        endpoint += "?" + url.Values{"after": []string{after}}.Encode()
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, fmt.Errorf("create request: %w", err)
+    }
+    c.setRequestHeaders(req)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        httpErr := newHTTPError(resp)
+        c.metrics.RecordError("list_templates", httpErr)
+        return nil, httpErr
+    }
+
+    var listResp templateListResponse
+    if err := json.NewDecoder(resp.Body).Decode(&listResp); err != nil {
+        return nil, fmt.Errorf("decode response: %w", err)
+    }
+    if listResp.Error != nil {
+        c.metrics.RecordError("list_templates", listResp.Error)
+        return nil, fmt.Errorf("API error: %w", listResp.Error)
+    }
+    return &listResp, nil
+}
+
+// WarmCache pre-fetches submitted templates so the first sends after startup do not wait on a
+// cold cache. It returns the number of approved templates available.
+func (c *Client) WarmCache(ctx context.Context) (int, error) {
+    templates, err := c.ListTemplates(ctx)
+    if err != nil {
+        return 0, fmt.Errorf("warm template cache: %w", err)
+    }
+
+    approved := 0
+    for _, template := range templates {
+        if template.Status == TemplateStatusApproved {
+            approved++
+        }
+    }
+    return approved, nil
+}
+
+// CreateTemplate submits a message template for review. If a template with the same name and
+// language was already submitted, the existing template is returned with its current status
+// instead of resubmitting; force skips that check and always submits.
+func (c *Client) CreateTemplate(ctx context.Context, template *Template, force bool) (*Template, error) {
+    if err := c.checkInitialized(); err != nil {
+        return nil, err
+    }
+    if err := validateTemplateDefinition(template); err != nil {
+        return nil, err
+    }
+
+    if !force {
+        existing, err := c.findTemplate(ctx, template.Name, template.Language)
+        if err != nil {
+            return nil, fmt.Errorf("look up existing template: %w", err)
+        }
+        if existing != nil {
+            return existing, nil
+        }
+    }
+
+    payload, err := json.Marshal(template)
+    if err != nil {
+        return nil, fmt.Errorf("marshal template: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiEndpoint+"/message_templates", bytes.NewReader(payload))
+    if err != nil {
+        return nil, fmt.Errorf("create request: %w", err)
+    }
+    c.setRequestHeaders(req)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    var createResp templateCreateResponse
+    if err := json.NewDecoder(resp.Body).Decode(&createResp); err != nil {
+        return nil, fmt.Errorf("decode response: %w", err)
+    }
+    if createResp.Error != nil {
+        c.metrics.RecordError("create_template", createResp.Error)
+        return nil, fmt.Errorf("API error: %w", createResp.Error)
+    }
+
+    created := *template
+    created.ID = createResp.ID
+    created.Status = createResp.Status
+    if created.Status == "" {
+        created.Status = TemplateStatusPending
+    }
+    if createResp.Category != "" {
+        created.Category = createResp.Category
+    }
+
+    c.templates.put(created)
+    c.metrics.RecordSuccess("create_template")
+    return &created, nil
+}
+
+// GetTemplateStatus returns the review status of a submitted template, such as PENDING or
+// APPROVED. Statuses are served from the template cache while it is fresh.
+func (c *Client) GetTemplateStatus(ctx context.Context, name, language string) (string, error) {
+    if err := c.checkInitialized(); err != nil {
+        return "", err
+    }
+    if name == "" || language == "" {
+        return "", fmt.Errorf("%w: name and language are required", ErrInvalidTemplate)
+    }
+
+    template, err := c.findTemplate(ctx, name, language)
+    if err != nil {
+        return "", fmt.Errorf("look up template: %w", err)
+    }
+    if template == nil {
+        return "", ErrTemplateNotFound
+    }
+    return template.Status, nil
+}
+
+// DeleteTemplate deletes a submitted message template in every language it was submitted in
+func (c *Client) DeleteTemplate(ctx context.Context, name string) error {
+    if err := c.checkInitialized(); err != nil {
+        return err
+    }
+    if name == "" {
+        return fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+    }
+
+    query := url.Values{"name": []string{name}}
+    req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.apiEndpoint+"/message_templates?"+query.Encode(), nil)
+    if err != nil {
+        return fmt.Errorf("create request: %w", err)
+    }
+    c.setRequestHeaders(req)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        httpErr := newHTTPError(resp)
+        c.metrics.RecordError("delete_template", httpErr)
+        return httpErr
+    }
+
+    c.templates.remove(name)
+    c.metrics.RecordSuccess("delete_template")
+    return nil
+}
+
+// validateTemplateDefinition checks a template against the API's submission rules so an
+// invalid template is rejected without a round trip: a lowercase name, a language, a known
+// category and at most one of each component type with exactly one body
+func validateTemplateDefinition(template *Template) error {
+    if template == nil {
+        return fmt.Errorf("%w: template is required", ErrInvalidTemplate)
+    }
+    if !templateNamePattern.MatchString(template.Name) {
+        return fmt.Errorf("%w: name %q must be lowercase letters, digits and underscores", ErrInvalidTemplate, template.Name)
+    }
+    if template.Language == "" {
+        return fmt.Errorf("%w: language is required", ErrInvalidTemplate)
+    }
+
+    switch strings.ToUpper(template.Category) {
+    case TemplateCategoryAuthentication, TemplateCategoryUtility, TemplateCategoryMarketing:
+    default:
+        return fmt.Errorf("%w: unsupported category %q", ErrInvalidTemplate, template.Category)
+    }
+
+    if len(template.Components) == 0 || len(template.Components) > maxTemplateComponents {
+        return fmt.Errorf("%w: need 1 to %d components, got %d", ErrInvalidTemplate, maxTemplateComponents, len(template.Components))
+    }
+
+    counts := make(map[string]int, len(template.Components))
+    for _, component := range template.Components {
+        componentType := strings.ToUpper(component.Type)
+        switch componentType {
+        case ComponentTypeHeader, ComponentTypeBody, ComponentTypeFooter, ComponentTypeButtons:
+        default:
+            return fmt.Errorf("%w: unsupported component type %q", ErrInvalidTemplate, component.Type)
+        }
+        counts[componentType]++
+        if counts[componentType] > 1 {
+            return fmt.Errorf("%w: more than one %s component", ErrInvalidTemplate, componentType)
+        }
+    }
+    if counts[ComponentTypeBody] != 1 {
+        return fmt.Errorf("%w: exactly one BODY component is required", ErrInvalidTemplate)
+    }
+
+    return nil
+}
+
+// findTemplate returns the submitted template with the given name and language, or nil.
+// The cache answers while fresh; otherwise the template list is refetched.
+func (c *Client) findTemplate(ctx context.Context, name, language string) (*Template, error) {
+    if template, fresh := c.templates.get(name, language); fresh {
+        return template, nil
+    }
+
+    if _, err := c.ListTemplates(ctx); err != nil {
+        return nil, err
+    }
+
+    template, _ := c.templates.get(name, language)
+    return template, nil
+}
+
+func templateKey(name, language string) string {
+    return strings.ToLower(name) + ":" + strings.ToLower(language)
+}
+
+// get returns the cached template, if any, and whether the cache is fresh enough to trust a miss
+func (tc *templateCache) get(name, language string) (*Template, bool) {
+    tc.mu.RLock()
+    defer tc.mu.RUnlock()
+
+    fresh := !tc.loadedAt.IsZero() && time.Since(tc.loadedAt) < templateCacheTTL
+    template, ok := tc.templates[templateKey(name, language)]
+    if !ok {
+        return nil, fresh
+    }
+    return &template, fresh
+}
+
+func (tc *templateCache) put(template Template) {
+    tc.mu.Lock()
+    defer tc.mu.Unlock()
+
+    if tc.templates == nil {
+        tc.templates = make(map[string]Template)
+    }
+    tc.templates[templateKey(template.Name, template.Language)] = template
+}
+
+// remove drops every language of the named template
+func (tc *templateCache) remove(name string) {
+    tc.mu.Lock()
+    defer tc.mu.Unlock()
+
+    prefix := strings.ToLower(name) + ":"
+    for key := range tc.templates {
+        if strings.HasPrefix(key, prefix) {
+            delete(tc.templates, key)
+        }
+    }
+}
+
+func (tc *templateCache) replace(templates []Template) {
+    byKey := make(map[string]Template, len(templates))
+    for _, template := range templates {
+        byKey[templateKey(template.Name, template.Language)] = template
+    }
+
+    tc.mu.Lock()
+    defer tc.mu.Unlock()
+    tc.templates = byKey
+    tc.loadedAt = time.Now()
+}
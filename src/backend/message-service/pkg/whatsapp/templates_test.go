@@ -0,0 +1,200 @@
+package whatsapp
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// templateAPI is a fake template endpoint serving submitted templates a page at a time
+type templateAPI struct {
+    mu        sync.Mutex
+    pages     [][]Template
+    creates   int
+    listCalls int
+}
+
+func (api *templateAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    api.mu.Lock()
+    defer api.mu.Unlock()
+
+    switch r.Method {
+    case http.MethodGet:
+        api.listCalls++
+        page := 0
+        if after := r.URL.Query().Get("after"); after != "" {
+            fmt.Sscanf(after, "page-%d", &page)
+        }
+        resp := templateListResponse{}
+        if page < len(api.pages) {
+            resp.Data = api.pages[page]
+        }
+        if page+1 < len(api.pages) {
+            resp.Paging.Cursors.After = fmt.Sprintf("page-%d", page+1)
+            resp.Paging.Next = "https://graph.example.com/next"
+        }
+        json.NewEncoder(w).Encode(resp)
+    case http.MethodPost:
+        api.creates++
+        json.NewEncoder(w).Encode(templateCreateResponse{ID: fmt.Sprintf("tpl-%d", api.creates), Status: TemplateStatusPending})
+    }
+}
+
+func newTemplateTestClient(t *testing.T, api http.Handler) *Client {
+    t.Helper()
+    server := httptest.NewServer(api)
+    t.Cleanup(server.Close)
+    return newTestClient(t, server)
+}
+
+func orderTemplate() *Template {
+    return &Template{
+        Name:       "order_update",
+        Language:   "en_US",
+        Category:   TemplateCategoryUtility,
+        Components: []TemplateComponent{{Type: ComponentTypeBody, Text: "Order {{1}} shipped"}},
+    }
+}
+
+func TestListTemplatesFollowsPages(t *testing.T) {
+    api := &templateAPI{pages: [][]Template{
+        {{Name: "first", Language: "en_US"}},
+        {{Name: "second", Language: "en_US"}},
+        {{Name: "third", Language: "en_US", Status: TemplateStatusApproved}},
+    }}
+    client := newTemplateTestClient(t, api)
+
+    templates, err := client.ListTemplates(context.Background())
+
+    require.NoError(t, err)
+    assert.Len(t, templates, 3)
+    assert.Equal(t, 3, api.listCalls)
+    status, err := client.GetTemplateStatus(context.Background(), "third", "en_US")
+    require.NoError(t, err)
+    assert.Equal(t, TemplateStatusApproved, status)
+}
+
+func TestListTemplatesReturnsHTTPError(t *testing.T) {
+    client := newTemplateTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusForbidden)
+        fmt.Fprint(w, "<html>Forbidden</html>")
+    }))
+
+    _, err := client.ListTemplates(context.Background())
+
+    var httpErr *HTTPError
+    require.True(t, errors.As(err, &httpErr))
+    assert.Equal(t, http.StatusForbidden, httpErr.StatusCode)
+    assert.True(t, errors.Is(err, ErrUnauthorized))
+}
+
+func TestCreateTemplateSubmitsNewTemplate(t *testing.T) {
+    api := &templateAPI{}
+    client := newTemplateTestClient(t, api)
+
+    created, err := client.CreateTemplate(context.Background(), orderTemplate(), false)
+
+    require.NoError(t, err)
+    assert.Equal(t, "tpl-1", created.ID)
+    assert.Equal(t, TemplateStatusPending, created.Status)
+    assert.Equal(t, 1, api.creates)
+}
+
+func TestCreateTemplateReturnsExistingTemplate(t *testing.T) {
+    existing := *orderTemplate()
+    existing.ID = "tpl-existing"
+    existing.Status = TemplateStatusApproved
+    api := &templateAPI{pages: [][]Template{{existing}}}
+    client := newTemplateTestClient(t, api)
+
+    created, err := client.CreateTemplate(context.Background(), orderTemplate(), false)
+
+    require.NoError(t, err)
+    assert.Equal(t, "tpl-existing", created.ID)
+    assert.Equal(t, TemplateStatusApproved, created.Status)
+    assert.Equal(t, 0, api.creates)
+}
+
+func TestCreateTemplateForcedResubmits(t *testing.T) {
+    existing := *orderTemplate()
+    existing.ID = "tpl-existing"
+    api := &templateAPI{pages: [][]Template{{existing}}}
+    client := newTemplateTestClient(t, api)
+
+    created, err := client.CreateTemplate(context.Background(), orderTemplate(), true)
+
+    require.NoError(t, err)
+    assert.Equal(t, "tpl-1", created.ID)
+    assert.Equal(t, 1, api.creates)
+    assert.Equal(t, 0, api.listCalls)
+}
+
+func TestValidateTemplateDefinition(t *testing.T) {
+    body := TemplateComponent{Type: ComponentTypeBody, Text: "Hello {{1}}"}
+    valid := func(modify func(*Template)) *Template {
+        template := &Template{
+            Name:       "welcome_message",
+            Language:   "en_US",
+            Category:   TemplateCategoryMarketing,
+            Components: []TemplateComponent{body},
+        }
+        modify(template)
+        return template
+    }
+
+    tests := []struct {
+        name     string
+        template *Template
+        wantErr  bool
+    }{
+        {"nil template", nil, true},
+        {"minimal template", valid(func(*Template) {}), false},
+        {"lowercase category", valid(func(tpl *Template) { tpl.Category = "utility" }), false},
+        {"all component types", valid(func(tpl *Template) {
+            tpl.Components = []TemplateComponent{
+                {Type: ComponentTypeHeader}, body, {Type: "footer"}, {Type: ComponentTypeButtons},
+            }
+        }), false},
+        {"uppercase name", valid(func(tpl *Template) { tpl.Name = "Welcome" }), true},
+        {"name with dash", valid(func(tpl *Template) { tpl.Name = "welcome-message" }), true},
+        {"empty name", valid(func(tpl *Template) { tpl.Name = "" }), true},
+        {"name too long", valid(func(tpl *Template) { tpl.Name = strings.Repeat("a", 513) }), true},
+        {"missing language", valid(func(tpl *Template) { tpl.Language = "" }), true},
+        {"unknown category", valid(func(tpl *Template) { tpl.Category = "TRANSACTIONAL" }), true},
+        {"no components", valid(func(tpl *Template) { tpl.Components = nil }), true},
+        {"too many components", valid(func(tpl *Template) {
+            tpl.Components = []TemplateComponent{
+                {Type: ComponentTypeHeader}, body, {Type: ComponentTypeFooter}, {Type: ComponentTypeButtons}, {Type: ComponentTypeFooter},
+            }
+        }), true},
+        {"unknown component", valid(func(tpl *Template) {
+            tpl.Components = []TemplateComponent{body, {Type: "CAROUSEL"}}
+        }), true},
+        {"duplicate header", valid(func(tpl *Template) {
+            tpl.Components = []TemplateComponent{{Type: ComponentTypeHeader}, {Type: ComponentTypeHeader}, body}
+        }), true},
+        {"no body", valid(func(tpl *Template) {
+            tpl.Components = []TemplateComponent{{Type: ComponentTypeHeader}}
+        }), true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := validateTemplateDefinition(tt.template)
+            if tt.wantErr {
+                assert.True(t, errors.Is(err, ErrInvalidTemplate), "got %v", err)
+                return
+            }
+            assert.NoError(t, err)
+        })
+    }
+}
@@ -4,15 +4,19 @@ package whatsapp
 
 import (
     "encoding/json" // go1.21
+    "errors"       // go1.21
+    "fmt"          // go1.21
     "time"         // go1.21
 )
 
 // Message status constants
 const (
-    MessageStatusDelivered = "delivered"
-    MessageStatusFailed    = "failed"
-    MessageStatusPending   = "pending"
-    MessageStatusSent      = "sent"
+    MessageStatusDelivered  = "delivered"
+    MessageStatusFailed     = "failed"
+    MessageStatusPending    = "pending"
+    MessageStatusProcessing = "processing"
+    MessageStatusSent       = "sent"
+    MessageStatusRead       = "read"
 )
 
 // Media type constants
@@ -23,6 +27,60 @@ const (
     MediaTypeAudio    = "audio"
 )
 
+// Message type constants
+const (
+    MessageTypeText        = "text"
+    MessageTypeMedia       = "media"
+    MessageTypeTemplate    = "template"
+    MessageTypeAddress     = "address"
+    MessageTypeInteractive = "interactive"
+    MessageTypeReaction    = "reaction"
+    MessageTypeLocation    = "location"
+    MessageTypeContacts    = "contacts"
+)
+
+// Interactive message type constants
+const (
+    InteractiveTypeButton = "button"
+    InteractiveTypeList   = "list"
+)
+
+// Interactive reply type constants reported in webhooks
+const (
+    InteractiveReplyButton = "button_reply"
+    InteractiveReplyList   = "list_reply"
+)
+
+// Webhook event type constants
+const (
+    WebhookEventTypeMessage = "message"
+    WebhookEventTypeStatus  = "status"
+    WebhookEventTypeOrder   = "order"
+    WebhookEventTypeTemplateStatusUpdate = "template_status_update"
+    WebhookEventTypeAccountAlert         = "account_alert"
+)
+
+// Template component type constants
+const (
+    ComponentTypeHeader  = "HEADER"
+    ComponentTypeBody    = "BODY"
+    ComponentTypeFooter  = "FOOTER"
+    ComponentTypeButtons = "BUTTONS"
+)
+
+// Template category constants
+const (
+    TemplateCategoryAuthentication = "AUTHENTICATION"
+    TemplateCategoryUtility        = "UTILITY"
+    TemplateCategoryMarketing      = "MARKETING"
+)
+
+// Template parameter format constants
+const (
+    ParameterFormatCurrency = "currency"
+    ParameterFormatDateTime = "date_time"
+)
+
 // MessageStatus represents the current status of a message
 type MessageStatus string
 
@@ -53,6 +111,9 @@ type Message struct {
     DeliveredAt  *time.Time            `json:"delivered_at,omitempty"`
     RetryCount   int                   `json:"retry_count"`
     Metadata     map[string]interface{} `json:"metadata,omitempty"`
+    BizOpaqueCallbackData string       `json:"biz_opaque_callback_data,omitempty"`
+    // IdempotencyKey, when set, is sent verbatim as the Idempotency-Key of every attempt
+    IdempotencyKey string              `json:"-"`
 }
 
 // MessageContent represents the content of a WhatsApp message
@@ -60,6 +121,7 @@ type MessageContent struct {
     Text        string            `json:"text,omitempty"`
     Caption     string            `json:"caption,omitempty"`
     MediaURL    string            `json:"media_url,omitempty"`
+    MediaID     string            `json:"media_id,omitempty"`
     MediaType   string            `json:"media_type,omitempty"`
     MediaSize   int64             `json:"media_size,omitempty"`
     MediaName   string            `json:"media_name,omitempty"`
@@ -67,6 +129,138 @@ type MessageContent struct {
     PreviewURL  string            `json:"preview_url,omitempty"`
     RichText    bool              `json:"rich_text"`
     Formatting  *MessageFormatting `json:"formatting,omitempty"`
+    // Address requests a delivery address; Text is shown as the message body
+    Address     *AddressContent    `json:"address,omitempty"`
+    Interactive *InteractiveContent `json:"interactive,omitempty"`
+    Reaction    *ReactionContent    `json:"reaction,omitempty"`
+    Location    *LocationContent    `json:"location,omitempty"`
+    Contacts    []ContactCard       `json:"contacts,omitempty"`
+    // ReplyTo quotes the message with this ID, like replying to it in WhatsApp. Reactions
+    // already name their message and ignore it.
+    ReplyTo     string              `json:"reply_to,omitempty"`
+}
+
+// ContactCard is a contact shared in a contacts message, in the shape the API expects
+type ContactCard struct {
+    Name   ContactName    `json:"name"`
+    Phones []ContactPhone `json:"phones,omitempty"`
+    Emails []ContactEmail `json:"emails,omitempty"`
+    Org    *ContactOrg    `json:"org,omitempty"`
+}
+
+// ContactName is the name of a shared contact; FormattedName is the name displayed
+type ContactName struct {
+    FormattedName string `json:"formatted_name"`
+    FirstName     string `json:"first_name,omitempty"`
+    LastName      string `json:"last_name,omitempty"`
+}
+
+// ContactPhone is a phone number of a shared contact. WaID, when set, lets the customer
+// message the contact directly.
+type ContactPhone struct {
+    Phone string `json:"phone"`
+    Type  string `json:"type,omitempty"`
+    WaID  string `json:"wa_id,omitempty"`
+}
+
+// ContactEmail is an email address of a shared contact
+type ContactEmail struct {
+    Email string `json:"email"`
+    Type  string `json:"type,omitempty"`
+}
+
+// ContactOrg is the organization a shared contact works for
+type ContactOrg struct {
+    Company    string `json:"company,omitempty"`
+    Department string `json:"department,omitempty"`
+    Title      string `json:"title,omitempty"`
+}
+
+// LocationContent is a map pin at Latitude and Longitude with an optional Name and Address.
+// Coordinates of exactly 0,0 are usually an unset location and are rejected unless
+// AllowZero is set.
+type LocationContent struct {
+    Latitude  float64 `json:"latitude"`
+    Longitude float64 `json:"longitude"`
+    Name      string  `json:"name,omitempty"`
+    Address   string  `json:"address,omitempty"`
+    AllowZero bool    `json:"allow_zero,omitempty"`
+}
+
+// ReactionContent reacts to the message MessageID with a single emoji; an empty Emoji
+// removes the reaction
+type ReactionContent struct {
+    MessageID string `json:"message_id"`
+    Emoji     string `json:"emoji"`
+}
+
+// InteractiveContent is a reply-button or list message. Button messages offer up to three
+// Buttons; list messages open Sections of rows from a button labelled ListButton.
+type InteractiveContent struct {
+    Type       string        `json:"type"`
+    Header     string        `json:"header,omitempty"`
+    Body       string        `json:"body"`
+    Footer     string        `json:"footer,omitempty"`
+    Buttons    []ReplyButton `json:"buttons,omitempty"`
+    ListButton string        `json:"list_button,omitempty"`
+    Sections   []ListSection `json:"sections,omitempty"`
+}
+
+// ReplyButton is a quick-reply button; its ID is returned in the reply webhook
+type ReplyButton struct {
+    ID    string `json:"id"`
+    Title string `json:"title"`
+}
+
+// ListSection groups the rows of a list message under an optional title
+type ListSection struct {
+    Title string    `json:"title,omitempty"`
+    Rows  []ListRow `json:"rows"`
+}
+
+// ListRow is a selectable list entry; its ID is returned in the reply webhook
+type ListRow struct {
+    ID          string `json:"id"`
+    Title       string `json:"title"`
+    Description string `json:"description,omitempty"`
+}
+
+// InteractiveReply is the button or list row a customer selected in reply to an
+// interactive message
+type InteractiveReply struct {
+    MessageID   string    `json:"message_id"`
+    From        string    `json:"from"`
+    Type        string    `json:"type"`
+    ID          string    `json:"id"`
+    Title       string    `json:"title"`
+    Description string    `json:"description,omitempty"`
+    Timestamp   time.Time `json:"timestamp"`
+}
+
+// AddressContent is an interactive address message asking the recipient for a delivery
+// address. Field names in Values, ValidationErrors and saved addresses are the API's
+// per-country address fields, e.g. "in_pin_code" for India.
+type AddressContent struct {
+    Country          string            `json:"country"`
+    Values           map[string]string `json:"values,omitempty"`
+    ValidationErrors map[string]string `json:"validation_errors,omitempty"`
+    SavedAddresses   []SavedAddress    `json:"saved_addresses,omitempty"`
+}
+
+// SavedAddress is a previously used address the recipient can pick instead of typing one
+type SavedAddress struct {
+    ID    string            `json:"id"`
+    Value map[string]string `json:"value"`
+}
+
+// AddressResponse is the address a customer submitted in reply to an address message.
+// SavedAddressID is set when they picked one of the offered saved addresses.
+type AddressResponse struct {
+    MessageID      string            `json:"message_id"`
+    From           string            `json:"from"`
+    SavedAddressID string            `json:"saved_address_id,omitempty"`
+    Values         map[string]string `json:"values"`
+    Timestamp      time.Time         `json:"timestamp"`
 }
 
 // MessageFormatting defines rich text formatting options
@@ -79,6 +273,7 @@ type MessageFormatting struct {
 
 // Template represents a WhatsApp message template
 type Template struct {
+    ID         string              `json:"id,omitempty"`
     Name       string              `json:"name"`
     Language   string              `json:"language"`
     Category   string              `json:"category"`
@@ -87,11 +282,16 @@ type Template struct {
     Version    string              `json:"version"`
     CreatedAt  time.Time          `json:"created_at"`
     UpdatedAt  time.Time          `json:"updated_at"`
+    // PlainTextFallback is sent instead when the template is unavailable and the
+    // recipient is inside the customer service window
+    PlainTextFallback string `json:"plain_text_fallback,omitempty"`
 }
 
 // TemplateComponent represents a component within a template
 type TemplateComponent struct {
     Type       string      `json:"type"`
+    // Text is the component text as registered, with {{n}} placeholders for Parameters
+    Text       string      `json:"text,omitempty"`
     Parameters []Parameter `json:"parameters"`
     SubType    string      `json:"sub_type,omitempty"`
     Index      int         `json:"index"`
@@ -136,6 +336,37 @@ type APIError struct {
     RetryAfter  *time.Duration   `json:"retry_after,omitempty"`
 }
 
+// Error implements the error interface so API errors can be wrapped and inspected with errors.As
+func (e *APIError) Error() string {
+    if e.Details != "" {
+        return fmt.Sprintf("%s (code %d): %s", e.Message, e.Code, e.Details)
+    }
+    return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// API error codes reported when a template cannot currently be sent
+const (
+    errorCodeTemplateNotFound = 132001
+    errorCodeTemplatePaused   = 132015
+    errorCodeTemplateDisabled = 132016
+)
+
+// CustomerServiceWindow is how long after a customer's last message free-form messages may be sent
+const CustomerServiceWindow = 24 * time.Hour
+
+// IsTemplateUnavailable reports whether err is an API error for a missing, paused or disabled template
+func IsTemplateUnavailable(err error) bool {
+    var apiErr *APIError
+    if !errors.As(err, &apiErr) {
+        return false
+    }
+    switch apiErr.Code {
+    case errorCodeTemplateNotFound, errorCodeTemplatePaused, errorCodeTemplateDisabled:
+        return true
+    }
+    return false
+}
+
 // RateLimitInfo provides rate limiting details
 type RateLimitInfo struct {
     Limit     int           `json:"limit"`
@@ -161,4 +392,65 @@ type WebhookEvent struct {
     Version     string          `json:"version"`
     Signature   string          `json:"signature"`
     DeliveryInfo *DeliveryInfo  `json:"delivery_info,omitempty"`
-}
\ No newline at end of file
+    From        string          `json:"from,omitempty"`
+    Referral    *Referral       `json:"referral,omitempty"`
+    Order       *OrderEvent     `json:"order,omitempty"`
+    BizOpaqueCallbackData string `json:"biz_opaque_callback_data,omitempty"`
+    Conversation *Conversation  `json:"conversation,omitempty"`
+    Pricing     *Pricing        `json:"pricing,omitempty"`
+    Address     *AddressResponse `json:"address,omitempty"`
+    InteractiveReply *InteractiveReply `json:"interactive_reply,omitempty"`
+    // ReplyTo is the ID of the message an inbound message quotes
+    ReplyTo     string          `json:"reply_to,omitempty"`
+}
+
+// Conversation identifies the billing conversation a sent message was delivered in
+type Conversation struct {
+    ID                  string              `json:"id"`
+    Origin              *ConversationOrigin `json:"origin,omitempty"`
+    ExpirationTimestamp string              `json:"expiration_timestamp,omitempty"`
+}
+
+// ConversationOrigin names the category that opened the conversation
+type ConversationOrigin struct {
+    Type string `json:"type"`
+}
+
+// Pricing describes how a message is charged
+type Pricing struct {
+    Billable     bool   `json:"billable"`
+    Category     string `json:"category"`
+    PricingModel string `json:"pricing_model,omitempty"`
+}
+
+// Referral carries click-to-WhatsApp ad attribution sent with the first inbound message
+type Referral struct {
+    SourceURL    string `json:"source_url"`
+    SourceID     string `json:"source_id,omitempty"`
+    SourceType   string `json:"source_type,omitempty"`
+    Headline     string `json:"headline,omitempty"`
+    Body         string `json:"body,omitempty"`
+    MediaType    string `json:"media_type,omitempty"`
+    ImageURL     string `json:"image_url,omitempty"`
+    VideoURL     string `json:"video_url,omitempty"`
+    ThumbnailURL string `json:"thumbnail_url,omitempty"`
+    CtwaClid     string `json:"ctwa_clid,omitempty"`
+}
+
+// OrderEvent is a catalog order placed by a customer from a sent product catalog
+type OrderEvent struct {
+    MessageID string             `json:"message_id"`
+    From      string             `json:"from"`
+    CatalogID string             `json:"catalog_id"`
+    Text      string             `json:"text,omitempty"`
+    Items     []OrderProductItem `json:"product_items"`
+    Timestamp time.Time          `json:"timestamp"`
+}
+
+// OrderProductItem is a single product line within an order
+type OrderProductItem struct {
+    ProductRetailerID string  `json:"product_retailer_id"`
+    Quantity          int     `json:"quantity"`
+    ItemPrice         float64 `json:"item_price"`
+    Currency          string  `json:"currency"`
+}
@@ -0,0 +1,130 @@
+// Package whatsapp provides typing indicators for the WhatsApp Business API client
+// Version: go1.21
+package whatsapp
+
+import (
+    "bytes"         // go1.21
+    "context"       // go1.21
+    "encoding/json" // go1.21
+    "errors"        // go1.21
+    "fmt"           // go1.21
+    "net/http"      // go1.21
+    "time"          // go1.21
+)
+
+const (
+    typingOn  = "typing_on"
+    typingOff = "typing_off"
+
+    // typingRefreshInterval re-sends the indicator before WhatsApp expires it after 25 seconds
+    typingRefreshInterval = 20 * time.Second
+    // typingStopTimeout bounds clearing the indicator after the callback returns
+    typingStopTimeout = 5 * time.Second
+)
+
+// typingPayload is the API body of a typing indicator
+type typingPayload struct {
+    MessagingProduct string       `json:"messaging_product"`
+    RecipientType    string       `json:"recipient_type"`
+    To               string       `json:"to"`
+    Type             string       `json:"type"`
+    Typing           typingStatus `json:"typing"`
+}
+
+type typingStatus struct {
+    Status string `json:"status"`
+}
+
+// SendTypingIndicator shows the recipient a typing indicator. WhatsApp clears it after
+// about 25 seconds or when the next message arrives; use WithTyping to keep it up for
+// longer work.
+func (c *Client) SendTypingIndicator(ctx context.Context, to string) error {
+    if err := c.sendTyping(ctx, to, typingOn); err != nil {
+        return err
+    }
+    c.metrics.RecordSuccess("typing_indicator")
+    return nil
+}
+
+// WithTyping shows a typing indicator to the recipient while fn runs, refreshing it before
+// it expires, and clears it when fn returns. Indicator failures do not stop fn; its error
+// is returned as is.
+func (c *Client) WithTyping(ctx context.Context, to string, fn func(ctx context.Context) error) error {
+    if fn == nil {
+        return errors.New("typing callback is required")
+    }
+
+    // The indicator is cosmetic, so a failure to show it is not worth failing the reply over
+    _ = c.SendTypingIndicator(ctx, to)
+
+    refreshCtx, stopRefresh := context.WithCancel(ctx)
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        ticker := time.NewTicker(typingRefreshInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-refreshCtx.Done():
+                return
+            case <-ticker.C:
+                _ = c.SendTypingIndicator(refreshCtx, to)
+            }
+        }
+    }()
+
+    err := fn(ctx)
+
+    stopRefresh()
+    <-done
+
+    // Clear the indicator even if ctx has ended so it does not linger
+    stopCtx, cancel := context.WithTimeout(context.Background(), typingStopTimeout)
+    defer cancel()
+    _ = c.sendTyping(stopCtx, to, typingOff)
+
+    return err
+}
+
+// sendTyping posts a typing indicator status for the recipient
+func (c *Client) sendTyping(ctx context.Context, to, status string) error {
+    if err := c.checkInitialized(); err != nil {
+        return err
+    }
+    if to == "" {
+        return errors.New("recipient is required")
+    }
+
+    if err := c.rateLimiter.acquire(ctx, 1); err != nil {
+        return fmt.Errorf("rate limit: %w", err)
+    }
+
+    payload, err := json.Marshal(typingPayload{
+        MessagingProduct: "whatsapp",
+        RecipientType:    "individual",
+        To:               to,
+        Type:             "typing",
+        Typing:           typingStatus{Status: status},
+    })
+    if err != nil {
+        return fmt.Errorf("marshal request: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiEndpoint+"/messages", bytes.NewReader(payload))
+    if err != nil {
+        return fmt.Errorf("create request: %w", err)
+    }
+
+    c.setRequestHeaders(req)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("do request: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode > 299 {
+        return newHTTPError(resp)
+    }
+    return nil
+}
@@ -0,0 +1,264 @@
+// Package whatsapp provides webhook payload parsing helpers for WhatsApp Business API events
+// Version: go1.21
+package whatsapp
+
+import (
+    "encoding/json" // go1.21
+    "fmt"           // go1.21
+)
+
+// inboundMessagePayload mirrors the fields of an inbound message payload that are parsed into typed events
+type inboundMessagePayload struct {
+    From        string                   `json:"from"`
+    Type        string                   `json:"type,omitempty"`
+    Referral    *Referral                `json:"referral,omitempty"`
+    Order       *orderPayload            `json:"order,omitempty"`
+    Interactive *interactiveReplyPayload `json:"interactive,omitempty"`
+    Context     *inboundContextPayload   `json:"context,omitempty"`
+}
+
+// inboundContextPayload identifies the message an inbound message replies to
+type inboundContextPayload struct {
+    From string `json:"from,omitempty"`
+    ID   string `json:"id"`
+}
+
+// interactiveReplyPayload mirrors the interactive object of an inbound reply to an interactive message
+type interactiveReplyPayload struct {
+    Type        string                 `json:"type"`
+    NfmReply    *nfmReplyPayload       `json:"nfm_reply,omitempty"`
+    ButtonReply *selectionReplyPayload `json:"button_reply,omitempty"`
+    ListReply   *selectionReplyPayload `json:"list_reply,omitempty"`
+}
+
+// selectionReplyPayload is the button or list row a customer selected
+type selectionReplyPayload struct {
+    ID          string `json:"id"`
+    Title       string `json:"title"`
+    Description string `json:"description,omitempty"`
+}
+
+// nfmReplyPayload carries the submitted form, such as an address, as a JSON-encoded string
+type nfmReplyPayload struct {
+    Name         string `json:"name"`
+    ResponseJSON string `json:"response_json"`
+}
+
+// addressReplyValues is the decoded response_json of an address message reply
+type addressReplyValues struct {
+    SavedAddressID string            `json:"saved_address_id,omitempty"`
+    Values         map[string]string `json:"values"`
+}
+
+// orderPayload mirrors the order object of an inbound order message
+type orderPayload struct {
+    CatalogID    string             `json:"catalog_id"`
+    Text         string             `json:"text,omitempty"`
+    ProductItems []OrderProductItem `json:"product_items"`
+}
+
+// statusPayload mirrors the billing fields of a message status payload
+type statusPayload struct {
+    Conversation *Conversation `json:"conversation,omitempty"`
+    Pricing      *Pricing      `json:"pricing,omitempty"`
+}
+
+// ParseReferral extracts click-to-WhatsApp referral attribution from an inbound message event.
+// It returns nil without error when the event carries no referral.
+func (e *WebhookEvent) ParseReferral() (*Referral, error) {
+    if e.Referral != nil {
+        return e.Referral, nil
+    }
+    if e.Type != WebhookEventTypeMessage || len(e.Payload) == 0 {
+        return nil, nil
+    }
+
+    var payload inboundMessagePayload
+    if err := json.Unmarshal(e.Payload, &payload); err != nil {
+        return nil, fmt.Errorf("unmarshal inbound message payload: %w", err)
+    }
+
+    if payload.Referral != nil {
+        if e.From == "" {
+            e.From = payload.From
+        }
+        e.Referral = payload.Referral
+    }
+
+    return e.Referral, nil
+}
+
+// ParseOrder extracts a catalog order from an inbound order event, accepting both
+// "order" events and message events whose payload type is "order".
+// It returns nil without error when the event carries no order.
+func (e *WebhookEvent) ParseOrder() (*OrderEvent, error) {
+    if e.Order != nil {
+        return e.Order, nil
+    }
+    if (e.Type != WebhookEventTypeMessage && e.Type != WebhookEventTypeOrder) || len(e.Payload) == 0 {
+        return nil, nil
+    }
+
+    var payload inboundMessagePayload
+    if err := json.Unmarshal(e.Payload, &payload); err != nil {
+        return nil, fmt.Errorf("unmarshal inbound message payload: %w", err)
+    }
+    if payload.Order == nil {
+        return nil, nil
+    }
+    if payload.Order.CatalogID == "" || len(payload.Order.ProductItems) == 0 {
+        return nil, fmt.Errorf("order payload requires a catalog ID and at least one product item")
+    }
+
+    if e.From == "" {
+        e.From = payload.From
+    }
+    e.Order = &OrderEvent{
+        MessageID: e.MessageID,
+        From:      e.From,
+        CatalogID: payload.Order.CatalogID,
+        Text:      payload.Order.Text,
+        Items:     payload.Order.ProductItems,
+        Timestamp: e.Timestamp,
+    }
+
+    return e.Order, nil
+}
+
+// ParseConversation extracts the billing conversation and pricing from a status event.
+// Either may be nil: statuses such as read carry neither, and pricing can arrive alone.
+func (e *WebhookEvent) ParseConversation() (*Conversation, *Pricing, error) {
+    if e.Conversation != nil || e.Pricing != nil {
+        return e.Conversation, e.Pricing, nil
+    }
+    if e.Type == WebhookEventTypeMessage || e.Type == WebhookEventTypeOrder || len(e.Payload) == 0 {
+        return nil, nil, nil
+    }
+
+    var payload statusPayload
+    if err := json.Unmarshal(e.Payload, &payload); err != nil {
+        return nil, nil, fmt.Errorf("unmarshal status payload: %w", err)
+    }
+    if payload.Conversation != nil && payload.Conversation.ID == "" {
+        return nil, nil, fmt.Errorf("conversation payload requires an ID")
+    }
+
+    e.Conversation = payload.Conversation
+    e.Pricing = payload.Pricing
+    return e.Conversation, e.Pricing, nil
+}
+
+// ParseAddress extracts the address a customer submitted in reply to an address message.
+// It returns nil without error when the event is not an address reply.
+func (e *WebhookEvent) ParseAddress() (*AddressResponse, error) {
+    if e.Address != nil {
+        return e.Address, nil
+    }
+    if e.Type != WebhookEventTypeMessage || len(e.Payload) == 0 {
+        return nil, nil
+    }
+
+    var payload inboundMessagePayload
+    if err := json.Unmarshal(e.Payload, &payload); err != nil {
+        return nil, fmt.Errorf("unmarshal inbound message payload: %w", err)
+    }
+    reply := payload.Interactive
+    if reply == nil || reply.Type != interactiveTypeNfmReply || reply.NfmReply == nil || reply.NfmReply.Name != interactiveTypeAddress {
+        return nil, nil
+    }
+
+    var values addressReplyValues
+    if err := json.Unmarshal([]byte(reply.NfmReply.ResponseJSON), &values); err != nil {
+        return nil, fmt.Errorf("unmarshal address response: %w", err)
+    }
+    if values.SavedAddressID == "" && len(values.Values) == 0 {
+        return nil, fmt.Errorf("address response requires values or a saved address ID")
+    }
+
+    if e.From == "" {
+        e.From = payload.From
+    }
+    e.Address = &AddressResponse{
+        MessageID:      e.MessageID,
+        From:           e.From,
+        SavedAddressID: values.SavedAddressID,
+        Values:         values.Values,
+        Timestamp:      e.Timestamp,
+    }
+
+    return e.Address, nil
+}
+
+// ParseInteractiveReply extracts the reply button or list row a customer selected, so
+// follow-up messages can be routed by its ID. It returns nil without error when the event
+// is not a button or list reply.
+func (e *WebhookEvent) ParseInteractiveReply() (*InteractiveReply, error) {
+    if e.InteractiveReply != nil {
+        return e.InteractiveReply, nil
+    }
+    if e.Type != WebhookEventTypeMessage || len(e.Payload) == 0 {
+        return nil, nil
+    }
+
+    var payload inboundMessagePayload
+    if err := json.Unmarshal(e.Payload, &payload); err != nil {
+        return nil, fmt.Errorf("unmarshal inbound message payload: %w", err)
+    }
+    if payload.Interactive == nil {
+        return nil, nil
+    }
+
+    var selection *selectionReplyPayload
+    switch payload.Interactive.Type {
+    case InteractiveReplyButton:
+        selection = payload.Interactive.ButtonReply
+    case InteractiveReplyList:
+        selection = payload.Interactive.ListReply
+    default:
+        return nil, nil
+    }
+    if selection == nil || selection.ID == "" {
+        return nil, fmt.Errorf("%s payload requires a selected ID", payload.Interactive.Type)
+    }
+
+    if e.From == "" {
+        e.From = payload.From
+    }
+    e.InteractiveReply = &InteractiveReply{
+        MessageID:   e.MessageID,
+        From:        e.From,
+        Type:        payload.Interactive.Type,
+        ID:          selection.ID,
+        Title:       selection.Title,
+        Description: selection.Description,
+        Timestamp:   e.Timestamp,
+    }
+
+    return e.InteractiveReply, nil
+}
+
+// ParseReplyContext extracts the ID of the message a customer quoted when replying, so
+// conversation threads can be reconstructed. It returns an empty ID without error when the
+// message quotes nothing.
+func (e *WebhookEvent) ParseReplyContext() (string, error) {
+    if e.ReplyTo != "" {
+        return e.ReplyTo, nil
+    }
+    if e.Type != WebhookEventTypeMessage || len(e.Payload) == 0 {
+        return "", nil
+    }
+
+    var payload inboundMessagePayload
+    if err := json.Unmarshal(e.Payload, &payload); err != nil {
+        return "", fmt.Errorf("unmarshal inbound message payload: %w", err)
+    }
+    if payload.Context == nil || payload.Context.ID == "" {
+        return "", nil
+    }
+
+    if e.From == "" {
+        e.From = payload.From
+    }
+    e.ReplyTo = payload.Context.ID
+    return e.ReplyTo, nil
+}